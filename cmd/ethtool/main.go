@@ -1,17 +1,88 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	ethtool "ethtool/pkg"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+var rootCmd = &cobra.Command{
+	Use:   "ethtool",
+	Short: "ethtool DEVNAME	Display standard information about device",
+	Run:   do_actions,
+}
+
+func init() {
+	for _, opt := range ethtool.Options() {
+		if opt.Short == "" {
+			rootCmd.Flags().Bool(opt.Name, false, opt.Help)
+		} else {
+			rootCmd.Flags().BoolP(opt.Name, opt.Short, false, opt.Help)
+		}
+	}
+	rootCmd.Flags().Uint64("debug", 0, "Turn on debugging messages")
+	rootCmd.Flags().Bool("all", false, "Show all notifications (with --monitor)")
+	rootCmd.Flags().Uint("wait", 0, "Wait up to N seconds for link to come back (with --negotiate)")
+}
+
+// sub_options returns the bool options set besides the main one, as
+// "--name" arguments
+func sub_options(cmd *cobra.Command, main string) []string {
+	var sargs []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if f.Value.Type() == "bool" && f.Name != main {
+			sargs = append(sargs, "--"+f.Name)
+		}
+	})
+	return sargs
+}
+
+// do_actions hands the first selected option over to the library
+func do_actions(cmd *cobra.Command, args []string) {
+	debug, _ := cmd.Flags().GetUint64("debug")
+	ethtool.SetDebug(debug)
+
+	/* the other options only select notification types */
+	if monitor, _ := cmd.Flags().GetBool("monitor"); monitor {
+		margs := sub_options(cmd, "monitor")
+		os.Exit(ethtool.Run("monitor", append(margs, args...)))
+	}
+	/* the other option is the sub command run on the queues */
+	if perqueue, _ := cmd.Flags().GetBool("per-queue"); perqueue {
+		if len(args) == 0 {
+			cmd.Help()
+			os.Exit(1)
+		}
+		qargs := append([]string{args[0]}, sub_options(cmd, "per-queue")...)
+		os.Exit(ethtool.Run("per-queue", append(qargs, args[1:]...)))
+	}
+	/* handed over as the wait parameter of the restart */
+	if negotiate, _ := cmd.Flags().GetBool("negotiate"); negotiate &&
+		cmd.Flags().Changed("wait") {
+		wait, _ := cmd.Flags().GetUint("wait")
+		args = append(args, "wait", fmt.Sprint(wait))
+	}
+	for _, opt := range ethtool.Options() {
+		v := cmd.Flag(opt.Name)
+		if v.Value.String() == "true" {
+			os.Exit(ethtool.Run(opt.Name, args))
+		}
+	}
+
+	/* ethtool DEVNAME */
+	if len(args) != 1 {
+		cmd.Help()
+		os.Exit(1)
+	}
+	os.Exit(ethtool.Run("", args))
+}
+
 func main() {
-	// err := ethtool.Parse_args()
-	// if err != nil {
-	// 	fmt.Printf("For more information run ethtool -h\n")
-	// 	return
-	// }
-	ethtool.Execute()
-	// fmt.Println(a)
-	// ethtool.Do_actions()
-	// ethtool.Show_usage()
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
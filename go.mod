@@ -5,4 +5,5 @@ go 1.15
 require (
 	github.com/junka/ioctl v0.0.0-20210408135354-ea6f0ed5c5f5
 	github.com/spf13/cobra v1.1.3
+	github.com/spf13/pflag v1.0.5
 )
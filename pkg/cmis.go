@@ -0,0 +1,887 @@
+package ethtool
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+ * CMIS decoder: QSFP-DD, OSFP, DSFP and other modules implementing the
+ * Common Management Interface Specification, rev 4.0 and 5.x.
+ *
+ * CMIS modules are only reachable through paged module memory reads,
+ * see module_eeprom.go.
+ */
+
+const (
+	CMIS_MAX_BANKS         = 4
+	CMIS_CHANNELS_PER_BANK = 8
+	CMIS_MAX_CHANNEL_NUM   = CMIS_MAX_BANKS * CMIS_CHANNELS_PER_BANK
+	CMIS_MAX_PAGES         = 0x30
+
+	/* Lower memory */
+	CMIS_ID_OFFSET                     = 0x00
+	CMIS_REV_COMPLIANCE_OFFSET         = 0x01
+	CMIS_MEMORY_MODEL_OFFSET           = 0x02
+	CMIS_MEMORY_MODEL_MASK             = 0x80
+	CMIS_CLEI_PRESENT_MASK             = 0x20
+	CMIS_MODULE_STATE_OFFSET           = 0x03
+	CMIS_MODULE_STATE_MASK             = 0x0E
+	CMIS_MODULE_STATE_SHIFT            = 1
+	CMIS_MODULE_STATE_MODULE_LOW_PWR   = 0x01
+	CMIS_MODULE_STATE_MODULE_PWR_UP    = 0x02
+	CMIS_MODULE_STATE_MODULE_READY     = 0x03
+	CMIS_MODULE_STATE_MODULE_PWR_DN    = 0x04
+	CMIS_MODULE_STATE_MODULE_FAULT     = 0x05
+	CMIS_MODULE_FLAGS_OFFSET           = 0x09
+	CMIS_CURR_TEMP_OFFSET              = 0x0E
+	CMIS_CURR_VCC_OFFSET               = 0x10
+	CMIS_MODULE_CONTROL_OFFSET         = 0x1A
+	CMIS_LOW_PWR_ALLOW_REQUEST_HW_MASK = 0x40
+	CMIS_LOW_PWR_REQUEST_SW_MASK       = 0x10
+	CMIS_MODULE_ACTIVE_FW_MAJOR_OFFSET = 0x27
+	CMIS_MODULE_ACTIVE_FW_MINOR_OFFSET = 0x28
+	CMIS_MODULE_FAULT_OFFSET           = 0x29
+	CMIS_MEDIA_TYPE_OFFSET             = 0x55
+	CMIS_APP_DESC_START_OFFSET         = 0x56
+	CMIS_APP_DESC_SIZE                 = 4
+	CMIS_APP_DESC_NUM                  = 8
+	CMIS_APP_HOST_ID_END               = 0xFF
+
+	/* Upper page 00h */
+	CMIS_VENDOR_NAME_START_OFFSET = 0x81
+	CMIS_VENDOR_NAME_END_OFFSET   = 0x90
+	CMIS_VENDOR_OUI_OFFSET        = 0x91
+	CMIS_VENDOR_PN_START_OFFSET   = 0x94
+	CMIS_VENDOR_PN_END_OFFSET     = 0xA3
+	CMIS_VENDOR_REV_START_OFFSET  = 0xA4
+	CMIS_VENDOR_REV_END_OFFSET    = 0xA5
+	CMIS_VENDOR_SN_START_OFFSET   = 0xA6
+	CMIS_VENDOR_SN_END_OFFSET     = 0xB5
+	CMIS_DATE_YEAR_OFFSET         = 0xB6
+	CMIS_DATE_VENDOR_LOT_OFFSET   = 0xBC
+	CMIS_CLEI_START_OFFSET        = 0xBE
+	CMIS_CLEI_END_OFFSET          = 0xC7
+	CMIS_PWR_CLASS_OFFSET         = 0xC8
+	CMIS_PWR_CLASS_MASK           = 0xE0
+	CMIS_PWR_CLASS_SHIFT          = 5
+	CMIS_PWR_MAX_POWER_OFFSET     = 0xC9
+	CMIS_CBL_ASM_LEN_OFFSET       = 0xCA
+	CMIS_6300M_MAX_LEN            = 0xFF
+	CMIS_LEN_MUL_MASK             = 0xC0
+	CMIS_LEN_VAL_MASK             = 0x3F
+	CMIS_CTOR_OFFSET              = 0xCB
+	CMIS_COPPER_ATT_5GHZ          = 0xCC
+	CMIS_COPPER_ATT_7GHZ          = 0xCD
+	CMIS_COPPER_ATT_12P9GHZ       = 0xCE
+	CMIS_COPPER_ATT_25P8GHZ       = 0xCF
+	CMIS_MEDIA_INTF_TECH_OFFSET   = 0xD4
+	CMIS_COPPER_UNEQUAL           = 0x0A
+	CMIS_MEDIA_INTF_TECH_LAST     = 0x0F
+
+	/* Upper page 01h */
+	CMIS_MODULE_INACTIVE_FW_MAJOR_OFFSET = 0x80
+	CMIS_MODULE_INACTIVE_FW_MINOR_OFFSET = 0x81
+	CMIS_SMF_LEN_OFFSET                  = 0x84
+	CMIS_OM5_LEN_OFFSET                  = 0x85
+	CMIS_OM4_LEN_OFFSET                  = 0x86
+	CMIS_OM3_LEN_OFFSET                  = 0x87
+	CMIS_OM2_LEN_OFFSET                  = 0x88
+	CMIS_NOM_WAVELENGTH_MSB              = 0x8A
+	CMIS_WAVELENGTH_TOL_MSB              = 0x8C
+	CMIS_PAGES_ADVER_OFFSET              = 0x8E
+	CMIS_BANKS_SUPPORTED_MASK            = 0x03
+	CMIS_PAGES_ADVER_VDM                 = 0x40
+	CMIS_DIAG_CHAN_ADVER_OFFSET          = 0xA0
+	CMIS_TX_BIAS_MON_MASK                = 0x01
+	CMIS_TX_PWR_MON_MASK                 = 0x02
+	CMIS_RX_PWR_MON_MASK                 = 0x04
+	CMIS_TX_BIAS_MUL_MASK                = 0x18
+	CMIS_TX_BIAS_MUL_SHIFT               = 3
+	CMIS_SIG_INTEG_TX_OFFSET             = 0xA1
+	CMIS_SIG_INTEG_RX_OFFSET             = 0xA2
+
+	/* Upper page 02h, module and lane thresholds */
+	CMIS_TEMP_HALRM_OFFSET    = 0x80
+	CMIS_TEMP_LALRM_OFFSET    = 0x82
+	CMIS_TEMP_HWARN_OFFSET    = 0x84
+	CMIS_TEMP_LWARN_OFFSET    = 0x86
+	CMIS_VCC_HALRM_OFFSET     = 0x88
+	CMIS_VCC_LALRM_OFFSET     = 0x8A
+	CMIS_VCC_HWARN_OFFSET     = 0x8C
+	CMIS_VCC_LWARN_OFFSET     = 0x8E
+	CMIS_TX_PWR_HALRM_OFFSET  = 0xB0
+	CMIS_TX_PWR_LALRM_OFFSET  = 0xB2
+	CMIS_TX_PWR_HWARN_OFFSET  = 0xB4
+	CMIS_TX_PWR_LWARN_OFFSET  = 0xB6
+	CMIS_TX_BIAS_HALRM_OFFSET = 0xB8
+	CMIS_TX_BIAS_LALRM_OFFSET = 0xBA
+	CMIS_TX_BIAS_HWARN_OFFSET = 0xBC
+	CMIS_TX_BIAS_LWARN_OFFSET = 0xBE
+	CMIS_RX_PWR_HALRM_OFFSET  = 0xC0
+	CMIS_RX_PWR_LALRM_OFFSET  = 0xC2
+	CMIS_RX_PWR_HWARN_OFFSET  = 0xC4
+	CMIS_RX_PWR_LWARN_OFFSET  = 0xC6
+
+	/* Upper page 11h, lane flags (one bit per lane) and monitors */
+	CMIS_TX_FAIL_OFFSET           = 0x87
+	CMIS_TX_LOS_OFFSET            = 0x88
+	CMIS_TX_CDR_LOL_OFFSET        = 0x89
+	CMIS_TX_ADAPT_EQ_FAIL_OFFSET  = 0x8A
+	CMIS_TX_PWR_AW_HALARM_OFFSET  = 0x8B
+	CMIS_TX_BIAS_AW_HALARM_OFFSET = 0x8F
+	CMIS_RX_LOS_OFFSET            = 0x93
+	CMIS_RX_CDR_LOL_OFFSET        = 0x94
+	CMIS_RX_PWR_AW_HALARM_OFFSET  = 0x95
+	CMIS_TX_PWR_OFFSET            = 0x9A
+	CMIS_TX_BIAS_OFFSET           = 0xAA
+	CMIS_RX_PWR_OFFSET            = 0xBA
+
+	/* Versatile Diagnostics Monitoring, pages 20h-2Fh */
+	CMIS_VDM_DESC_PAGE     = 0x20
+	CMIS_VDM_VALUE_PAGE    = 0x24
+	CMIS_VDM_ADVER_PAGE    = 0x2F
+	CMIS_VDM_GROUPS_OFFSET = 0x80
+	CMIS_VDM_GROUPS_MASK   = 0x03
+	CMIS_VDM_MAX_GROUPS    = 4
+	CMIS_VDM_GROUP_ENTRIES = 64
+	CMIS_VDM_LANE_MASK     = 0x0F
+)
+
+/* cmis_memory_map gives access to the pages of a module. Upper pages
+ * are indexed with their in-page offsets, 0x80 to 0xff, and are nil when
+ * the module does not implement them.
+ */
+type cmis_memory_map struct {
+	lower_memory []byte
+	upper_memory [CMIS_MAX_BANKS][CMIS_MAX_PAGES][]byte
+	num_banks    int
+}
+
+func (m *cmis_memory_map) page(bank int, page int) []byte {
+	return m.upper_memory[bank][page]
+}
+
+var cmis_module_states = map[uint8]string{
+	CMIS_MODULE_STATE_MODULE_LOW_PWR: "ModuleLowPwr",
+	CMIS_MODULE_STATE_MODULE_PWR_UP:  "ModulePwrUp",
+	CMIS_MODULE_STATE_MODULE_READY:   "ModuleReady",
+	CMIS_MODULE_STATE_MODULE_PWR_DN:  "ModulePwrDn",
+	CMIS_MODULE_STATE_MODULE_FAULT:   "ModuleFault",
+}
+
+var cmis_fault_causes = map[uint8]string{
+	0x00: "No fault detected / not supported",
+	0x01: "TEC runaway",
+	0x02: "Data memory corrupted",
+	0x03: "Program memory corrupted",
+}
+
+var cmis_media_intf_tech = []string{
+	"850 nm VCSEL",
+	"1310 nm VCSEL",
+	"1550 nm VCSEL",
+	"1310 nm FP",
+	"1310 nm DFB",
+	"1550 nm DFB",
+	"1310 nm EML",
+	"1550 nm EML",
+	"Others/Undefined",
+	"1490 nm DFB",
+	"Copper cable unequalized",
+	"Copper cable passive equalized",
+	"Copper cable, near and far end limiting active equalizers",
+	"Copper cable, far end limiting active equalizers",
+	"Copper cable, near end limiting active equalizers",
+	"Copper cable, linear active equalizers",
+}
+
+/* Module media types, the media interface codes of an application
+ * depend on them
+ */
+const (
+	CMIS_MEDIA_TYPE_UNDEFINED = 0x00
+	CMIS_MEDIA_TYPE_MMF       = 0x01
+	CMIS_MEDIA_TYPE_SMF       = 0x02
+	CMIS_MEDIA_TYPE_COPPER    = 0x03
+	CMIS_MEDIA_TYPE_ACTIVE    = 0x04
+	CMIS_MEDIA_TYPE_BASE_T    = 0x05
+)
+
+var cmis_media_types = map[uint8]string{
+	CMIS_MEDIA_TYPE_UNDEFINED: "Undefined",
+	CMIS_MEDIA_TYPE_MMF:       "Optical Interfaces: MMF",
+	CMIS_MEDIA_TYPE_SMF:       "Optical Interfaces: SMF",
+	CMIS_MEDIA_TYPE_COPPER:    "Passive Copper Cables",
+	CMIS_MEDIA_TYPE_ACTIVE:    "Active Cables",
+	CMIS_MEDIA_TYPE_BASE_T:    "BASE-T",
+}
+
+/* Host electrical interface codes, SFF-8024 table 4-5 */
+var sff8024_host_intf_codes = map[uint8]string{
+	0x01: "1000BASE-CX",
+	0x02: "XAUI",
+	0x03: "XFI",
+	0x04: "SFI",
+	0x05: "25GAUI C2M",
+	0x06: "XLAUI C2M",
+	0x07: "XLPPI",
+	0x08: "LAUI-2 C2M",
+	0x09: "50GAUI-2 C2M",
+	0x0A: "50GAUI-1 C2M",
+	0x0B: "CAUI-4 C2M",
+	0x0C: "100GAUI-4 C2M",
+	0x0D: "100GAUI-2 C2M",
+	0x0E: "200GAUI-8 C2M",
+	0x0F: "200GAUI-4 C2M",
+	0x10: "400GAUI-16 C2M",
+	0x11: "400GAUI-8 C2M",
+	0x4B: "100GAUI-1-S C2M",
+	0x4C: "100GAUI-1-L C2M",
+	0x4F: "200GAUI-2-S C2M",
+	0x50: "200GAUI-2-L C2M",
+	0x51: "400GAUI-4-S C2M",
+	0x52: "400GAUI-4-L C2M",
+}
+
+/* Media interface codes by media type, SFF-8024 tables 4-6 to 4-10 */
+var sff8024_media_intf_codes = map[uint8]map[uint8]string{
+	CMIS_MEDIA_TYPE_MMF: {
+		0x01: "10GBASE-SW",
+		0x02: "10GBASE-SR",
+		0x03: "25GBASE-SR",
+		0x04: "40GBASE-SR4",
+		0x05: "40GE SWDM4 MSA",
+		0x06: "40GE BiDi",
+		0x07: "50GBASE-SR",
+		0x08: "100GBASE-SR10",
+		0x09: "100GBASE-SR4",
+		0x0A: "100GE SWDM4 MSA",
+		0x0B: "100GE BiDi",
+		0x0C: "100GBASE-SR2",
+		0x0D: "100G-SR",
+		0x0E: "200GBASE-SR4",
+		0x0F: "400GBASE-SR16",
+		0x10: "400GBASE-SR8",
+		0x11: "400G-SR4",
+		0x12: "800G-SR8",
+		0x1A: "400GBASE-SR4.2",
+	},
+	CMIS_MEDIA_TYPE_SMF: {
+		0x01: "10GBASE-LW",
+		0x02: "10GBASE-EW",
+		0x03: "10G-ZW",
+		0x04: "10GBASE-LR",
+		0x05: "10GBASE-ER",
+		0x06: "10G-ZR",
+		0x07: "25GBASE-LR",
+		0x08: "25GBASE-ER",
+		0x09: "40GBASE-LR4",
+		0x0A: "40GBASE-FR",
+		0x0B: "50GBASE-FR",
+		0x0C: "50GBASE-LR",
+		0x0D: "100GBASE-LR4",
+		0x0E: "100GBASE-ER4",
+		0x0F: "100G PSM4 MSA",
+		0x10: "100G CWDM4 MSA",
+		0x11: "100G 4WDM-10 MSA",
+		0x12: "100G 4WDM-20 MSA",
+		0x13: "100G 4WDM-40 MSA",
+		0x14: "100GBASE-DR",
+		0x15: "100G-FR/100GBASE-FR1",
+		0x16: "100G-LR/100GBASE-LR1",
+		0x17: "200GBASE-DR4",
+		0x18: "200GBASE-FR4",
+		0x19: "200GBASE-LR4",
+		0x1A: "400GBASE-FR8",
+		0x1B: "400GBASE-LR8",
+		0x1C: "400GBASE-DR4",
+		0x1D: "400G-FR4/400GBASE-FR4",
+		0x1E: "400G-LR4-10",
+	},
+	CMIS_MEDIA_TYPE_COPPER: {
+		0x01: "Copper cable",
+	},
+	CMIS_MEDIA_TYPE_ACTIVE: {
+		0x01: "Active Cable assembly with BER < 1e-12",
+		0x02: "Active Cable assembly with BER < 5e-5",
+		0x03: "Active Cable assembly with BER < 2.6e-4",
+		0x04: "Active Cable assembly with BER < 1e-6",
+	},
+	CMIS_MEDIA_TYPE_BASE_T: {
+		0x01: "1000BASE-T",
+		0x02: "2.5GBASE-T",
+		0x03: "5GBASE-T",
+		0x04: "10GBASE-T",
+	},
+}
+
+/* cmis_vdm_f16 decodes the VDM F16 format: a 5-bit exponent biased by
+ * 24 and an 11-bit mantissa, value = mantissa * 10^(exponent - 24)
+ */
+func cmis_vdm_f16(raw uint16) float64 {
+	exp := int(raw >> 11)
+	mant := float64(raw & 0x7ff)
+	return mant * math.Pow10(exp-24)
+}
+
+/* VDM observable types, CMIS 5 table 8-165 */
+var cmis_vdm_observables = map[uint8]struct {
+	name string
+	show func(raw uint16) string
+}{
+	1: {"Laser age", func(raw uint16) string {
+		return fmt.Sprintf("%d%%", raw)
+	}},
+	2: {"TEC current", func(raw uint16) string {
+		return fmt.Sprintf("%.2f%%", float64(int16(raw))*100/32767)
+	}},
+	3: {"Laser frequency error", func(raw uint16) string {
+		return fmt.Sprintf("%d MHz", int(int16(raw))*10)
+	}},
+	4: {"Laser temperature", func(raw uint16) string {
+		return fmt.Sprintf("%.2f degrees C", float64(int16(raw))/256)
+	}},
+	5:  {"eSNR media input", cmis_vdm_show_db},
+	6:  {"eSNR host input", cmis_vdm_show_db},
+	7:  {"PAM4 LTP media input", cmis_vdm_show_db},
+	8:  {"PAM4 LTP host input", cmis_vdm_show_db},
+	9:  {"Pre-FEC BER min media input", cmis_vdm_show_ber},
+	10: {"Pre-FEC BER min host input", cmis_vdm_show_ber},
+	11: {"Pre-FEC BER max media input", cmis_vdm_show_ber},
+	12: {"Pre-FEC BER max host input", cmis_vdm_show_ber},
+	13: {"Pre-FEC BER avg media input", cmis_vdm_show_ber},
+	14: {"Pre-FEC BER avg host input", cmis_vdm_show_ber},
+	15: {"Pre-FEC BER cur media input", cmis_vdm_show_ber},
+	16: {"Pre-FEC BER cur host input", cmis_vdm_show_ber},
+}
+
+func cmis_vdm_show_db(raw uint16) string {
+	return fmt.Sprintf("%.2f dB", float64(raw)/256)
+}
+
+func cmis_vdm_show_ber(raw uint16) string {
+	return fmt.Sprintf("%.2e", cmis_vdm_f16(raw))
+}
+
+func cmis_show_identifier(m *cmis_memory_map) {
+	sff8024_show_identifier(m.lower_memory, CMIS_ID_OFFSET)
+}
+
+func cmis_show_connector(m *cmis_memory_map) {
+	sff8024_show_connector(m.page(0, 0x00), CMIS_CTOR_OFFSET)
+}
+
+func cmis_show_oui(m *cmis_memory_map) {
+	id := m.page(0, 0x00)
+	fmt.Printf("\t%-41s : %02x:%02x:%02x\n", "Vendor OUI",
+		id[CMIS_VENDOR_OUI_OFFSET], id[CMIS_VENDOR_OUI_OFFSET+1],
+		id[CMIS_VENDOR_OUI_OFFSET+2])
+}
+
+func cmis_show_rev_compliance(m *cmis_memory_map) {
+	rev := m.lower_memory[CMIS_REV_COMPLIANCE_OFFSET]
+	fmt.Printf("\t%-41s : Rev. %d.%d\n", "Revision compliance",
+		rev>>4, rev&0x0f)
+}
+
+func cmis_show_power_info(m *cmis_memory_map) {
+	id := m.page(0, 0x00)
+
+	/* Power class is in the 3 most significant bits */
+	base_power := (id[CMIS_PWR_CLASS_OFFSET] & CMIS_PWR_CLASS_MASK) >>
+		CMIS_PWR_CLASS_SHIFT
+	/* The real power is in 0.25W units */
+	max_power := float64(id[CMIS_PWR_MAX_POWER_OFFSET]) * 0.25
+
+	fmt.Printf("\t%-41s : %d\n", "Power class", base_power+1)
+	fmt.Printf("\t%-41s : %.02fW\n", "Max power", max_power)
+}
+
+/* cmis_len_multiplier returns the unit of a length register, in meters
+ * for cable assemblies and in kilometers for SMF links
+ */
+func cmis_len_multiplier(v uint8) float64 {
+	switch v & CMIS_LEN_MUL_MASK {
+	case 0x00:
+		return 0.1
+	case 0x40:
+		return 1
+	case 0x80:
+		return 10
+	default:
+		return 100
+	}
+}
+
+func cmis_show_cbl_asm_len(m *cmis_memory_map) {
+	fn := "Cable assembly length"
+	v := m.page(0, 0x00)[CMIS_CBL_ASM_LEN_OFFSET]
+
+	if v == CMIS_6300M_MAX_LEN {
+		fmt.Printf("\t%-41s : > 6.3km\n", fn)
+		return
+	}
+	fmt.Printf("\t%-41s : %0.2fm\n", fn,
+		float64(v&CMIS_LEN_VAL_MASK)*cmis_len_multiplier(v))
+}
+
+func cmis_show_sig_integrity(m *cmis_memory_map) {
+	p := m.page(0, 0x01)
+	if p == nil {
+		return
+	}
+
+	/* CDR bypass control is bit 1, CDR implementation bit 0 */
+	fmt.Printf("\t%-41s : %s\n", "Tx CDR bypass control",
+		yesno(p[CMIS_SIG_INTEG_TX_OFFSET]&0x02 != 0))
+	fmt.Printf("\t%-41s : %s\n", "Rx CDR bypass control",
+		yesno(p[CMIS_SIG_INTEG_RX_OFFSET]&0x02 != 0))
+	fmt.Printf("\t%-41s : %s\n", "Tx CDR",
+		yesno(p[CMIS_SIG_INTEG_TX_OFFSET]&0x01 != 0))
+	fmt.Printf("\t%-41s : %s\n", "Rx CDR",
+		yesno(p[CMIS_SIG_INTEG_RX_OFFSET]&0x01 != 0))
+}
+
+func cmis_show_mit_compliance(m *cmis_memory_map) {
+	id := m.page(0, 0x00)
+	tech := id[CMIS_MEDIA_INTF_TECH_OFFSET]
+
+	fmt.Printf("\t%-41s : 0x%02x", "Transmitter technology", tech)
+	if tech <= CMIS_MEDIA_INTF_TECH_LAST {
+		fmt.Printf(" (%s)\n", cmis_media_intf_tech[tech])
+	} else {
+		fmt.Printf(" (reserved or unknown)\n")
+	}
+
+	if tech >= CMIS_COPPER_UNEQUAL && tech <= CMIS_MEDIA_INTF_TECH_LAST {
+		fmt.Printf("\t%-41s : %ddb\n", "Attenuation at 5GHz",
+			id[CMIS_COPPER_ATT_5GHZ])
+		fmt.Printf("\t%-41s : %ddb\n", "Attenuation at 7GHz",
+			id[CMIS_COPPER_ATT_7GHZ])
+		fmt.Printf("\t%-41s : %ddb\n", "Attenuation at 12.9GHz",
+			id[CMIS_COPPER_ATT_12P9GHZ])
+		fmt.Printf("\t%-41s : %ddb\n", "Attenuation at 25.8GHz",
+			id[CMIS_COPPER_ATT_25P8GHZ])
+	} else if p := m.page(0, 0x01); p != nil {
+		fmt.Printf("\t%-41s : %.3fnm\n", "Laser wavelength",
+			float64(sff_u16(p, CMIS_NOM_WAVELENGTH_MSB))*0.05)
+		fmt.Printf("\t%-41s : %.3fnm\n", "Laser wavelength tolerance",
+			float64(sff_u16(p, CMIS_WAVELENGTH_TOL_MSB))*0.005)
+	}
+}
+
+func cmis_show_link_len(m *cmis_memory_map) {
+	p := m.page(0, 0x01)
+	if p == nil {
+		return
+	}
+
+	/* Only the 0.1km and 1km multipliers are defined for SMF */
+	v := p[CMIS_SMF_LEN_OFFSET]
+	mul := 0.0
+	if v&CMIS_LEN_MUL_MASK <= 0x40 {
+		mul = cmis_len_multiplier(v)
+	}
+	fmt.Printf("\t%-41s : %0.2fkm\n", "Length (SMF)",
+		float64(v&CMIS_LEN_VAL_MASK)*mul)
+
+	sff_show_value_with_unit(p, CMIS_OM5_LEN_OFFSET, "Length (OM5)", 2, "m")
+	sff_show_value_with_unit(p, CMIS_OM4_LEN_OFFSET, "Length (OM4)", 2, "m")
+	sff_show_value_with_unit(p, CMIS_OM3_LEN_OFFSET,
+		"Length (OM3 50/125um)", 2, "m")
+	sff_show_value_with_unit(p, CMIS_OM2_LEN_OFFSET,
+		"Length (OM2 50/125um)", 1, "m")
+}
+
+func cmis_show_vendor_info(m *cmis_memory_map) {
+	id := m.page(0, 0x00)
+
+	sff_show_ascii(id, CMIS_VENDOR_NAME_START_OFFSET,
+		CMIS_VENDOR_NAME_END_OFFSET, "Vendor name")
+	cmis_show_oui(m)
+	sff_show_ascii(id, CMIS_VENDOR_PN_START_OFFSET,
+		CMIS_VENDOR_PN_END_OFFSET, "Vendor PN")
+	sff_show_ascii(id, CMIS_VENDOR_REV_START_OFFSET,
+		CMIS_VENDOR_REV_END_OFFSET, "Vendor rev")
+	sff_show_ascii(id, CMIS_VENDOR_SN_START_OFFSET,
+		CMIS_VENDOR_SN_END_OFFSET, "Vendor SN")
+	sff_show_ascii(id, CMIS_DATE_YEAR_OFFSET,
+		CMIS_DATE_VENDOR_LOT_OFFSET+1, "Date code")
+
+	if m.lower_memory[CMIS_MEMORY_MODEL_OFFSET]&CMIS_CLEI_PRESENT_MASK != 0 {
+		sff_show_ascii(id, CMIS_CLEI_START_OFFSET,
+			CMIS_CLEI_END_OFFSET, "CLEI code")
+	}
+}
+
+func cmis_show_fw_version(m *cmis_memory_map) {
+	fmt.Printf("\t%-41s : %d.%d\n", "Active firmware version",
+		m.lower_memory[CMIS_MODULE_ACTIVE_FW_MAJOR_OFFSET],
+		m.lower_memory[CMIS_MODULE_ACTIVE_FW_MINOR_OFFSET])
+
+	p := m.page(0, 0x01)
+	if p == nil {
+		return
+	}
+	fmt.Printf("\t%-41s : %d.%d\n", "Inactive firmware version",
+		p[CMIS_MODULE_INACTIVE_FW_MAJOR_OFFSET],
+		p[CMIS_MODULE_INACTIVE_FW_MINOR_OFFSET])
+}
+
+func cmis_show_mod_state(m *cmis_memory_map) {
+	state := (m.lower_memory[CMIS_MODULE_STATE_OFFSET] &
+		CMIS_MODULE_STATE_MASK) >> CMIS_MODULE_STATE_SHIFT
+
+	fmt.Printf("\t%-41s : 0x%02x", "Module State", state)
+	if desc, ok := cmis_module_states[state]; ok {
+		fmt.Printf(" (%s)\n", desc)
+	} else {
+		fmt.Printf(" (reserved or unknown)\n")
+	}
+
+	/* The fault cause is only meaningful in the ModuleFault state */
+	if state != CMIS_MODULE_STATE_MODULE_FAULT {
+		return
+	}
+	cause := m.lower_memory[CMIS_MODULE_FAULT_OFFSET]
+	fmt.Printf("\t%-41s : 0x%02x", "Module Fault Cause", cause)
+	if desc, ok := cmis_fault_causes[cause]; ok {
+		fmt.Printf(" (%s)\n", desc)
+	} else {
+		fmt.Printf(" (reserved or unknown)\n")
+	}
+}
+
+func cmis_show_mod_lvl_controls(m *cmis_memory_map) {
+	ctrl := m.lower_memory[CMIS_MODULE_CONTROL_OFFSET]
+
+	fmt.Printf("\t%-41s : %s\n", "LowPwrAllowRequestHW",
+		onoff(ctrl&CMIS_LOW_PWR_ALLOW_REQUEST_HW_MASK != 0))
+	fmt.Printf("\t%-41s : %s\n", "LowPwrRequestSW",
+		onoff(ctrl&CMIS_LOW_PWR_REQUEST_SW_MASK != 0))
+}
+
+/* cmis_show_applications lists the application descriptors the module
+ * advertises in lower memory, the list ends at the first unused one
+ */
+func cmis_show_applications(m *cmis_memory_map) {
+	lower := m.lower_memory
+	media := lower[CMIS_MEDIA_TYPE_OFFSET]
+
+	fmt.Printf("\t%-41s : 0x%02x", "Media type", media)
+	if desc, ok := cmis_media_types[media]; ok {
+		fmt.Printf(" (%s)\n", desc)
+	} else {
+		fmt.Printf(" (reserved or unknown)\n")
+	}
+
+	for i := 0; i < CMIS_APP_DESC_NUM; i++ {
+		app := lower[CMIS_APP_DESC_START_OFFSET+i*CMIS_APP_DESC_SIZE:]
+		if app[0] == CMIS_APP_HOST_ID_END || app[0] == 0 {
+			break
+		}
+
+		host, ok := sff8024_host_intf_codes[app[0]]
+		if !ok {
+			host = fmt.Sprintf("unknown host interface 0x%02x", app[0])
+		}
+		media_intf, ok := sff8024_media_intf_codes[media][app[1]]
+		if !ok {
+			media_intf = fmt.Sprintf("unknown media interface 0x%02x", app[1])
+		}
+
+		fmt.Printf("\t%-41s : %s / %s\n",
+			fmt.Sprintf("Application %d", i+1), host, media_intf)
+		fmt.Printf("\t%-41s : %d host, %d media, "+
+			"host lane assignment 0x%02x\n",
+			fmt.Sprintf("Application %d lanes", i+1),
+			app[2]>>4, app[2]&0x0f, app[3])
+	}
+}
+
+func cmis_parse_dom(m *cmis_memory_map, sd *sff_diags) {
+	lower := m.lower_memory
+
+	/* Module level monitors */
+	sd.sfp_temp[MCURR] = int16(sff_u16(lower, CMIS_CURR_TEMP_OFFSET))
+	sd.sfp_voltage[MCURR] = sff_u16(lower, CMIS_CURR_VCC_OFFSET)
+
+	p01 := m.page(0, 0x01)
+	if p01 == nil {
+		return
+	}
+	/* Bias current is reported in 2uA units times this multiplier */
+	mul := uint16(1) << ((p01[CMIS_DIAG_CHAN_ADVER_OFFSET] &
+		CMIS_TX_BIAS_MUL_MASK) >> CMIS_TX_BIAS_MUL_SHIFT)
+
+	/* Lane monitors, 8 lanes per bank */
+	for bank := 0; bank < m.num_banks; bank++ {
+		p := m.page(bank, 0x11)
+		if p == nil {
+			continue
+		}
+		for i := 0; i < CMIS_CHANNELS_PER_BANK; i++ {
+			scd := &sd.scd[bank*CMIS_CHANNELS_PER_BANK+i]
+			scd.bias_cur = sff_u16(p, CMIS_TX_BIAS_OFFSET+2*i) * mul
+			scd.tx_power = sff_u16(p, CMIS_TX_PWR_OFFSET+2*i)
+			scd.rx_power = sff_u16(p, CMIS_RX_PWR_OFFSET+2*i)
+		}
+	}
+
+	/* Thresholds */
+	p02 := m.page(0, 0x02)
+	if p02 == nil {
+		return
+	}
+	sd.supports_alarms = true
+
+	sd.sfp_temp[HALRM] = int16(sff_u16(p02, CMIS_TEMP_HALRM_OFFSET))
+	sd.sfp_temp[LALRM] = int16(sff_u16(p02, CMIS_TEMP_LALRM_OFFSET))
+	sd.sfp_temp[HWARN] = int16(sff_u16(p02, CMIS_TEMP_HWARN_OFFSET))
+	sd.sfp_temp[LWARN] = int16(sff_u16(p02, CMIS_TEMP_LWARN_OFFSET))
+
+	sd.sfp_voltage[HALRM] = sff_u16(p02, CMIS_VCC_HALRM_OFFSET)
+	sd.sfp_voltage[LALRM] = sff_u16(p02, CMIS_VCC_LALRM_OFFSET)
+	sd.sfp_voltage[HWARN] = sff_u16(p02, CMIS_VCC_HWARN_OFFSET)
+	sd.sfp_voltage[LWARN] = sff_u16(p02, CMIS_VCC_LWARN_OFFSET)
+
+	sd.tx_power[HALRM] = sff_u16(p02, CMIS_TX_PWR_HALRM_OFFSET)
+	sd.tx_power[LALRM] = sff_u16(p02, CMIS_TX_PWR_LALRM_OFFSET)
+	sd.tx_power[HWARN] = sff_u16(p02, CMIS_TX_PWR_HWARN_OFFSET)
+	sd.tx_power[LWARN] = sff_u16(p02, CMIS_TX_PWR_LWARN_OFFSET)
+
+	sd.bias_cur[HALRM] = sff_u16(p02, CMIS_TX_BIAS_HALRM_OFFSET) * mul
+	sd.bias_cur[LALRM] = sff_u16(p02, CMIS_TX_BIAS_LALRM_OFFSET) * mul
+	sd.bias_cur[HWARN] = sff_u16(p02, CMIS_TX_BIAS_HWARN_OFFSET) * mul
+	sd.bias_cur[LWARN] = sff_u16(p02, CMIS_TX_BIAS_LWARN_OFFSET) * mul
+
+	sd.rx_power[HALRM] = sff_u16(p02, CMIS_RX_PWR_HALRM_OFFSET)
+	sd.rx_power[LALRM] = sff_u16(p02, CMIS_RX_PWR_LALRM_OFFSET)
+	sd.rx_power[HWARN] = sff_u16(p02, CMIS_RX_PWR_HWARN_OFFSET)
+	sd.rx_power[LWARN] = sff_u16(p02, CMIS_RX_PWR_LWARN_OFFSET)
+}
+
+func cmis_show_flag(name string, on bool) {
+	state := "Off"
+	if on {
+		state = "On"
+	}
+	fmt.Printf("\t%-41s : %s\n", name, state)
+}
+
+func cmis_show_flags(m *cmis_memory_map) {
+	levels := []string{"high alarm", "low alarm", "high warning", "low warning"}
+
+	/* Temperature flags are the low nibble of the module flags byte,
+	 * voltage flags the high nibble
+	 */
+	flags := m.lower_memory[CMIS_MODULE_FLAGS_OFFSET]
+	for i, l := range levels {
+		cmis_show_flag("Module temperature "+l, flags&(1<<i) != 0)
+	}
+	for i, l := range levels {
+		cmis_show_flag("Module voltage "+l, flags&(1<<(4+i)) != 0)
+	}
+
+	lane_flags := []struct {
+		name   string
+		offset int
+	}{
+		{"Tx fault", CMIS_TX_FAIL_OFFSET},
+		{"Tx loss of signal", CMIS_TX_LOS_OFFSET},
+		{"Tx CDR loss of lock", CMIS_TX_CDR_LOL_OFFSET},
+		{"Tx adaptive eq fault", CMIS_TX_ADAPT_EQ_FAIL_OFFSET},
+		{"Rx loss of signal", CMIS_RX_LOS_OFFSET},
+		{"Rx CDR loss of lock", CMIS_RX_CDR_LOL_OFFSET},
+	}
+	/* One byte per level, in the same order as levels */
+	lane_aw_flags := []struct {
+		name   string
+		offset int
+	}{
+		{"Laser bias current", CMIS_TX_BIAS_AW_HALARM_OFFSET},
+		{"Laser tx power", CMIS_TX_PWR_AW_HALARM_OFFSET},
+		{"Laser rx power", CMIS_RX_PWR_AW_HALARM_OFFSET},
+	}
+
+	for bank := 0; bank < m.num_banks; bank++ {
+		p := m.page(bank, 0x11)
+		if p == nil {
+			continue
+		}
+		for i := 0; i < CMIS_CHANNELS_PER_BANK; i++ {
+			ch := bank*CMIS_CHANNELS_PER_BANK + i + 1
+			for _, f := range lane_flags {
+				cmis_show_flag(fmt.Sprintf("%s (Chan %d)", f.name, ch),
+					p[f.offset]&(1<<i) != 0)
+			}
+			for _, f := range lane_aw_flags {
+				for j, l := range levels {
+					cmis_show_flag(fmt.Sprintf("%s %-12s (Chan %d)",
+						f.name, l, ch), p[f.offset+j]&(1<<i) != 0)
+				}
+			}
+		}
+	}
+}
+
+func cmis_show_dom(m *cmis_memory_map) {
+	var sd sff_diags
+
+	cmis_parse_dom(m, &sd)
+
+	sff_print_temp("Module temperature", sd.sfp_temp[MCURR])
+	sff_print_vcc("Module voltage", sd.sfp_voltage[MCURR])
+
+	/* Flat memory modules have no lane monitors */
+	p01 := m.page(0, 0x01)
+	if p01 == nil {
+		return
+	}
+
+	adver := p01[CMIS_DIAG_CHAN_ADVER_OFFSET]
+	channels := m.num_banks * CMIS_CHANNELS_PER_BANK
+	if adver&CMIS_TX_BIAS_MON_MASK != 0 {
+		for i := 0; i < channels; i++ {
+			sff_print_bias(fmt.Sprintf("%s (Channel %d)",
+				"Laser tx bias current", i+1), sd.scd[i].bias_cur)
+		}
+	}
+	if adver&CMIS_TX_PWR_MON_MASK != 0 {
+		for i := 0; i < channels; i++ {
+			sff_print_power(fmt.Sprintf("%s (Channel %d)",
+				"Transmit avg optical power", i+1), sd.scd[i].tx_power)
+		}
+	}
+	if adver&CMIS_RX_PWR_MON_MASK != 0 {
+		for i := 0; i < channels; i++ {
+			sff_print_power(fmt.Sprintf("%s (Channel %d)",
+				"Rcvr signal avg optical power", i+1), sd.scd[i].rx_power)
+		}
+	}
+
+	fmt.Printf("\t%-41s : %s\n", "Alarm/warning flags implemented",
+		yesno(sd.supports_alarms))
+	if sd.supports_alarms {
+		cmis_show_flags(m)
+		sff_show_thresholds(&sd)
+	}
+}
+
+/* cmis_show_vdm prints the real time values of the observables the
+ * module describes in its VDM descriptor pages
+ */
+func cmis_show_vdm(m *cmis_memory_map) {
+	for g := 0; g < CMIS_VDM_MAX_GROUPS; g++ {
+		desc := m.page(0, CMIS_VDM_DESC_PAGE+g)
+		vals := m.page(0, CMIS_VDM_VALUE_PAGE+g)
+		if desc == nil || vals == nil {
+			continue
+		}
+		for i := 0; i < CMIS_VDM_GROUP_ENTRIES; i++ {
+			off := ETH_MODULE_EEPROM_PAGE_LEN + 2*i
+			tp := desc[off+1]
+			if tp == 0 {
+				continue
+			}
+			lane := int(desc[off]&CMIS_VDM_LANE_MASK) + 1
+			raw := sff_u16(vals, off)
+
+			obs, ok := cmis_vdm_observables[tp]
+			if !ok {
+				fmt.Printf("\t%-41s : 0x%04x\n", fmt.Sprintf(
+					"VDM type %d (Lane %d)", tp, lane), raw)
+				continue
+			}
+			fmt.Printf("\t%-41s : %s\n",
+				fmt.Sprintf("%s (Lane %d)", obs.name, lane), obs.show(raw))
+		}
+	}
+}
+
+func cmis_show_all(m *cmis_memory_map) {
+	cmis_show_identifier(m)
+	cmis_show_power_info(m)
+	cmis_show_connector(m)
+	cmis_show_cbl_asm_len(m)
+	cmis_show_sig_integrity(m)
+	cmis_show_mit_compliance(m)
+	cmis_show_applications(m)
+	cmis_show_link_len(m)
+	cmis_show_vendor_info(m)
+	cmis_show_rev_compliance(m)
+	cmis_show_fw_version(m)
+	cmis_show_mod_state(m)
+	cmis_show_mod_lvl_controls(m)
+	cmis_show_dom(m)
+	cmis_show_vdm(m)
+}
+
+/* cmis_read fetches the pages the decoder looks at: page 00h always,
+ * then for paged modules pages 01h and 02h, the lane pages 10h and 11h
+ * of every bank, and the VDM pages if the module advertises them. Pages
+ * that cannot be read are left nil.
+ */
+func cmis_read(read module_reader) (*cmis_memory_map, error) {
+	var m cmis_memory_map
+	var err error
+
+	m.num_banks = 1
+	m.lower_memory, err = module_lower_page(read, ETH_I2C_ADDRESS_LOW)
+	if err != nil {
+		return nil, err
+	}
+	upper := func(bank int, page int) error {
+		p, err := module_upper_page(read, ETH_I2C_ADDRESS_LOW,
+			uint8(page), uint8(bank))
+		m.upper_memory[bank][page] = p
+		return err
+	}
+
+	err = upper(0, 0x00)
+	if err != nil {
+		return nil, err
+	}
+	/* Flat memory modules only have page 00h */
+	if m.lower_memory[CMIS_MEMORY_MODEL_OFFSET]&CMIS_MEMORY_MODEL_MASK != 0 {
+		return &m, nil
+	}
+
+	/* Only page 00h is mandatory to decode, show what we have if a
+	 * module (or a saved image) lacks any of the others.
+	 */
+	for _, page := range []int{0x01, 0x02} {
+		upper(0, page)
+	}
+	p01 := m.page(0, 0x01)
+	if p01 == nil {
+		return &m, nil
+	}
+	switch p01[CMIS_PAGES_ADVER_OFFSET] & CMIS_BANKS_SUPPORTED_MASK {
+	case 0x01:
+		m.num_banks = 2
+	case 0x02:
+		m.num_banks = 4
+	}
+	for bank := 0; bank < m.num_banks; bank++ {
+		for _, page := range []int{0x10, 0x11} {
+			upper(bank, page)
+		}
+	}
+
+	if p01[CMIS_PAGES_ADVER_OFFSET]&CMIS_PAGES_ADVER_VDM == 0 {
+		return &m, nil
+	}
+	err = upper(0, CMIS_VDM_ADVER_PAGE)
+	if err != nil {
+		return &m, nil
+	}
+	groups := int(m.page(0, CMIS_VDM_ADVER_PAGE)[CMIS_VDM_GROUPS_OFFSET]&
+		CMIS_VDM_GROUPS_MASK) + 1
+	for g := 0; g < groups; g++ {
+		upper(0, CMIS_VDM_DESC_PAGE+g)
+		upper(0, CMIS_VDM_VALUE_PAGE+g)
+	}
+	return &m, nil
+}
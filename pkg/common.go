@@ -1,6 +1,11 @@
 package ethtool
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
 
 const OFF_FLAG_DEF_SIZE = 12
 
@@ -32,10 +37,13 @@ const (
 		ETH_FLAG_RXHASH)
 )
 
+/* the kernel may use up to SCHAR_MAX words for each link mode mask */
+const ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32 = 127
+
 type link_mode struct {
-	supported      [127]byte
-	advertising    [127]byte
-	lp_advertising [127]byte
+	supported      [ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32]uint32
+	advertising    [ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32]uint32
+	lp_advertising [ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32]uint32
 }
 type ethtool_link_usettings struct {
 	// struct {
@@ -140,7 +148,7 @@ func print_flags(info []flag_info, n_info uint32, value uint32) {
 		if value&info[i].value > 0 {
 			fmt.Printf("%s%s", sep, string(info[i].name))
 			sep = " "
-			value &= ^info[0].value
+			value &= ^info[i].value
 		}
 		i++
 		n_info--
@@ -151,7 +159,7 @@ func print_flags(info []flag_info, n_info uint32, value uint32) {
 	}
 }
 
-func unparse_wolopts(wolopts int) []byte {
+func unparse_wolopts(wolopts uint32) []byte {
 	buf := make([]byte, 0)
 	if wolopts > 0 {
 		if wolopts&WAKE_PHY > 0 {
@@ -185,6 +193,58 @@ func unparse_wolopts(wolopts int) []byte {
 	return buf
 }
 
+/* parse_value_mask parses "value[/mask]", the mask defaults to all ones */
+func parse_value_mask(s string) (uint32, uint32, error) {
+	mask := uint64(0xffffffff)
+	parts := strings.SplitN(s, "/", 2)
+	value, err := strconv.ParseUint(parts[0], 0, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) == 2 {
+		mask, err = strconv.ParseUint(parts[1], 0, 32)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return uint32(value & mask), uint32(mask), nil
+}
+
+/* parse_wolopts is the inverse of unparse_wolopts, it also takes a
+ * numeric value[/mask]
+ */
+func parse_wolopts(optstr string) (uint32, uint32, error) {
+	if len(optstr) > 0 && optstr[0] >= '0' && optstr[0] <= '9' {
+		return parse_value_mask(optstr)
+	}
+	data := uint32(0)
+	for _, c := range optstr {
+		switch c {
+		case 'p':
+			data |= WAKE_PHY
+		case 'u':
+			data |= WAKE_UCAST
+		case 'm':
+			data |= WAKE_MCAST
+		case 'b':
+			data |= WAKE_BCAST
+		case 'a':
+			data |= WAKE_ARP
+		case 'g':
+			data |= WAKE_MAGIC
+		case 's':
+			data |= WAKE_MAGICSECURE
+		case 'f':
+			data |= WAKE_FILTER
+		case 'd':
+			data = 0
+		default:
+			return 0, 0, syscall.EINVAL
+		}
+	}
+	return data, 0xffffffff, nil
+}
+
 func dump_wol(wol *ethtool_wolinfo) {
 	fmt.Printf("	Supports Wake-on: %s\n", unparse_wolopts(wol.supported))
 	fmt.Printf("	Wake-on: %s\n", unparse_wolopts(wol.wolopts))
@@ -236,4 +296,20 @@ type cmd_context struct {
 	json       bool     /* Output JSON, if supported */
 	show_stats bool     /* include command-specific stats */
 	//netlink
+	nlctx *nl_context /* nil when the netlink interface is not in use */
+}
+
+// cstring converts a NUL padded C string to a Go string.
+func cstring(b []byte) string {
+	for i := 0; i < len(b); i++ {
+		if b[i] == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// gstring returns the i-th ETH_GSTRING_LEN sized entry of a string set.
+func gstring(strings *ethtool_gstrings, i uint32) string {
+	return cstring(strings.data[i*ETH_GSTRING_LEN : (i+1)*ETH_GSTRING_LEN])
 }
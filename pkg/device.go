@@ -0,0 +1,486 @@
+package ethtool
+
+import (
+	"fmt"
+	"math/bits"
+	"syscall"
+	"unsafe"
+)
+
+// Device is an open ethtool handle on one network interface.
+// All methods return typed results instead of printing them.
+type Device struct {
+	ctx *cmd_context
+}
+
+// DriverInfo describes the driver bound to a device (ETHTOOL_GDRVINFO).
+type DriverInfo struct {
+	Driver              string
+	Version             string
+	FirmwareVersion     string
+	ExpansionROMVersion string
+	BusInfo             string
+	NPrivFlags          uint32
+	NStats              uint32
+	TestInfoLen         uint32
+	EEDumpLen           uint32
+	RegDumpLen          uint32
+}
+
+// RingParam holds the RX/TX ring sizes (ETHTOOL_GRINGPARAM).
+type RingParam struct {
+	RxMaxPending      uint32
+	RxMiniMaxPending  uint32
+	RxJumboMaxPending uint32
+	TxMaxPending      uint32
+	RxPending         uint32
+	RxMiniPending     uint32
+	RxJumboPending    uint32
+	TxPending         uint32
+}
+
+// Channels holds the channel counts (ETHTOOL_GCHANNELS).
+type Channels struct {
+	MaxRx         uint32
+	MaxTx         uint32
+	MaxOther      uint32
+	MaxCombined   uint32
+	RxCount       uint32
+	TxCount       uint32
+	OtherCount    uint32
+	CombinedCount uint32
+}
+
+// Coalesce holds the interrupt coalescing parameters (ETHTOOL_GCOALESCE).
+type Coalesce struct {
+	RxCoalesceUsecs          uint32
+	RxMaxCoalescedFrames     uint32
+	RxCoalesceUsecsIrq       uint32
+	RxMaxCoalescedFramesIrq  uint32
+	TxCoalesceUsecs          uint32
+	TxMaxCoalescedFrames     uint32
+	TxCoalesceUsecsIrq       uint32
+	TxMaxCoalescedFramesIrq  uint32
+	StatsBlockCoalesceUsecs  uint32
+	UseAdaptiveRxCoalesce    uint32
+	UseAdaptiveTxCoalesce    uint32
+	PktRateLow               uint32
+	RxCoalesceUsecsLow       uint32
+	RxMaxCoalescedFramesLow  uint32
+	TxCoalesceUsecsLow       uint32
+	TxMaxCoalescedFramesLow  uint32
+	PktRateHigh              uint32
+	RxCoalesceUsecsHigh      uint32
+	RxMaxCoalescedFramesHigh uint32
+	TxCoalesceUsecsHigh      uint32
+	TxMaxCoalescedFramesHigh uint32
+	RateSampleInterval       uint32
+}
+
+// Feature is the state of one generic netdev feature (ETHTOOL_GFEATURES).
+type Feature struct {
+	Name         string
+	Available    bool
+	Requested    bool
+	Active       bool
+	NeverChanged bool
+}
+
+// Stat is one named driver statistic (ETHTOOL_GSTATS).
+type Stat struct {
+	Name  string
+	Value uint64
+}
+
+// Open returns a Device for the interface called name.
+// The caller must Close it when done.
+func Open(name string) (*Device, error) {
+	ctx := &cmd_context{devname: name}
+	err := open_ioctl(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Device{ctx: ctx}, nil
+}
+
+// Close releases the control socket of the device.
+func (d *Device) Close() error {
+	uninit_ioctl(d.ctx)
+	return nil
+}
+
+// Name returns the interface name the device was opened with.
+func (d *Device) Name() string {
+	return d.ctx.devname
+}
+
+// DriverInfo returns driver name, version and capability sizes.
+func (d *Device) DriverInfo() (DriverInfo, error) {
+	info, err := get_drvinfo(d.ctx)
+	if err != nil {
+		return DriverInfo{}, fmt.Errorf("cannot get driver information: %w", err)
+	}
+	return info, nil
+}
+
+/*
+ * The helpers below do the work of the Device methods and return the
+ * bare ioctl errors, the do_* handlers print them after their own
+ * messages.
+ */
+
+func get_drvinfo(ctx *cmd_context) (DriverInfo, error) {
+	drvinfo := ethtool_drvinfo{cmd: ETHTOOL_GDRVINFO}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&drvinfo)))
+	if err != nil {
+		return DriverInfo{}, err
+	}
+	return DriverInfo{
+		Driver:              cstring(drvinfo.driver[:]),
+		Version:             cstring(drvinfo.version[:]),
+		FirmwareVersion:     cstring(drvinfo.fw_version[:]),
+		ExpansionROMVersion: cstring(drvinfo.erom_version[:]),
+		BusInfo:             cstring(drvinfo.bus_info[:]),
+		NPrivFlags:          drvinfo.n_priv_flags,
+		NStats:              drvinfo.n_stats,
+		TestInfoLen:         drvinfo.testinfo_len,
+		EEDumpLen:           drvinfo.eedump_len,
+		RegDumpLen:          drvinfo.regdump_len,
+	}, nil
+}
+
+// Rings returns the current and maximum ring sizes.
+func (d *Device) Rings() (RingParam, error) {
+	r, err := get_rings(d.ctx)
+	if err != nil {
+		return RingParam{}, fmt.Errorf("cannot get device ring settings: %w", err)
+	}
+	return r, nil
+}
+
+func get_rings(ctx *cmd_context) (RingParam, error) {
+	ering := ethtool_ringparam{cmd: ETHTOOL_GRINGPARAM}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&ering)))
+	if err != nil {
+		return RingParam{}, err
+	}
+	return RingParam{
+		RxMaxPending:      ering.rx_max_pending,
+		RxMiniMaxPending:  ering.rx_mini_max_pending,
+		RxJumboMaxPending: ering.rx_jumbo_max_pending,
+		TxMaxPending:      ering.tx_max_pending,
+		RxPending:         ering.rx_pending,
+		RxMiniPending:     ering.rx_mini_pending,
+		RxJumboPending:    ering.rx_jumbo_pending,
+		TxPending:         ering.tx_pending,
+	}, nil
+}
+
+// SetRings applies the pending ring sizes of r. The maximums are ignored.
+func (d *Device) SetRings(r RingParam) error {
+	err := set_rings(d.ctx, r)
+	if err != nil {
+		return fmt.Errorf("cannot set device ring parameters: %w", err)
+	}
+	return nil
+}
+
+func set_rings(ctx *cmd_context, r RingParam) error {
+	ering := ethtool_ringparam{
+		cmd:              ETHTOOL_SRINGPARAM,
+		rx_pending:       r.RxPending,
+		rx_mini_pending:  r.RxMiniPending,
+		rx_jumbo_pending: r.RxJumboPending,
+		tx_pending:       r.TxPending,
+	}
+	return send_ioctl(ctx, uintptr(unsafe.Pointer(&ering)))
+}
+
+// Channels returns the current and maximum channel counts.
+func (d *Device) Channels() (Channels, error) {
+	c, err := get_channels(d.ctx)
+	if err != nil {
+		return Channels{}, fmt.Errorf("cannot get device channel parameters: %w", err)
+	}
+	return c, nil
+}
+
+func get_channels(ctx *cmd_context) (Channels, error) {
+	echannels := ethtool_channels{cmd: ETHTOOL_GCHANNELS}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&echannels)))
+	if err != nil {
+		return Channels{}, err
+	}
+	return Channels{
+		MaxRx:         echannels.max_rx,
+		MaxTx:         echannels.max_tx,
+		MaxOther:      echannels.max_other,
+		MaxCombined:   echannels.max_combined,
+		RxCount:       echannels.rx_count,
+		TxCount:       echannels.tx_count,
+		OtherCount:    echannels.other_count,
+		CombinedCount: echannels.combined_count,
+	}, nil
+}
+
+// SetChannels applies the channel counts of c. The maximums are ignored.
+func (d *Device) SetChannels(c Channels) error {
+	err := set_channels(d.ctx, c)
+	if err != nil {
+		return fmt.Errorf("cannot set device channel parameters: %w", err)
+	}
+	return nil
+}
+
+func set_channels(ctx *cmd_context, c Channels) error {
+	echannels := ethtool_channels{
+		cmd:            ETHTOOL_SCHANNELS,
+		rx_count:       c.RxCount,
+		tx_count:       c.TxCount,
+		other_count:    c.OtherCount,
+		combined_count: c.CombinedCount,
+	}
+	return send_ioctl(ctx, uintptr(unsafe.Pointer(&echannels)))
+}
+
+// Coalesce returns the interrupt coalescing parameters.
+func (d *Device) Coalesce() (Coalesce, error) {
+	c, err := get_coalesce(d.ctx)
+	if err != nil {
+		return Coalesce{}, fmt.Errorf("cannot get device coalesce settings: %w", err)
+	}
+	return c, nil
+}
+
+func get_coalesce(ctx *cmd_context) (Coalesce, error) {
+	ecoal := ethtool_coalesce{cmd: ETHTOOL_GCOALESCE}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&ecoal)))
+	if err != nil {
+		return Coalesce{}, err
+	}
+	return coalesce_from_ioctl(&ecoal), nil
+}
+
+// SetCoalesce changes the interrupt coalescing parameters.
+func (d *Device) SetCoalesce(c Coalesce) error {
+	err := set_coalesce(d.ctx, c)
+	if err != nil {
+		return fmt.Errorf("cannot set device coalesce parameters: %w", err)
+	}
+	return nil
+}
+
+func set_coalesce(ctx *cmd_context, c Coalesce) error {
+	ecoal := coalesce_to_ioctl(&c)
+	ecoal.cmd = ETHTOOL_SCOALESCE
+	return send_ioctl(ctx, uintptr(unsafe.Pointer(&ecoal)))
+}
+
+// PerQueueCoalesce returns the coalescing parameters of every queue set
+// in mask, lowest queue first. mask holds 32 queues per word.
+func (d *Device) PerQueueCoalesce(mask []uint32) ([]Coalesce, error) {
+	c, err := get_per_queue_coalesce(d.ctx, mask)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get device per queue parameters: %w", err)
+	}
+	return c, nil
+}
+
+func get_per_queue_coalesce(ctx *cmd_context, mask []uint32) ([]Coalesce, error) {
+	queues, err := per_queue_coalesce(ctx, mask, ETHTOOL_GCOALESCE, nil)
+	if err != nil {
+		return nil, err
+	}
+	c := make([]Coalesce, len(queues))
+	for i := range queues {
+		c[i] = coalesce_from_ioctl(&queues[i])
+	}
+	return c, nil
+}
+
+// SetPerQueueCoalesce changes the coalescing parameters of the queues set
+// in mask; c has one entry per queue, lowest queue first.
+func (d *Device) SetPerQueueCoalesce(mask []uint32, c []Coalesce) error {
+	err := set_per_queue_coalesce(d.ctx, mask, c)
+	if err != nil {
+		return fmt.Errorf("cannot set device per queue parameters: %w", err)
+	}
+	return nil
+}
+
+func set_per_queue_coalesce(ctx *cmd_context, mask []uint32, c []Coalesce) error {
+	_, err := per_queue_coalesce(ctx, mask, ETHTOOL_SCOALESCE, c)
+	return err
+}
+
+/* per_queue_coalesce issues ETHTOOL_PERQUEUE: the ethtool_per_queue_op
+ * header is followed by one ethtool_coalesce per queue in the mask.
+ */
+func per_queue_coalesce(ctx *cmd_context, mask []uint32, sub_command uint32,
+	c []Coalesce) ([]ethtool_coalesce, error) {
+	var op ethtool_per_queue_op
+	if len(mask) > len(op.queue_mask) {
+		return nil, syscall.EINVAL
+	}
+	n_queues := 0
+	for _, w := range mask {
+		n_queues += bits.OnesCount32(w)
+	}
+	if n_queues == 0 || (c != nil && len(c) != n_queues) {
+		return nil, syscall.EINVAL
+	}
+
+	hdr_len := int(unsafe.Sizeof(op))
+	coal_len := int(unsafe.Sizeof(ethtool_coalesce{}))
+	buf := make([]uint32, (hdr_len+n_queues*coal_len)/4)
+	per_queue_op := (*ethtool_per_queue_op)(unsafe.Pointer(&buf[0]))
+	per_queue_op.cmd = ETHTOOL_PERQUEUE
+	per_queue_op.sub_command = sub_command
+	copy(per_queue_op.queue_mask[:], mask)
+	queues := (*[MAX_NUM_QUEUE]ethtool_coalesce)(unsafe.Pointer(&buf[hdr_len/4]))[:n_queues:n_queues]
+	for i := range c {
+		queues[i] = coalesce_to_ioctl(&c[i])
+		queues[i].cmd = sub_command
+	}
+
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&buf[0])))
+	if err != nil {
+		return nil, err
+	}
+	return append([]ethtool_coalesce(nil), queues...), nil
+}
+
+func coalesce_from_ioctl(ecoal *ethtool_coalesce) Coalesce {
+	return Coalesce{
+		RxCoalesceUsecs:          ecoal.rx_coalesce_usecs,
+		RxMaxCoalescedFrames:     ecoal.rx_max_coalesced_frames,
+		RxCoalesceUsecsIrq:       ecoal.rx_coalesce_usecs_irq,
+		RxMaxCoalescedFramesIrq:  ecoal.rx_max_coalesced_frames_irq,
+		TxCoalesceUsecs:          ecoal.tx_coalesce_usecs,
+		TxMaxCoalescedFrames:     ecoal.tx_max_coalesced_frames,
+		TxCoalesceUsecsIrq:       ecoal.tx_coalesce_usecs_irq,
+		TxMaxCoalescedFramesIrq:  ecoal.tx_max_coalesced_frames_irq,
+		StatsBlockCoalesceUsecs:  ecoal.stats_block_coalesce_usecs,
+		UseAdaptiveRxCoalesce:    ecoal.use_adaptive_rx_coalesce,
+		UseAdaptiveTxCoalesce:    ecoal.use_adaptive_tx_coalesce,
+		PktRateLow:               ecoal.pkt_rate_low,
+		RxCoalesceUsecsLow:       ecoal.rx_coalesce_usecs_low,
+		RxMaxCoalescedFramesLow:  ecoal.rx_max_coalesced_frames_low,
+		TxCoalesceUsecsLow:       ecoal.tx_coalesce_usecs_low,
+		TxMaxCoalescedFramesLow:  ecoal.tx_max_coalesced_frames_low,
+		PktRateHigh:              ecoal.pkt_rate_high,
+		RxCoalesceUsecsHigh:      ecoal.rx_coalesce_usecs_high,
+		RxMaxCoalescedFramesHigh: ecoal.rx_max_coalesced_frames_high,
+		TxCoalesceUsecsHigh:      ecoal.tx_coalesce_usecs_high,
+		TxMaxCoalescedFramesHigh: ecoal.tx_max_coalesced_frames_high,
+		RateSampleInterval:       ecoal.rate_sample_interval,
+	}
+}
+
+func coalesce_to_ioctl(c *Coalesce) ethtool_coalesce {
+	return ethtool_coalesce{
+		rx_coalesce_usecs:            c.RxCoalesceUsecs,
+		rx_max_coalesced_frames:      c.RxMaxCoalescedFrames,
+		rx_coalesce_usecs_irq:        c.RxCoalesceUsecsIrq,
+		rx_max_coalesced_frames_irq:  c.RxMaxCoalescedFramesIrq,
+		tx_coalesce_usecs:            c.TxCoalesceUsecs,
+		tx_max_coalesced_frames:      c.TxMaxCoalescedFrames,
+		tx_coalesce_usecs_irq:        c.TxCoalesceUsecsIrq,
+		tx_max_coalesced_frames_irq:  c.TxMaxCoalescedFramesIrq,
+		stats_block_coalesce_usecs:   c.StatsBlockCoalesceUsecs,
+		use_adaptive_rx_coalesce:     c.UseAdaptiveRxCoalesce,
+		use_adaptive_tx_coalesce:     c.UseAdaptiveTxCoalesce,
+		pkt_rate_low:                 c.PktRateLow,
+		rx_coalesce_usecs_low:        c.RxCoalesceUsecsLow,
+		rx_max_coalesced_frames_low:  c.RxMaxCoalescedFramesLow,
+		tx_coalesce_usecs_low:        c.TxCoalesceUsecsLow,
+		tx_max_coalesced_frames_low:  c.TxMaxCoalescedFramesLow,
+		pkt_rate_high:                c.PktRateHigh,
+		rx_coalesce_usecs_high:       c.RxCoalesceUsecsHigh,
+		rx_max_coalesced_frames_high: c.RxMaxCoalescedFramesHigh,
+		tx_coalesce_usecs_high:       c.TxCoalesceUsecsHigh,
+		tx_max_coalesced_frames_high: c.TxMaxCoalescedFramesHigh,
+		rate_sample_interval:         c.RateSampleInterval,
+	}
+}
+
+// Features returns the state of every generic feature the device knows.
+func (d *Device) Features() ([]Feature, error) {
+	defs := get_feature_defs(d.ctx)
+	if defs.n_features == 0 {
+		return nil, fmt.Errorf("cannot get device feature names")
+	}
+	state := ethtool_gfeatures{
+		cmd:  ETHTOOL_GFEATURES,
+		size: uint32((defs.n_features + 32 - 1) / 32),
+	}
+	err := send_ioctl(d.ctx, uintptr(unsafe.Pointer(&state)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot get device generic features: %w", err)
+	}
+
+	features := make([]Feature, 0, defs.n_features)
+	for i := uint64(0); i < defs.n_features; i++ {
+		block := &state.features[i/32]
+		bit := uint32(1) << (i % 32)
+		features = append(features, Feature{
+			Name:         cstring(defs.def[i].name[:]),
+			Available:    block.available&bit != 0,
+			Requested:    block.requested&bit != 0,
+			Active:       block.active&bit != 0,
+			NeverChanged: block.never_changed&bit != 0,
+		})
+	}
+	return features, nil
+}
+
+// Stats returns the NIC statistics reported by the driver.
+func (d *Device) Stats() ([]Stat, error) {
+	return device_stats(d.ctx, ETHTOOL_GSTATS, ETH_SS_STATS)
+}
+
+// PhyStats returns the PHY statistics reported by the driver.
+func (d *Device) PhyStats() ([]Stat, error) {
+	return device_stats(d.ctx, ETHTOOL_GPHYSTATS, ETH_SS_PHY_STATS)
+}
+
+func device_stats(ctx *cmd_context, cmd uint32, stringset uint32) ([]Stat, error) {
+	strings := get_stats_strings(ctx, stringset)
+	if strings == nil {
+		return nil, fmt.Errorf("cannot get stats strings information")
+	}
+	stats, err := get_stats(ctx, cmd, strings)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get stats information: %w", err)
+	}
+	return stats, nil
+}
+
+func get_stats_strings(ctx *cmd_context, stringset uint32) *ethtool_gstrings {
+	drvinfo := ethtool_drvinfo{}
+	return get_stringset(ctx, stringset,
+		unsafe.Offsetof(drvinfo.n_stats), 0)
+}
+
+/* get_stats reads the values of the statistics named by strings */
+func get_stats(ctx *cmd_context, cmd uint32, strings *ethtool_gstrings) ([]Stat, error) {
+	stats := &ethtool_stats{
+		cmd:     cmd,
+		n_stats: strings.len,
+	}
+	if stats.n_stats > 0 {
+		err := send_ioctl(ctx, uintptr(unsafe.Pointer(stats)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]Stat, 0, stats.n_stats)
+	for i := uint32(0); i < stats.n_stats; i++ {
+		result = append(result, Stat{
+			Name:  gstring(strings, i),
+			Value: stats.data[i],
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,411 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * Register dump of the e1000 and e1000e drivers: the first words hold
+ * the device, receive and transmit control registers, then the PHY type
+ * and, for Marvell PHYs, the PHY specific status and control registers.
+ * regs->version is 1 << 24 | revision id << 16 | device id.
+ */
+
+const (
+	E1000_DUMP_LEN = 13 * 4
+
+	/* Register dump indices */
+	E1000_DUMP_CTRL     = 0
+	E1000_DUMP_STATUS   = 1
+	E1000_DUMP_RCTL     = 2
+	E1000_DUMP_RDLEN    = 3
+	E1000_DUMP_RDH      = 4
+	E1000_DUMP_RDT      = 5
+	E1000_DUMP_RDTR     = 6
+	E1000_DUMP_TCTL     = 7
+	E1000_DUMP_TDLEN    = 8
+	E1000_DUMP_TDH      = 9
+	E1000_DUMP_TDT      = 10
+	E1000_DUMP_TIDV     = 11
+	E1000_DUMP_PHY_TYPE = 12
+	E1000_DUMP_M88_PSSR = 13
+	E1000_DUMP_M88_PSCR = 17
+
+	/* Device Control */
+	E1000_CTRL_FD      = 0x00000001 /* Full duplex.0=half; 1=full */
+	E1000_CTRL_LRST    = 0x00000008 /* Link reset. 0=normal,1=reset */
+	E1000_CTRL_ASDE    = 0x00000020 /* Auto-speed detect enable */
+	E1000_CTRL_SLU     = 0x00000040 /* Set link up (Force Link) */
+	E1000_CTRL_ILOS    = 0x00000080 /* Invert Loss-Of Signal */
+	E1000_CTRL_SPD_SEL = 0x00000300 /* Speed Select Mask */
+	E1000_CTRL_SPD_10  = 0x00000000 /* Force 10Mb */
+	E1000_CTRL_SPD_100 = 0x00000100 /* Force 100Mb */
+	E1000_CTRL_FRCSPD  = 0x00000800 /* Force Speed */
+	E1000_CTRL_FRCDPX  = 0x00001000 /* Force Duplex */
+	E1000_CTRL_RST     = 0x04000000 /* Global reset */
+	E1000_CTRL_RFCE    = 0x08000000 /* Receive Flow Control enable */
+	E1000_CTRL_TFCE    = 0x10000000 /* Transmit flow control enable */
+	E1000_CTRL_VME     = 0x40000000 /* IEEE VLAN mode enable */
+	E1000_CTRL_PHY_RST = 0x80000000 /* PHY Reset */
+
+	/* Device Status */
+	E1000_STATUS_FD         = 0x00000001 /* Full duplex.0=half,1=full */
+	E1000_STATUS_LU         = 0x00000002 /* Link up.0=no,1=link */
+	E1000_STATUS_FUNC_MASK  = 0x0000000C /* PCI Function Mask */
+	E1000_STATUS_FUNC_SHIFT = 2
+	E1000_STATUS_TXOFF      = 0x00000010 /* transmission paused */
+	E1000_STATUS_TBIMODE    = 0x00000020 /* TBI mode */
+	E1000_STATUS_SPEED_MASK = 0x000000C0
+	E1000_STATUS_SPEED_10   = 0x00000000 /* Speed 10Mb/s */
+	E1000_STATUS_SPEED_100  = 0x00000040 /* Speed 100Mb/s */
+	E1000_STATUS_PCI66      = 0x00000800 /* In 66Mhz slot */
+	E1000_STATUS_BUS64      = 0x00001000 /* In 64 bit slot */
+	E1000_STATUS_PCIX_MODE  = 0x00002000 /* PCI-X mode */
+	E1000_STATUS_PCIX_SPEED = 0x0000C000 /* PCI-X bus speed */
+	E1000_STATUS_PCIX_66    = 0x00000000
+	E1000_STATUS_PCIX_100   = 0x00004000
+	E1000_STATUS_PCIX_133   = 0x00008000
+	E1000_STATUS_GIO_M_ENA  = 0x00080000 /* GIO master enable status */
+
+	/* Receive Control */
+	E1000_RCTL_EN          = 0x00000002 /* enable */
+	E1000_RCTL_SBP         = 0x00000004 /* store bad packet */
+	E1000_RCTL_UPE         = 0x00000008 /* unicast promiscuous enable */
+	E1000_RCTL_MPE         = 0x00000010 /* multicast promiscuous enab */
+	E1000_RCTL_LPE         = 0x00000020 /* long packet enable */
+	E1000_RCTL_LBM_MASK    = 0x000000C0 /* loopback mode */
+	E1000_RCTL_LBM_MAC     = 0x00000040 /* MAC loopback mode */
+	E1000_RCTL_RDMTS_MASK  = 0x00000300 /* rx desc min threshold size */
+	E1000_RCTL_RDMTS_HALF  = 0x00000000
+	E1000_RCTL_RDMTS_QUAT  = 0x00000100
+	E1000_RCTL_RDMTS_EIGTH = 0x00000200
+	E1000_RCTL_MO_MASK     = 0x00003000 /* multicast offset */
+	E1000_RCTL_MO_SHIFT    = 12
+	E1000_RCTL_BAM         = 0x00008000 /* broadcast enable */
+	E1000_RCTL_SZ_MASK     = 0x00030000 /* rx buffer size */
+	E1000_RCTL_SZ_SHIFT    = 16
+	E1000_RCTL_VFE         = 0x00040000 /* vlan filter enable */
+	E1000_RCTL_CFIEN       = 0x00080000 /* canonical form enable */
+	E1000_RCTL_CFI         = 0x00100000 /* canonical form indicator */
+	E1000_RCTL_DPF         = 0x00400000 /* discard pause frames */
+	E1000_RCTL_PMCF        = 0x00800000 /* pass MAC control frames */
+	E1000_RCTL_BSEX        = 0x02000000 /* Buffer size extension */
+	E1000_RCTL_SECRC       = 0x04000000 /* Strip Ethernet CRC */
+
+	/* Transmit Control */
+	E1000_TCTL_EN     = 0x00000002 /* enable tx */
+	E1000_TCTL_PSP    = 0x00000008 /* pad short packets */
+	E1000_TCTL_CT     = 0x00000ff0 /* collision threshold */
+	E1000_TCTL_COLD   = 0x003ff000 /* collision distance */
+	E1000_TCTL_SWXOFF = 0x00400000 /* SW Xoff transmission */
+	E1000_TCTL_RTLC   = 0x01000000 /* Re-transmit on late collision */
+
+	/* M88E1000 PHY Specific Status Register */
+	M88_PSSR_JABBER       = 0x0001 /* 1=Jabber */
+	M88_PSSR_REV_POLARITY = 0x0002 /* 1=Polarity reversed */
+	M88_PSSR_DOWNSHIFT    = 0x0020 /* 1=Downshifted */
+	M88_PSSR_MDIX         = 0x0040 /* 1=MDIX; 0=MDI */
+	M88_PSSR_CABLE_LEN    = 0x0380 /* cable length estimate */
+	M88_PSSR_CABLE_SHIFT  = 7
+	M88_PSSR_LINK         = 0x0400 /* 1=Link up, 0=Link down */
+	M88_PSSR_SPD_DPLX     = 0x0800 /* 1=Speed & Duplex resolved */
+	M88_PSSR_DPLX         = 0x2000 /* 1=Duplex 0=Half Duplex */
+	M88_PSSR_SPEED        = 0xC000 /* Speed, bits 14:15 */
+	M88_PSSR_10MBS        = 0x0000 /* 00=10Mbs */
+	M88_PSSR_100MBS       = 0x4000 /* 01=100Mbs */
+
+	/* M88E1000 PHY Specific Control Register */
+	M88_PSCR_JABBER_DISABLE    = 0x0001 /* 1=Jabber Function disabled */
+	M88_PSCR_POLARITY_REVERSAL = 0x0002 /* 1=Polarity Reversal enabled */
+	M88_PSCR_SQE_TEST          = 0x0004 /* 1=SQE Test enabled */
+	M88_PSCR_CLK125_DISABLE    = 0x0010 /* 1=CLK125 low, 0=CLK125 toggling */
+	M88_PSCR_MDIX_MASK         = 0x0060
+	M88_PSCR_MDI_MANUAL_MODE   = 0x0000 /* MDI Crossover Mode bits 6:5 */
+	M88_PSCR_MDIX_MANUAL_MODE  = 0x0020 /* Manual MDIX configuration */
+	M88_PSCR_AUTO_X_1000T      = 0x0040 /* 1000BASE-T: Auto crossover */
+	M88_PSCR_10BT_EXT_DIST     = 0x0080 /* 1=Lower 10BASE-T RX Threshold */
+	M88_PSCR_MII_5BIT          = 0x0100 /* 1=5-Bit interface in 100BASE-TX */
+	M88_PSCR_SCRAMBLER_DISABLE = 0x0200 /* 1=Scrambler disable */
+	M88_PSCR_FORCE_LINK_GOOD   = 0x0400 /* 1=Force link good */
+	M88_PSCR_ASSERT_CRS_ON_TX  = 0x0800 /* 1=Assert CRS on Transmit */
+)
+
+/* e1000_phy_m88 as the two drivers number it in their phy type enum */
+const (
+	E1000_PHY_M88  = 0
+	E1000E_PHY_M88 = 2
+)
+
+func e1000_dump_ctrl(reg uint32) {
+	fmt.Printf("0x00000: CTRL (Device control register)  0x%08X\n"+
+		"      Invert Loss-Of-Signal:         %s\n"+
+		"      Receive flow control:          %s\n"+
+		"      Transmit flow control:         %s\n"+
+		"      VLAN mode:                     %s\n"+
+		"      Auto speed detect:             %s\n"+
+		"      Set link up:                   %s\n",
+		reg,
+		yesno(reg&E1000_CTRL_ILOS != 0),
+		endis(reg&E1000_CTRL_RFCE != 0),
+		endis(reg&E1000_CTRL_TFCE != 0),
+		endis(reg&E1000_CTRL_VME != 0),
+		endis(reg&E1000_CTRL_ASDE != 0),
+		yesno(reg&E1000_CTRL_SLU != 0))
+
+	speed := "1000Mb/s"
+	switch reg & E1000_CTRL_SPD_SEL {
+	case E1000_CTRL_SPD_10:
+		speed = "10Mb/s"
+	case E1000_CTRL_SPD_100:
+		speed = "100Mb/s"
+	}
+	fmt.Printf("      Speed select:                  %s\n"+
+		"      Force speed:                   %s\n"+
+		"      Force duplex:                  %s\n"+
+		"      Duplex:                        %s\n"+
+		"      Link reset:                    %s\n"+
+		"      Device reset:                  %s\n"+
+		"      PHY reset:                     %s\n",
+		speed,
+		yesno(reg&E1000_CTRL_FRCSPD != 0),
+		yesno(reg&E1000_CTRL_FRCDPX != 0),
+		regs_bit(reg&E1000_CTRL_FD != 0, "full", "half"),
+		yesno(reg&E1000_CTRL_LRST != 0),
+		yesno(reg&E1000_CTRL_RST != 0),
+		yesno(reg&E1000_CTRL_PHY_RST != 0))
+}
+
+func e1000_dump_status(reg uint32, pcie bool) {
+	speed := "1000Mb/s"
+	switch reg & E1000_STATUS_SPEED_MASK {
+	case E1000_STATUS_SPEED_10:
+		speed = "10Mb/s"
+	case E1000_STATUS_SPEED_100:
+		speed = "100Mb/s"
+	}
+	fmt.Printf("0x00008: STATUS (Device status register) 0x%08X\n"+
+		"      Duplex:                        %s\n"+
+		"      Link up:                       %s\n"+
+		"      TBI mode:                      %s\n"+
+		"      Link speed:                    %s\n"+
+		"      Transmission paused:           %s\n",
+		reg,
+		regs_bit(reg&E1000_STATUS_FD != 0, "full", "half"),
+		regs_bit(reg&E1000_STATUS_LU != 0, "link config", "no link config"),
+		endis(reg&E1000_STATUS_TBIMODE != 0),
+		speed,
+		yesno(reg&E1000_STATUS_TXOFF != 0))
+
+	if pcie {
+		fmt.Printf("      Bus type:                      PCI Express\n"+
+			"      GIO master:                    %s\n",
+			endis(reg&E1000_STATUS_GIO_M_ENA != 0))
+	} else if reg&E1000_STATUS_PCIX_MODE != 0 {
+		bus_speed := "reserved"
+		switch reg & E1000_STATUS_PCIX_SPEED {
+		case E1000_STATUS_PCIX_66:
+			bus_speed = "66MHz"
+		case E1000_STATUS_PCIX_100:
+			bus_speed = "100MHz"
+		case E1000_STATUS_PCIX_133:
+			bus_speed = "133MHz"
+		}
+		fmt.Printf("      Bus type:                      PCI-X\n"+
+			"      Bus speed:                     %s\n",
+			bus_speed)
+	} else {
+		bus_speed := "33MHz"
+		if reg&E1000_STATUS_PCI66 != 0 {
+			bus_speed = "66MHz"
+		}
+		fmt.Printf("      Bus type:                      PCI\n"+
+			"      Bus speed:                     %s\n",
+			bus_speed)
+	}
+	if !pcie {
+		bus_width := "32-bit"
+		if reg&E1000_STATUS_BUS64 != 0 {
+			bus_width = "64-bit"
+		}
+		fmt.Printf("      Bus width:                     %s\n", bus_width)
+	}
+	fmt.Printf("      Port number:                   %d\n",
+		(reg&E1000_STATUS_FUNC_MASK)>>E1000_STATUS_FUNC_SHIFT)
+}
+
+func e1000_dump_rctl(reg uint32) {
+	threshold := "reserved"
+	switch reg & E1000_RCTL_RDMTS_MASK {
+	case E1000_RCTL_RDMTS_HALF:
+		threshold = "1/2"
+	case E1000_RCTL_RDMTS_QUAT:
+		threshold = "1/4"
+	case E1000_RCTL_RDMTS_EIGTH:
+		threshold = "1/8"
+	}
+
+	sizes := []string{"2048", "1024", "512", "256"}
+	if reg&E1000_RCTL_BSEX != 0 {
+		sizes = []string{"reserved", "16384", "8192", "4096"}
+	}
+
+	fmt.Printf("0x00100: RCTL (Receive control register) 0x%08X\n"+
+		"      Receiver:                      %s\n"+
+		"      Store bad packets:             %s\n"+
+		"      Unicast promiscuous:           %s\n"+
+		"      Multicast promiscuous:         %s\n"+
+		"      Long packet:                   %s\n"+
+		"      Loopback:                      %s\n"+
+		"      Descriptor minimum threshold size: %s\n"+
+		"      Multicast offset:              bits %d-%d\n"+
+		"      Broadcast accept mode:         %s\n"+
+		"      VLAN filter:                   %s\n"+
+		"      Canonical form indicator:      %s\n"+
+		"      Discard pause frames:          %s\n"+
+		"      Pass MAC control frames:       %s\n"+
+		"      Strip Ethernet CRC:            %s\n"+
+		"      Receive buffer size:           %s\n",
+		reg,
+		endis(reg&E1000_RCTL_EN != 0),
+		endis(reg&E1000_RCTL_SBP != 0),
+		endis(reg&E1000_RCTL_UPE != 0),
+		endis(reg&E1000_RCTL_MPE != 0),
+		endis(reg&E1000_RCTL_LPE != 0),
+		regs_bit(reg&E1000_RCTL_LBM_MASK == E1000_RCTL_LBM_MAC, "MAC", "none"),
+		threshold,
+		47-(reg&E1000_RCTL_MO_MASK)>>E1000_RCTL_MO_SHIFT,
+		36-(reg&E1000_RCTL_MO_MASK)>>E1000_RCTL_MO_SHIFT,
+		regs_bit(reg&E1000_RCTL_BAM != 0, "accept", "ignore"),
+		endis(reg&E1000_RCTL_VFE != 0),
+		regs_bit(reg&E1000_RCTL_CFIEN != 0, "enabled", "disabled")+
+			regs_bit(reg&E1000_RCTL_CFI != 0, ", CFI set", ""),
+		regs_bit(reg&E1000_RCTL_DPF != 0, "ignored", "filtered"),
+		regs_bit(reg&E1000_RCTL_PMCF != 0, "pass", "don't pass"),
+		yesno(reg&E1000_RCTL_SECRC != 0),
+		sizes[(reg&E1000_RCTL_SZ_MASK)>>E1000_RCTL_SZ_SHIFT])
+}
+
+func e1000_dump_tctl(reg uint32) {
+	fmt.Printf("0x00400: TCTL (Transmit ctrl register) 0x%08X\n"+
+		"      Transmitter:                   %s\n"+
+		"      Pad short packets:             %s\n"+
+		"      Collision threshold:           %d\n"+
+		"      Collision distance:            %d\n"+
+		"      Software XOFF Transmission:    %s\n"+
+		"      Re-transmit on late collision: %s\n",
+		reg,
+		endis(reg&E1000_TCTL_EN != 0),
+		endis(reg&E1000_TCTL_PSP != 0),
+		(reg&E1000_TCTL_CT)>>4,
+		(reg&E1000_TCTL_COLD)>>12,
+		yesno(reg&E1000_TCTL_SWXOFF != 0),
+		endis(reg&E1000_TCTL_RTLC != 0))
+}
+
+func e1000_dump_m88(pssr uint32, pscr uint32) {
+	cable_len := []string{"< 50 m", "50 - 80 m", "80 - 110 m",
+		"110 - 140 m", "> 140 m", "unknown", "unknown", "unknown"}
+	speed := "1000 Mb/s"
+	switch pssr & M88_PSSR_SPEED {
+	case M88_PSSR_10MBS:
+		speed = "10 Mb/s"
+	case M88_PSSR_100MBS:
+		speed = "100 Mb/s"
+	}
+
+	fmt.Printf("M88 PHY STATUS REGISTER:                 0x%08X\n"+
+		"      Jabber:                        %s\n"+
+		"      Polarity:                      %s\n"+
+		"      Downshifted:                   %s\n"+
+		"      MDI/MDIX:                      %s\n"+
+		"      Cable Length Estimate:         %s\n"+
+		"      Link State:                    %s\n"+
+		"      Speed & Duplex Resolved:       %s\n"+
+		"      Speed:                         %s\n"+
+		"      Duplex:                        %s\n",
+		pssr,
+		yesno(pssr&M88_PSSR_JABBER != 0),
+		regs_bit(pssr&M88_PSSR_REV_POLARITY != 0, "reverse", "normal"),
+		yesno(pssr&M88_PSSR_DOWNSHIFT != 0),
+		regs_bit(pssr&M88_PSSR_MDIX != 0, "MDIX", "MDI"),
+		cable_len[(pssr&M88_PSSR_CABLE_LEN)>>M88_PSSR_CABLE_SHIFT],
+		regs_bit(pssr&M88_PSSR_LINK != 0, "up", "down"),
+		yesno(pssr&M88_PSSR_SPD_DPLX != 0),
+		speed,
+		regs_bit(pssr&M88_PSSR_DPLX != 0, "full", "half"))
+
+	mdix := "auto"
+	switch pscr & M88_PSCR_MDIX_MASK {
+	case M88_PSCR_MDI_MANUAL_MODE:
+		mdix = "force MDI"
+	case M88_PSCR_MDIX_MANUAL_MODE:
+		mdix = "force MDIX"
+	case M88_PSCR_AUTO_X_1000T:
+		mdix = "1000 auto, 10/100 MDI"
+	}
+	fmt.Printf("M88 PHY CONTROL REGISTER:                0x%08X\n"+
+		"      Jabber function:               %s\n"+
+		"      Auto-polarity:                 %s\n"+
+		"      SQE Test:                      %s\n"+
+		"      CLK125:                        %s\n"+
+		"      Auto-MDIX:                     %s\n"+
+		"      Extended 10Base-T Distance:    %s\n"+
+		"      100Base-TX Interface:          %s\n"+
+		"      Scrambler:                     %s\n"+
+		"      Force Link Good:               %s\n"+
+		"      Assert CRS on Transmit:        %s\n",
+		pscr,
+		endis(pscr&M88_PSCR_JABBER_DISABLE == 0),
+		endis(pscr&M88_PSCR_POLARITY_REVERSAL == 0),
+		endis(pscr&M88_PSCR_SQE_TEST != 0),
+		endis(pscr&M88_PSCR_CLK125_DISABLE == 0),
+		mdix,
+		endis(pscr&M88_PSCR_10BT_EXT_DIST != 0),
+		regs_bit(pscr&M88_PSCR_MII_5BIT != 0, "5-bit", "MII"),
+		endis(pscr&M88_PSCR_SCRAMBLER_DISABLE == 0),
+		yesno(pscr&M88_PSCR_FORCE_LINK_GOOD != 0),
+		yesno(pscr&M88_PSCR_ASSERT_CRS_ON_TX != 0))
+}
+
+func e1000_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	if regs.len < E1000_DUMP_LEN || regs.version>>24 != 1 {
+		return -1
+	}
+	/* e1000e only drives PCI Express parts */
+	e1000e := cstring(info.driver[:]) == "e1000e"
+
+	e1000_dump_ctrl(regs_u32(regs, E1000_DUMP_CTRL))
+	e1000_dump_status(regs_u32(regs, E1000_DUMP_STATUS), e1000e)
+	e1000_dump_rctl(regs_u32(regs, E1000_DUMP_RCTL))
+	fmt.Printf("0x02808: RDLEN (Receive desc length)   0x%08X\n"+
+		"0x02810: RDH   (Receive desc head)     0x%08X\n"+
+		"0x02818: RDT   (Receive desc tail)     0x%08X\n"+
+		"0x02820: RDTR  (Receive delay timer)   0x%08X\n",
+		regs_u32(regs, E1000_DUMP_RDLEN),
+		regs_u32(regs, E1000_DUMP_RDH),
+		regs_u32(regs, E1000_DUMP_RDT),
+		regs_u32(regs, E1000_DUMP_RDTR))
+	e1000_dump_tctl(regs_u32(regs, E1000_DUMP_TCTL))
+	fmt.Printf("0x03808: TDLEN (Transmit desc length)  0x%08X\n"+
+		"0x03810: TDH   (Transmit desc head)    0x%08X\n"+
+		"0x03818: TDT   (Transmit desc tail)    0x%08X\n"+
+		"0x03820: TIDV  (Transmit delay timer)  0x%08X\n",
+		regs_u32(regs, E1000_DUMP_TDLEN),
+		regs_u32(regs, E1000_DUMP_TDH),
+		regs_u32(regs, E1000_DUMP_TDT),
+		regs_u32(regs, E1000_DUMP_TIDV))
+
+	phy_type := regs_u32(regs, E1000_DUMP_PHY_TYPE)
+	m88 := uint32(E1000_PHY_M88)
+	if e1000e {
+		m88 = E1000E_PHY_M88
+	}
+	if phy_type != m88 {
+		fmt.Printf("PHY type:                                %d\n", phy_type)
+		return 0
+	}
+	fmt.Printf("PHY type:                                M88\n")
+	if regs.len >= (E1000_DUMP_M88_PSCR+1)*4 {
+		e1000_dump_m88(regs_u32(regs, E1000_DUMP_M88_PSSR),
+			regs_u32(regs, E1000_DUMP_M88_PSCR))
+	}
+	return 0
+}
@@ -83,8 +83,8 @@ type ethtool_drvinfo struct {
 
 type ethtool_wolinfo struct {
 	cmd       uint32
-	supported int
-	wolopts   int
+	supported uint32
+	wolopts   uint32
 	sopass    [SOPASS_MAX]uint8
 }
 
@@ -548,7 +548,7 @@ type ethtool_per_queue_op struct {
 	cmd         uint32
 	sub_command uint32
 	queue_mask  [128]uint32
-	data        *byte
+	/* char data[]; follows */
 }
 
 type ethtool_fecparam struct {
@@ -967,5 +967,5 @@ type ethtool_link_settings struct {
 	master_slave_state     uint8
 	reserved1              [1]uint8
 	reserved               [7]uint32
-	link_mode_masks        [0]uint32
+	/* __u32 link_mode_masks[]; follows, see ethtool_link_settings_req */
 }
@@ -3,6 +3,8 @@ package ethtool
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math"
 	"net"
 	"os"
@@ -12,7 +14,6 @@ import (
 	"unsafe"
 
 	"github.com/junka/ioctl"
-	"github.com/spf13/cobra"
 )
 
 const (
@@ -110,31 +111,50 @@ func parse_generic_cmdline(ctx *cmd_context,
 
 				case CMDL_S32:
 					p := (*int32)(unsafe.Pointer((*info)[idx].wanted_val))
-					val, _ := strconv.ParseInt(argp[i], 10, 32)
+					val, err := strconv.ParseInt(argp[i], 10, 32)
+					if err != nil {
+						return -1
+					}
 					*p = int32(val)
 
 				case CMDL_U8:
 					p := (*uint8)(unsafe.Pointer((*info)[idx].wanted_val))
-					val, _ := strconv.ParseUint(argp[i], 10, 8)
+					val, err := strconv.ParseUint(argp[i], 0, 8)
+					if err != nil {
+						return -1
+					}
 					*p = uint8(val)
 
 				case CMDL_U16:
 					p := (*uint16)(unsafe.Pointer((*info)[idx].wanted_val))
-					val, _ := strconv.ParseUint(argp[i], 10, 16)
+					val, err := strconv.ParseUint(argp[i], 0, 16)
+					if err != nil {
+						return -1
+					}
 					*p = uint16(val)
 
 				case CMDL_U32:
 					p := (*uint32)(unsafe.Pointer((*info)[idx].wanted_val))
-					val, _ := strconv.ParseUint(argp[i], 10, 32)
+					val, err := strconv.ParseUint(argp[i], 0, 32)
+					if err != nil {
+						return -1
+					}
 					*p = uint32(val)
 
 				case CMDL_U64:
 					p := (*uint64)(unsafe.Pointer((*info)[idx].wanted_val))
-					*p, _ = strconv.ParseUint(argp[i], 10, 64)
+					val, err := strconv.ParseUint(argp[i], 0, 64)
+					if err != nil {
+						return -1
+					}
+					*p = val
 
 				case CMDL_BE16:
 					p := (*int16)(unsafe.Pointer((*info)[idx].wanted_val))
-					val, _ := strconv.ParseUint(argp[i], 10, 16)
+					val, err := strconv.ParseUint(argp[i], 0, 16)
+					if err != nil {
+						return -1
+					}
 					*p = int16(val)
 
 				case CMDL_IP4:
@@ -155,13 +175,13 @@ func parse_generic_cmdline(ctx *cmd_context,
 					if argp[i] == "on" {
 						p = (*uint32)(unsafe.Pointer(((*info)[idx].wanted_val)))
 						*p |= (*info)[idx].flag_val
-					} else if argp[i] == "off" {
+					} else if argp[i] != "off" {
 						return -1
 					}
 
 				case CMDL_STR:
-					s := (*[]byte)(unsafe.Pointer((*info)[idx].wanted_val))
-					copy(*s, (argp[i]))
+					s := (*string)(unsafe.Pointer((*info)[idx].wanted_val))
+					*s = argp[i]
 
 				default:
 					return -1
@@ -176,6 +196,15 @@ func parse_generic_cmdline(ctx *cmd_context,
 	return 0
 }
 
+func flag_to_cmdline_info(name string, value uint32,
+	wanted *uint32, mask *uint32, cli *cmdline_info) {
+	cli.name = name
+	cli.tp = CMDL_FLAG
+	cli.flag_val = value
+	cli.wanted_val = uintptr(unsafe.Pointer(wanted))
+	cli.seen_val = uintptr(unsafe.Pointer(mask))
+}
+
 const SIOCETHTOOL = 0x8946
 
 func send_ioctl(ctx *cmd_context, cmd uintptr) error {
@@ -189,9 +218,18 @@ func init_ioctl(ctx *cmd_context, no_dev bool) int {
 		ctx.fd = -1
 		return 0
 	}
-	if len(ctx.devname) > IFNAMESIZE {
-		fmt.Println("Device name longer than %u characters")
-		return -1
+	err := open_ioctl(ctx)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return 70
+	}
+	return 0
+}
+
+// open_ioctl opens the control socket used for SIOCETHTOOL on ctx.devname.
+func open_ioctl(ctx *cmd_context) error {
+	if len(ctx.devname) >= IFNAMESIZE {
+		return fmt.Errorf("Device name longer than %d characters", IFNAMESIZE-1)
 	}
 	copy(ctx.ifr.ifr_name[:], ctx.devname)
 	var err error
@@ -200,10 +238,9 @@ func init_ioctl(ctx *cmd_context, no_dev bool) int {
 		ctx.fd, err = syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW, 16)
 	}
 	if ctx.fd < 0 || err != nil {
-		fmt.Printf("cannot get control socket")
-		return 70
+		return fmt.Errorf("cannot get control socket: %v", err)
 	}
-	return 0
+	return nil
 }
 
 func uninit_ioctl(ctx *cmd_context) {
@@ -213,38 +250,38 @@ func uninit_ioctl(ctx *cmd_context) {
 }
 
 //
-func dump_drvinfo(info *ethtool_drvinfo) int {
+func dump_drvinfo(info *DriverInfo) int {
 	stats_sp, test_info_sp, eedump_sp, regdump_sp, priv_sp := "no", "no", "no", "no", "no"
-	if info.n_stats > 0 {
+	if info.NStats > 0 {
 		stats_sp = "yes"
 	}
-	if info.testinfo_len > 0 {
+	if info.TestInfoLen > 0 {
 		test_info_sp = "yes"
 	}
-	if info.eedump_len > 0 {
+	if info.EEDumpLen > 0 {
 		eedump_sp = "yes"
 	}
-	if info.regdump_len > 0 {
+	if info.RegDumpLen > 0 {
 		regdump_sp = "yes"
 	}
-	if info.n_priv_flags > 0 {
+	if info.NPrivFlags > 0 {
 		priv_sp = "yes"
 	}
-	fmt.Printf("driver: %.*s\n"+
-		"version: %.*s\n"+
-		"firmware-version: %.*s\n"+
-		"expansion-rom-version: %.*s\n"+
-		"bus-info: %.*s\n"+
+	fmt.Printf("driver: %s\n"+
+		"version: %s\n"+
+		"firmware-version: %s\n"+
+		"expansion-rom-version: %s\n"+
+		"bus-info: %s\n"+
 		"supports-statistics: %s\n"+
 		"supports-test: %s\n"+
 		"supports-eeprom-access: %s\n"+
 		"supports-register-dump: %s\n"+
 		"supports-priv-flags: %s\n",
-		len(string(info.driver[:])), info.driver,
-		len(info.version), info.version,
-		len(info.fw_version), info.fw_version,
-		len(info.erom_version), info.erom_version,
-		len(info.bus_info), info.bus_info, stats_sp,
+		info.Driver,
+		info.Version,
+		info.FirmwareVersion,
+		info.ExpansionROMVersion,
+		info.BusInfo, stats_sp,
 		test_info_sp, eedump_sp, regdump_sp, priv_sp)
 
 	return 0
@@ -252,11 +289,74 @@ func dump_drvinfo(info *ethtool_drvinfo) int {
 
 type driver_dump struct {
 	name       string
-	regdump_fn func(info *ethtool_drvinfo, regs *ethtool_regs)
+	regdump_fn func(info *ethtool_drvinfo, regs *ethtool_regs) int
+	version    uint32 /* regs.version current drivers report */
+}
+
+/* Drivers whose register dump we know how to pretty print, a regdump_fn
+ * returns 0 if it decoded the dump and non-zero to fall back to hex.
+ */
+var driver_list = []driver_dump{
+	{"r8169", realtek_dump_regs, 0},
+	{"e1000", e1000_dump_regs, 1 << 24},
+	{"e1000e", e1000_dump_regs, 1 << 24},
+	{"igb", igb_dump_regs, 1 << 24},
+	{"ixgbe", ixgbe_dump_regs, 1 << 24},
+	{"i40e", i40e_dump_regs, 1},
+	{"tg3", tg3_dump_regs, 0},
+	{"fec", fec_dump_regs, 0},
+	{"st_mac100", st_mac100_dump_regs, 0},
+	{"st_gmac", st_gmac_dump_regs, 0},
+	{"vmxnet3", vmxnet3_dump_regs, VMXNET3_DUMP_VERSION},
+}
+
+/* driver_dump_version returns the dump version to assume for a saved
+ * dump of driver, a raw dump does not record it.
+ */
+func driver_dump_version(driver string) uint32 {
+	for i := 0; i < len(driver_list); i++ {
+		if driver_list[i].name == driver {
+			return driver_list[i].version
+		}
+	}
+	return 0
+}
+
+/* read_regs_file replaces the register values in regs with a dump saved
+ * with -d raw on.
+ */
+func read_regs_file(file string, regs *ethtool_regs) int {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Printf("Can't open '%s': %v\n", file, errors.Unwrap(err))
+		return 75
+	}
+	if len(data) > MAX_DATA_BUF {
+		fmt.Printf("Register dump '%s' is too large\n", file)
+		return 75
+	}
+	regs.len = uint32(copy(regs.data[:], data))
+	return 0
+}
+
+/* regs_u32 returns the n-th 32 bit word of a register dump, drivers fill
+ * the dump in host byte order.
+ */
+func regs_u32(regs *ethtool_regs, n uint32) uint32 {
+	return *(*uint32)(unsafe.Pointer(&regs.data[4*n]))
+}
+
+/* regs_bit names the state of a register bit */
+func regs_bit(set bool, on string, off string) string {
+	if set {
+		return on
+	}
+	return off
 }
 
-// do not support pretty dump
-var driver_list = []driver_dump{}
+func endis(on bool) string {
+	return regs_bit(on, "enabled", "disabled")
+}
 
 func dump_hex(file *os.File, data []uint8, len uint32, offset uint32) {
 
@@ -271,51 +371,67 @@ func dump_hex(file *os.File, data []uint8, len uint32, offset uint32) {
 	fmt.Fprintf(file, "\n")
 }
 
+/* nested_regs returns the drvinfo and regs structures some drivers
+ * append to their register dump, or nil if there are none.
+ */
+func nested_regs(info *ethtool_drvinfo,
+	regs *ethtool_regs) (*ethtool_drvinfo, *ethtool_regs) {
+	var ninfo ethtool_drvinfo
+	var nregs ethtool_regs
+
+	info_len := uint32(unsafe.Sizeof(ninfo))
+	hdr_len := uint32(unsafe.Offsetof(nregs.data))
+	if info.regdump_len <= regs.len+info_len+hdr_len ||
+		regs.len+info_len+hdr_len > MAX_DATA_BUF {
+		return nil, nil
+	}
+
+	data := regs.data[regs.len:]
+	copy((*[unsafe.Sizeof(ninfo)]byte)(unsafe.Pointer(&ninfo))[:], data)
+	data = data[info_len:]
+	copy((*[unsafe.Offsetof(nregs.data)]byte)(unsafe.Pointer(&nregs))[:], data)
+	data = data[hdr_len:]
+	if nregs.len > uint32(len(data)) {
+		return nil, nil
+	}
+	copy(nregs.data[:], data[:nregs.len])
+	return &ninfo, &nregs
+}
+
 func dump_regs(gregs_dump_raw int, gregs_dump_hex int,
 	info *ethtool_drvinfo, regs *ethtool_regs) int {
 
-	// if gregs_dump_raw != 0 {
-	// 	// fwrite(regs.data, regs.len, 1, stdout);
-	// 	if info.regdump_len > regs.len+uint32(unsafe.Sizeof(*info)+unsafe.Sizeof(*regs)) {
-	// 		info = (*ethtool_drvinfo)(&regs.data[regs.len])
-	// 		regs = (*ethtool_regs)(&regs.data[regs.len+uint32(unsafe.Sizeof(*info))])
-
-	// 		return dump_regs(gregs_dump_raw, gregs_dump_hex, info, regs)
-	// 	}
-	// 	return 0
-	// }
-
-	// if gregs_dump_hex == 0 {
-	// 	for i := 0; i < len(driver_list); i++ {
-	// 		if driver_list[i].name == string(info.driver[:]) {
-	// 			if driver_list[i].regdump_fn(info, regs) == 0 {
-	// 				/* Recurse dump if some drvinfo and regs structures are nested */
-	// 				if info.regdump_len > regs.len+unsafe.Sizeof(*info)+unsafe.Sizeof(*regs) {
-	// 					info = (&regs.data[regs.len])
-	// 					regs = (&regs.data[regs.len+uint32(unsafe.Sizeof(*info))])
-
-	// 					return dump_regs(gregs_dump_raw, gregs_dump_hex, info, regs)
-	// 				}
-	// 			}
-	// 			/* This version (or some other
-	// 			 * variation in the dump format) is
-	// 			 * not handled; fall back to hex
-	// 			 */
-	// 			break
-	// 		}
-	// 	}
-	// }
-
-	// dump_hex(os.Stdout, regs.data, regs.len, 0)
-
-	// /* Recurse dump if some drvinfo and regs structures are nested */
-	// if info.regdump_len > regs.len+unsafe.Sizeof(*info)+unsafe.Sizeof(*regs) {
-	// 	info = (&regs.data[0] + regs.len)
-	// 	regs = (&regs.data[0] + regs.len + unsafe.Sizeof(*info))
+	if regs.len > MAX_DATA_BUF {
+		return -1
+	}
 
-	// 	return dump_regs(gregs_dump_raw, gregs_dump_hex, info, regs)
-	// }
+	if gregs_dump_raw != 0 {
+		os.Stdout.Write(regs.data[:regs.len])
+	} else {
+		decoded := false
+		if gregs_dump_hex == 0 {
+			driver := cstring(info.driver[:])
+			for i := 0; i < len(driver_list); i++ {
+				if driver_list[i].name == driver {
+					/* This version (or some other
+					 * variation in the dump format) may
+					 * not be handled; fall back to hex
+					 */
+					decoded = driver_list[i].regdump_fn(info, regs) == 0
+					break
+				}
+			}
+		}
+		if !decoded {
+			dump_hex(os.Stdout, regs.data[:], regs.len, 0)
+		}
+	}
 
+	/* Recurse dump if some drvinfo and regs structures are nested */
+	ninfo, nregs := nested_regs(info, regs)
+	if ninfo != nil {
+		return dump_regs(gregs_dump_raw, gregs_dump_hex, ninfo, nregs)
+	}
 	return 0
 }
 
@@ -362,7 +478,7 @@ func dump_test(test *ethtool_test,
 }
 
 func dump_pause(epause *ethtool_pauseparam,
-	advertising uint32, lp_advertising uint32) int {
+	advertising []uint32, lp_advertising []uint32) int {
 	neg, rx, tx, arx, atx := "off", "off", "off", "off", "off"
 	if epause.autoneg > 0 {
 		neg = "on"
@@ -379,20 +495,23 @@ func dump_pause(epause *ethtool_pauseparam,
 			"TX:		%s\n",
 		neg, rx, tx)
 
-	if lp_advertising != 0 {
+	if !link_mode_is_empty(lp_advertising) {
 		an_rx, an_tx := 0, 0
+		pause := link_mode_test_bit(ETHTOOL_LINK_MODE_Pause_BIT, advertising)
+		lp_pause := link_mode_test_bit(ETHTOOL_LINK_MODE_Pause_BIT, lp_advertising)
+		asym := link_mode_test_bit(ETHTOOL_LINK_MODE_Asym_Pause_BIT, advertising)
+		lp_asym := link_mode_test_bit(ETHTOOL_LINK_MODE_Asym_Pause_BIT, lp_advertising)
 
 		/* Work out negotiated pause frame usage per
 		 * IEEE 802.3-2005 table 28B-3.
 		 */
-		if (advertising & lp_advertising & 1 << ETHTOOL_LINK_MODE_Pause_BIT) != 0 {
+		if pause && lp_pause {
 			an_tx = 1
 			an_rx = 1
-		} else if (advertising & lp_advertising &
-			1 << ETHTOOL_LINK_MODE_Asym_Pause_BIT) != 0 {
-			if (advertising & 1 << ETHTOOL_LINK_MODE_Pause_BIT) != 0 {
+		} else if asym && lp_asym {
+			if pause {
 				an_rx = 1
-			} else if (lp_advertising & 1 << ETHTOOL_LINK_MODE_Pause_BIT) != 0 {
+			} else if lp_pause {
 				an_tx = 1
 			}
 		}
@@ -412,17 +531,17 @@ func dump_pause(epause *ethtool_pauseparam,
 	return 0
 }
 
-func dump_ring(ering *ethtool_ringparam) int {
+func dump_ring(ering *RingParam) int {
 	fmt.Printf(
 		"Pre-set maximums:\n"+
 			"RX:		%d\n"+
 			"RX Mini:	%d\n"+
 			"RX Jumbo:	%d\n"+
 			"TX:		%d\n",
-		ering.rx_max_pending,
-		ering.rx_mini_max_pending,
-		ering.rx_jumbo_max_pending,
-		ering.tx_max_pending)
+		ering.RxMaxPending,
+		ering.RxMiniMaxPending,
+		ering.RxJumboMaxPending,
+		ering.TxMaxPending)
 
 	fmt.Printf(
 		"Current hardware settings:\n"+
@@ -430,25 +549,25 @@ func dump_ring(ering *ethtool_ringparam) int {
 			"RX Mini:	%d\n"+
 			"RX Jumbo:	%d\n"+
 			"TX:		%d\n",
-		ering.rx_pending,
-		ering.rx_mini_pending,
-		ering.rx_jumbo_pending,
-		ering.tx_pending)
+		ering.RxPending,
+		ering.RxMiniPending,
+		ering.RxJumboPending,
+		ering.TxPending)
 
 	fmt.Printf("\n")
 	return 0
 }
 
-func dump_channels(echannels *ethtool_channels) int {
+func dump_channels(echannels *Channels) int {
 	fmt.Printf(
 		"Pre-set maximums:\n"+
 			"RX:		%d\n"+
 			"TX:		%d\n"+
 			"Other:		%d\n"+
 			"Combined:	%d\n",
-		echannels.max_rx, echannels.max_tx,
-		echannels.max_other,
-		echannels.max_combined)
+		echannels.MaxRx, echannels.MaxTx,
+		echannels.MaxOther,
+		echannels.MaxCombined)
 
 	fmt.Printf(
 		"Current hardware settings:\n"+
@@ -456,20 +575,20 @@ func dump_channels(echannels *ethtool_channels) int {
 			"TX:		%d\n"+
 			"Other:		%d\n"+
 			"Combined:	%d\n",
-		echannels.rx_count, echannels.tx_count,
-		echannels.other_count,
-		echannels.combined_count)
+		echannels.RxCount, echannels.TxCount,
+		echannels.OtherCount,
+		echannels.CombinedCount)
 
 	fmt.Printf("\n")
 	return 0
 }
 
-func dump_coalesce(ecoal *ethtool_coalesce) int {
+func dump_coalesce(ecoal *Coalesce) int {
 	urx, utx := "off", "off"
-	if ecoal.use_adaptive_rx_coalesce != 0 {
+	if ecoal.UseAdaptiveRxCoalesce != 0 {
 		urx = "on"
 	}
-	if ecoal.use_adaptive_tx_coalesce != 0 {
+	if ecoal.UseAdaptiveTxCoalesce != 0 {
 		utx = "on"
 	}
 	fmt.Printf("Adaptive RX: %s  TX: %s\n", urx, utx)
@@ -500,55 +619,50 @@ func dump_coalesce(ecoal *ethtool_coalesce) int {
 			"tx-usecs-high: %d\n"+
 			"tx-frames-high: %d\n"+
 			"\n",
-		ecoal.stats_block_coalesce_usecs,
-		ecoal.rate_sample_interval,
-		ecoal.pkt_rate_low,
-		ecoal.pkt_rate_high,
-
-		ecoal.rx_coalesce_usecs,
-		ecoal.rx_max_coalesced_frames,
-		ecoal.rx_coalesce_usecs_irq,
-		ecoal.rx_max_coalesced_frames_irq,
-
-		ecoal.tx_coalesce_usecs,
-		ecoal.tx_max_coalesced_frames,
-		ecoal.tx_coalesce_usecs_irq,
-		ecoal.tx_max_coalesced_frames_irq,
-
-		ecoal.rx_coalesce_usecs_low,
-		ecoal.rx_max_coalesced_frames_low,
-		ecoal.tx_coalesce_usecs_low,
-		ecoal.tx_max_coalesced_frames_low,
-
-		ecoal.rx_coalesce_usecs_high,
-		ecoal.rx_max_coalesced_frames_high,
-		ecoal.tx_coalesce_usecs_high,
-		ecoal.tx_max_coalesced_frames_high)
+		ecoal.StatsBlockCoalesceUsecs,
+		ecoal.RateSampleInterval,
+		ecoal.PktRateLow,
+		ecoal.PktRateHigh,
+
+		ecoal.RxCoalesceUsecs,
+		ecoal.RxMaxCoalescedFrames,
+		ecoal.RxCoalesceUsecsIrq,
+		ecoal.RxMaxCoalescedFramesIrq,
+
+		ecoal.TxCoalesceUsecs,
+		ecoal.TxMaxCoalescedFrames,
+		ecoal.TxCoalesceUsecsIrq,
+		ecoal.TxMaxCoalescedFramesIrq,
+
+		ecoal.RxCoalesceUsecsLow,
+		ecoal.RxMaxCoalescedFramesLow,
+		ecoal.TxCoalesceUsecsLow,
+		ecoal.TxMaxCoalescedFramesLow,
+
+		ecoal.RxCoalesceUsecsHigh,
+		ecoal.RxMaxCoalescedFramesHigh,
+		ecoal.TxCoalesceUsecsHigh,
+		ecoal.TxMaxCoalescedFramesHigh)
 
 	return 0
 }
 
-func dump_per_queue_coalesce(per_queue_opt *ethtool_per_queue_op,
-	queue_mask *uint32, n_queues int) {
-	// struct ethtool_coalesce *ecoal;
-
-	// ecoal = (struct ethtool_coalesce *)(per_queue_opt + 1);
-	// for (i = 0; i < __KERNEL_DIV_ROUND_UP(MAX_NUM_QUEUE, 32); i++) {
-	// 	int queue = i * 32;
-	// 	__u32 mask = queue_mask[i];
-
-	// 	while (mask > 0) {
-	// 		if (mask & 0x1) {
-	// 			fmt.Printf(  "Queue: %d\n", queue);
-	// 			dump_coalesce(ecoal + idx);
-	// 			idx++;
-	// 		}
-	// 		mask = mask >> 1;
-	// 		queue++;
-	// 	}
-	// 	if (idx == n_queues)
-	// 		break;
-	// }
+func dump_per_queue_coalesce(queues []Coalesce, queue_mask []uint32) {
+	idx := 0
+	for i := 0; i < len(queue_mask) && idx < len(queues); i++ {
+		queue := i * 32
+		mask := queue_mask[i]
+
+		for mask > 0 {
+			if mask&0x1 != 0 {
+				fmt.Printf("Queue: %d\n", queue)
+				dump_coalesce(&queues[idx])
+				idx++
+			}
+			mask = mask >> 1
+			queue++
+		}
+	}
 }
 
 type feature_state struct {
@@ -561,25 +675,26 @@ func dump_one_feature(indent string, name string,
 	ref_state *feature_state,
 	index uint32) {
 
-	v := state.features.features[index/32].active & (1 << (index % 32))
+	block := &state.features.features[index/32]
+	bit := uint32(1) << (index % 32)
+	v := block.active & bit
 
 	if ref_state != nil {
-		rv := ref_state.features.features[index/32].active & (1 << (index % 32))
+		rv := ref_state.features.features[index/32].active & bit
 		if (v ^ rv) == 0 {
 			return
 		}
 	}
 	active_str := "off"
-	if state.features.features[index/32].active > 0 {
+	if v != 0 {
 		active_str = "on"
 	}
 	change_str := ""
-	if (state.features.features[index/32].available == 0) ||
-		(state.features.features[index/32].never_changed != 0) {
-		change_str = "[fixed]"
-	} else if state.features.features[index/32].requested^
-		state.features.features[index/32].active > 0 {
-		if state.features.features[index/32].requested > 0 {
+	if (block.available&bit == 0) ||
+		(block.never_changed&bit != 0) {
+		change_str = " [fixed]"
+	} else if (block.requested^block.active)&bit != 0 {
+		if block.requested&bit != 0 {
 			change_str = " [requested on]"
 		} else {
 			change_str = " [requested off]"
@@ -657,7 +772,7 @@ func dump_features(defs *feature_defs,
 			if defs.off_flag_matched[i] != 1 {
 				/* Show all matching feature states */
 				dump_one_feature(ind_str,
-					string(defs.def[j].name[:]),
+					cstring(defs.def[j].name[:]),
 					state, ref_state, j)
 			} else {
 				/* Show full state with the old flag name */
@@ -670,7 +785,7 @@ func dump_features(defs *feature_defs,
 	/* Show all unmatched features that have non-null names */
 	for j := uint32(0); uint64(j) < defs.n_features; j++ {
 		if defs.def[j].off_flag_index < 0 && defs.def[j].name[0] != 0 {
-			dump_one_feature("", string(defs.def[j].name[:]),
+			dump_one_feature("", cstring(defs.def[j].name[:]),
 				state, ref_state, j)
 		}
 	}
@@ -719,11 +834,57 @@ func dump_rxfhash(fhash int, val uint64) int {
 	}
 	fmt.Printf(" use these fields for computing Hash flow key:\n")
 
-	// fmt.Printf("%s\n", unparse_rxfhashopts(val))
+	fmt.Printf("%s\n", unparse_rxfhashopts(val))
 
 	return 0
 }
 
+var rxfhash_opts = []struct {
+	letter byte
+	flag   uint64
+	name   string
+}{
+	{'m', RXH_L2DA, "L2DA"},
+	{'v', RXH_VLAN, "VLAN tag"},
+	{'t', RXH_L3_PROTO, "L3 proto"},
+	{'s', RXH_IP_SRC, "IP SA"},
+	{'d', RXH_IP_DST, "IP DA"},
+	{'f', RXH_L4_B_0_1, "L4 bytes 0 & 1 [TCP/UDP src port]"},
+	{'n', RXH_L4_B_2_3, "L4 bytes 2 & 3 [TCP/UDP dst port]"},
+	{'r', RXH_DISCARD, ""},
+}
+
+/* parse_rxfhashopts turns the m|v|t|s|d|f|n|r letters into RXH_* bits */
+func parse_rxfhashopts(optstr string) (uint64, bool) {
+	data := uint64(0)
+	for i := 0; i < len(optstr); i++ {
+		found := false
+		for _, o := range rxfhash_opts {
+			if optstr[i] == o.letter {
+				data |= o.flag
+				found = true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return data, true
+}
+
+func unparse_rxfhashopts(opts uint64) string {
+	if opts == 0 {
+		return "None"
+	}
+	s := ""
+	for _, o := range rxfhash_opts {
+		if opts&o.flag != 0 && o.name != "" {
+			s += o.name + "\n"
+		}
+	}
+	return s
+}
+
 func dump_eeecmd(ep *ethtool_eee) {
 	var link_mode [127]uint32
 
@@ -914,10 +1075,7 @@ func get_stringset(ctx *cmd_context, set_id uint32,
 }
 
 func do_gdrv(ctx *cmd_context) int {
-	drvinfo := ethtool_drvinfo{
-		cmd: ETHTOOL_GDRVINFO,
-	}
-	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&drvinfo)))
+	drvinfo, err := get_drvinfo(ctx)
 	if err != nil {
 		fmt.Printf("Cannot get driver information: %v\n", err)
 		return 71
@@ -942,9 +1100,10 @@ func do_gpause(ctx *cmd_context) int {
 			fmt.Printf("Cannot get device settings: %v\n", err)
 			return 1
 		}
-		dump_pause(&epause, ecmd.advertising, ecmd.lp_advertising)
+		dump_pause(&epause, []uint32{ecmd.advertising},
+			[]uint32{ecmd.lp_advertising})
 	} else {
-		dump_pause(&epause, 0, 0)
+		dump_pause(&epause, nil, nil)
 	}
 	return 0
 }
@@ -997,7 +1156,6 @@ func do_spause(ctx *cmd_context) int {
 
 	ret := parse_generic_cmdline(ctx, &gpause_changed, &cmdline_pause)
 	if ret != 0 {
-		fmt.Printf("Parse cmdline args error\n")
 		return -1
 	}
 	epause.cmd = ETHTOOL_GPAUSEPARAM
@@ -1027,8 +1185,7 @@ func do_spause(ctx *cmd_context) int {
 func do_gcoalesce(ctx *cmd_context) int {
 	fmt.Printf("Coalesce parameters for %s:\n", ctx.devname)
 
-	ecoal := ethtool_coalesce{cmd: ETHTOOL_GCOALESCE}
-	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&ecoal)))
+	ecoal, err := get_coalesce(ctx)
 	if err == nil {
 		dump_coalesce(&ecoal)
 
@@ -1040,6 +1197,134 @@ func do_gcoalesce(ctx *cmd_context) int {
 	return 0
 }
 
+/* coalesce_params lists the -C parameters in ETHTOOL_A_COALESCE_* order
+ * with the Coalesce field each one sets
+ */
+var coalesce_params = []struct {
+	name  string
+	attr  uint16
+	field func(c *Coalesce) *uint32
+}{
+	{"rx-usecs", ETHTOOL_A_COALESCE_RX_USECS,
+		func(c *Coalesce) *uint32 { return &c.RxCoalesceUsecs }},
+	{"rx-frames", ETHTOOL_A_COALESCE_RX_MAX_FRAMES,
+		func(c *Coalesce) *uint32 { return &c.RxMaxCoalescedFrames }},
+	{"rx-usecs-irq", ETHTOOL_A_COALESCE_RX_USECS_IRQ,
+		func(c *Coalesce) *uint32 { return &c.RxCoalesceUsecsIrq }},
+	{"rx-frames-irq", ETHTOOL_A_COALESCE_RX_MAX_FRAMES_IRQ,
+		func(c *Coalesce) *uint32 { return &c.RxMaxCoalescedFramesIrq }},
+	{"tx-usecs", ETHTOOL_A_COALESCE_TX_USECS,
+		func(c *Coalesce) *uint32 { return &c.TxCoalesceUsecs }},
+	{"tx-frames", ETHTOOL_A_COALESCE_TX_MAX_FRAMES,
+		func(c *Coalesce) *uint32 { return &c.TxMaxCoalescedFrames }},
+	{"tx-usecs-irq", ETHTOOL_A_COALESCE_TX_USECS_IRQ,
+		func(c *Coalesce) *uint32 { return &c.TxCoalesceUsecsIrq }},
+	{"tx-frames-irq", ETHTOOL_A_COALESCE_TX_MAX_FRAMES_IRQ,
+		func(c *Coalesce) *uint32 { return &c.TxMaxCoalescedFramesIrq }},
+	{"stats-block-usecs", ETHTOOL_A_COALESCE_STATS_BLOCK_USECS,
+		func(c *Coalesce) *uint32 { return &c.StatsBlockCoalesceUsecs }},
+	{"adaptive-rx", ETHTOOL_A_COALESCE_USE_ADAPTIVE_RX,
+		func(c *Coalesce) *uint32 { return &c.UseAdaptiveRxCoalesce }},
+	{"adaptive-tx", ETHTOOL_A_COALESCE_USE_ADAPTIVE_TX,
+		func(c *Coalesce) *uint32 { return &c.UseAdaptiveTxCoalesce }},
+	{"pkt-rate-low", ETHTOOL_A_COALESCE_PKT_RATE_LOW,
+		func(c *Coalesce) *uint32 { return &c.PktRateLow }},
+	{"rx-usecs-low", ETHTOOL_A_COALESCE_RX_USECS_LOW,
+		func(c *Coalesce) *uint32 { return &c.RxCoalesceUsecsLow }},
+	{"rx-frames-low", ETHTOOL_A_COALESCE_RX_MAX_FRAMES_LOW,
+		func(c *Coalesce) *uint32 { return &c.RxMaxCoalescedFramesLow }},
+	{"tx-usecs-low", ETHTOOL_A_COALESCE_TX_USECS_LOW,
+		func(c *Coalesce) *uint32 { return &c.TxCoalesceUsecsLow }},
+	{"tx-frames-low", ETHTOOL_A_COALESCE_TX_MAX_FRAMES_LOW,
+		func(c *Coalesce) *uint32 { return &c.TxMaxCoalescedFramesLow }},
+	{"pkt-rate-high", ETHTOOL_A_COALESCE_PKT_RATE_HIGH,
+		func(c *Coalesce) *uint32 { return &c.PktRateHigh }},
+	{"rx-usecs-high", ETHTOOL_A_COALESCE_RX_USECS_HIGH,
+		func(c *Coalesce) *uint32 { return &c.RxCoalesceUsecsHigh }},
+	{"rx-frames-high", ETHTOOL_A_COALESCE_RX_MAX_FRAMES_HIGH,
+		func(c *Coalesce) *uint32 { return &c.RxMaxCoalescedFramesHigh }},
+	{"tx-usecs-high", ETHTOOL_A_COALESCE_TX_USECS_HIGH,
+		func(c *Coalesce) *uint32 { return &c.TxCoalesceUsecsHigh }},
+	{"tx-frames-high", ETHTOOL_A_COALESCE_TX_MAX_FRAMES_HIGH,
+		func(c *Coalesce) *uint32 { return &c.TxMaxCoalescedFramesHigh }},
+	{"sample-interval", ETHTOOL_A_COALESCE_RATE_SAMPLE_INTERVAL,
+		func(c *Coalesce) *uint32 { return &c.RateSampleInterval }},
+}
+
+/* coalesce_cmdline holds what the user asked for with -C */
+type coalesce_cmdline struct {
+	changed int
+	wanted  []int32
+	bools   [2]int
+	seen    []uint32
+	info    []cmdline_info
+}
+
+/* parse_coalesce_cmdline parses the -C arguments; do_generic_set on the
+ * returned info then updates ecoal with the wanted values.
+ */
+func parse_coalesce_cmdline(ctx *cmd_context, ecoal *Coalesce) (*coalesce_cmdline, int) {
+	cl := &coalesce_cmdline{
+		wanted: make([]int32, len(coalesce_params)),
+		seen:   make([]uint32, len(coalesce_params)),
+	}
+	for i, p := range coalesce_params {
+		cl.wanted[i] = -1
+		info := cmdline_info{
+			name:      p.name,
+			tp:        CMDL_S32,
+			seen_val:  uintptr(unsafe.Pointer(&cl.seen[i])),
+			ioctl_val: uintptr(unsafe.Pointer(p.field(ecoal))),
+		}
+		if p.attr == ETHTOOL_A_COALESCE_USE_ADAPTIVE_RX ||
+			p.attr == ETHTOOL_A_COALESCE_USE_ADAPTIVE_TX {
+			b := &cl.bools[p.attr-ETHTOOL_A_COALESCE_USE_ADAPTIVE_RX]
+			*b = -1
+			info.tp = CMDL_BOOL
+			info.wanted_val = uintptr(unsafe.Pointer(b))
+		} else {
+			info.wanted_val = uintptr(unsafe.Pointer(&cl.wanted[i]))
+		}
+		cl.info = append(cl.info, info)
+	}
+	if parse_generic_cmdline(ctx, &cl.changed, &cl.info) != 0 {
+		return nil, -1
+	}
+	return cl, 0
+}
+
+func do_scoalesce(ctx *cmd_context) int {
+	var ecoal Coalesce
+
+	cl, ret := parse_coalesce_cmdline(ctx, &ecoal)
+	if ret != 0 {
+		return ret
+	}
+
+	var err error
+	ecoal, err = get_coalesce(ctx)
+	if err != nil {
+		fmt.Printf("Cannot get device coalesce settings: %v\n", err)
+		return 82
+	}
+
+	changed := 0
+	do_generic_set(&cl.info, &changed)
+
+	if changed == 0 {
+		fmt.Printf("no coalesce parameters changed, aborting\n")
+		return 80
+	}
+
+	err = set_coalesce(ctx, ecoal)
+	if err != nil {
+		fmt.Printf("Cannot set device coalesce parameters: %v\n", err)
+		return 83
+	}
+
+	return 0
+}
+
 func get_features(ctx *cmd_context, defs *feature_defs) feature_state {
 
 	var eval ethtool_value
@@ -1190,12 +1475,171 @@ func do_gfeatures(ctx *cmd_context) int {
 }
 
 func do_sfeatures(ctx *cmd_context) int {
+	var efeatures *ethtool_sfeatures
+	var off_flags_wanted, off_flags_mask uint32
+	any_changed := 0
+
+	defs := get_feature_defs(ctx)
+	if defs.n_features > 0 {
+		efeatures = &ethtool_sfeatures{
+			cmd:  ETHTOOL_SFEATURES,
+			size: uint32((defs.n_features + 32 - 1) / 32),
+		}
+	}
+
+	/* Generate cmdline_info for legacy flags and kernel-named
+	 * features, and parse our arguments.
+	 */
+	cmdline_features := make([]cmdline_info, 2*OFF_FLAG_DEF_SIZE, 2*OFF_FLAG_DEF_SIZE+int(defs.n_features))
+	for i := 0; i < OFF_FLAG_DEF_SIZE; i++ {
+		flag_to_cmdline_info(off_flag_def[i].short_name,
+			off_flag_def[i].value, &off_flags_wanted,
+			&off_flags_mask, &cmdline_features[i])
+		flag_to_cmdline_info(off_flag_def[i].long_name,
+			off_flag_def[i].value, &off_flags_wanted,
+			&off_flags_mask, &cmdline_features[OFF_FLAG_DEF_SIZE+i])
+	}
+	for i := uint64(0); i < defs.n_features; i++ {
+		var cli cmdline_info
+		block := &efeatures.features[i/32]
+		flag_to_cmdline_info(cstring(defs.def[i].name[:]),
+			1<<(i%32), &block.requested, &block.valid, &cli)
+		cmdline_features = append(cmdline_features, cli)
+	}
+	if parse_generic_cmdline(ctx, &any_changed, &cmdline_features) != 0 {
+		return -1
+	}
+
+	if any_changed == 0 {
+		fmt.Printf("no features changed\n")
+		return 0
+	}
+
+	old_state := get_features(ctx, &defs)
+
+	if efeatures != nil {
+		/* For each offload that the user specified, update any
+		 * related features that the user did not specify and that
+		 * are not fixed.  Warn if all related features are fixed.
+		 */
+		for i := 0; i < OFF_FLAG_DEF_SIZE; i++ {
+			fixed := true
+
+			if off_flags_mask&off_flag_def[i].value == 0 {
+				continue
+			}
+			for j := uint64(0); j < defs.n_features; j++ {
+				old_block := &old_state.features.features[j/32]
+				block := &efeatures.features[j/32]
+				bit := uint32(1) << (j % 32)
+				if defs.def[j].off_flag_index != i ||
+					old_block.available&bit == 0 ||
+					old_block.never_changed&bit != 0 {
+					continue
+				}
+				fixed = false
+				if block.valid&bit == 0 {
+					block.valid |= bit
+					if off_flags_wanted&off_flag_def[i].value != 0 {
+						block.requested |= bit
+					}
+				}
+			}
+			if fixed {
+				fmt.Printf("Cannot change %s\n", off_flag_def[i].long_name)
+			}
+		}
+
+		/* Warn about generic features named on the command line
+		 * that the device cannot change
+		 */
+		for j := uint64(0); j < defs.n_features; j++ {
+			old_block := &old_state.features.features[j/32]
+			bit := uint32(1) << (j % 32)
+			if efeatures.features[j/32].valid&bit != 0 &&
+				defs.def[j].off_flag_index < 0 &&
+				(old_block.available&bit == 0 ||
+					old_block.never_changed&bit != 0) {
+				fmt.Printf("Cannot change %s\n", cstring(defs.def[j].name[:]))
+			}
+		}
+
+		err := send_ioctl(ctx, uintptr(unsafe.Pointer(efeatures)))
+		if err != nil {
+			fmt.Printf("Cannot set device feature settings: %v\n", err)
+			return 1
+		}
+	} else {
+		for i := 0; i < OFF_FLAG_DEF_SIZE; i++ {
+			if off_flag_def[i].set_cmd == 0 ||
+				off_flags_mask&off_flag_def[i].value == 0 {
+				continue
+			}
+			eval := ethtool_value{cmd: off_flag_def[i].set_cmd}
+			if off_flags_wanted&off_flag_def[i].value != 0 {
+				eval.data = 1
+			}
+			err := send_ioctl(ctx, uintptr(unsafe.Pointer(&eval)))
+			if err != nil {
+				fmt.Printf("Cannot set device %s settings: %v\n",
+					off_flag_def[i].long_name, err)
+				return 1
+			}
+		}
+
+		if off_flags_mask&ETH_FLAG_EXT_MASK != 0 {
+			eval := ethtool_value{cmd: ETHTOOL_GFLAGS}
+			err := send_ioctl(ctx, uintptr(unsafe.Pointer(&eval)))
+			if err != nil {
+				fmt.Printf("Cannot get device flag settings: %v\n", err)
+				return 91
+			}
+
+			eval.cmd = ETHTOOL_SFLAGS
+			eval.data = (eval.data & ^off_flags_mask & ETH_FLAG_EXT_MASK) |
+				(off_flags_wanted & off_flags_mask & ETH_FLAG_EXT_MASK)
+			err = send_ioctl(ctx, uintptr(unsafe.Pointer(&eval)))
+			if err != nil {
+				fmt.Printf("Cannot set device flag settings: %v\n", err)
+				return 92
+			}
+		}
+	}
+
+	/* Compare new state with requested state */
+	new_state := get_features(ctx, &defs)
+	changed := new_state.off_flags != old_state.off_flags
+	mismatch := new_state.off_flags !=
+		(old_state.off_flags & ^off_flags_mask)|(off_flags_wanted&off_flags_mask)
+	if efeatures != nil {
+		for i := uint32(0); i < efeatures.size; i++ {
+			old_active := old_state.features.features[i].active
+			new_active := new_state.features.features[i].active
+			valid := efeatures.features[i].valid
+			requested := efeatures.features[i].requested
+			if old_active != new_active {
+				changed = true
+			}
+			if new_active != (old_active & ^valid)|(requested&valid) {
+				mismatch = true
+			}
+		}
+	}
+	if mismatch {
+		if !changed {
+			fmt.Printf("Could not change any device features\n")
+			return 1
+		}
+		fmt.Printf("Actual changes:\n")
+		dump_features(&defs, &new_state, &old_state)
+	}
+
 	return 0
 }
 
 func do_sring(ctx *cmd_context) int {
 
-	var ering ethtool_ringparam
+	var ering RingParam
 	gring_changed := 0
 	ring_rx_wanted := int32(-1)
 	ring_rx_mini_wanted := int32(-1)
@@ -1206,33 +1650,35 @@ func do_sring(ctx *cmd_context) int {
 			name:       "rx",
 			tp:         CMDL_S32,
 			wanted_val: uintptr(unsafe.Pointer(&ring_rx_wanted)),
-			ioctl_val:  uintptr(unsafe.Pointer(&ering.rx_pending)),
+			ioctl_val:  uintptr(unsafe.Pointer(&ering.RxPending)),
 		},
 		{
 			name:       "rx-mini",
 			tp:         CMDL_S32,
 			wanted_val: uintptr(unsafe.Pointer(&ring_rx_mini_wanted)),
-			ioctl_val:  uintptr(unsafe.Pointer(&ering.rx_mini_pending)),
+			ioctl_val:  uintptr(unsafe.Pointer(&ering.RxMiniPending)),
 		},
 		{
 			name:       "rx-jumbo",
 			tp:         CMDL_S32,
 			wanted_val: uintptr(unsafe.Pointer(&ring_rx_jumbo_wanted)),
-			ioctl_val:  uintptr(unsafe.Pointer(&ering.rx_jumbo_pending)),
+			ioctl_val:  uintptr(unsafe.Pointer(&ering.RxJumboPending)),
 		},
 		{
 			name:       "tx",
 			tp:         CMDL_S32,
 			wanted_val: uintptr(unsafe.Pointer(&ring_tx_wanted)),
-			ioctl_val:  uintptr(unsafe.Pointer(&ering.tx_pending)),
+			ioctl_val:  uintptr(unsafe.Pointer(&ering.TxPending)),
 		},
 	}
 	changed := 0
 
-	parse_generic_cmdline(ctx, &gring_changed, &cmdline_ring)
+	if parse_generic_cmdline(ctx, &gring_changed, &cmdline_ring) != 0 {
+		return -1
+	}
 
-	ering.cmd = ETHTOOL_GRINGPARAM
-	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&ering)))
+	var err error
+	ering, err = get_rings(ctx)
 	if err != nil {
 		fmt.Printf("Cannot get device ring settings: %v\n", err)
 		return 76
@@ -1245,8 +1691,7 @@ func do_sring(ctx *cmd_context) int {
 		return 80
 	}
 
-	ering.cmd = ETHTOOL_SRINGPARAM
-	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&ering)))
+	err = set_rings(ctx, ering)
 	if err != nil {
 		fmt.Printf("Cannot set device ring parameters: %v\n", err)
 		return 81
@@ -1264,10 +1709,7 @@ func do_gring(ctx *cmd_context) int {
 
 	fmt.Printf("Ring parameters for %s:\n", ctx.devname)
 
-	ering := ethtool_ringparam{
-		cmd: ETHTOOL_GRINGPARAM,
-	}
-	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&ering)))
+	ering, err := get_rings(ctx)
 	if err == nil {
 		dump_ring(&ering)
 	} else {
@@ -1280,9 +1722,9 @@ func do_gring(ctx *cmd_context) int {
 
 func do_gregs(ctx *cmd_context) int {
 	gregs_changed := 0
-	gregs_dump_raw := 1
-	gregs_dump_hex := 1
-	gregs_dump_file := 0
+	gregs_dump_raw := 0
+	gregs_dump_hex := 0
+	gregs_dump_file := ""
 	cmdline_gregs := []cmdline_info{
 		{
 			name:       "raw",
@@ -1300,14 +1742,17 @@ func do_gregs(ctx *cmd_context) int {
 			wanted_val: uintptr(unsafe.Pointer(&gregs_dump_file)),
 		},
 	}
-	parse_generic_cmdline(ctx, &gregs_changed, &cmdline_gregs)
+	if parse_generic_cmdline(ctx, &gregs_changed, &cmdline_gregs) < 0 {
+		return -1
+	}
 
 	drvinfo := ethtool_drvinfo{cmd: ETHTOOL_GDRVINFO}
 	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&drvinfo)))
 	if err != nil {
-		fmt.Printf("Cannot get driver information")
+		fmt.Printf("Cannot get driver information: %v\n", err)
 		return 72
 	}
+	replay := gregs_dump_raw == 0 && gregs_dump_file != ""
 
 	regs := ethtool_regs{
 		cmd: ETHTOOL_GREGS,
@@ -1315,41 +1760,22 @@ func do_gregs(ctx *cmd_context) int {
 	}
 	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&regs)))
 	if err != nil {
-		fmt.Printf("Cannot get register dump: %v\n", err)
-		return 74
+		/* A saved dump only needs the format version of the live one */
+		if !replay {
+			fmt.Printf("Cannot get register dump: %v\n", err)
+			return 74
+		}
+		regs.version = driver_dump_version(cstring(drvinfo.driver[:]))
 	}
 
-	// if (!gregs_dump_raw && gregs_dump_file != NULL) {
-	// 	/* overwrite reg values from file dump */
-	// 	FILE *f = fopen(gregs_dump_file, "r");
-	// 	struct ethtool_regs *nregs;
-	// 	struct stat st;
-	// 	size_t nread;
-
-	// 	if (!f || fstat(fileno(f), &st) < 0) {
-	// 		fmt.Printf( "Can't open '%s': %s\n",
-	// 			gregs_dump_file, strerror(errno));
-	// 		if (f)
-	// 			fclose(f);
-
-	// 		return 75;
-	// 	}
-
-	// 	nregs = realloc(regs, sizeof(*regs) + st.st_size);
-	// 	if (!nregs) {
-	// 		fmt.Printf("Cannot allocate memory for register dump");
-	// 		 /* was not freed by realloc */
-	// 		return 73;
-	// 	}
-	// 	regs = nregs;
-	// 	regs.len = st.st_size;
-	// 	nread = fread(regs.data, regs.len, 1, f);
-	// 	fclose(f);
-	// 	if (nread != 1) {
-
-	// 		return 75;
-	// 	}
-	// }
+	if replay {
+		/* overwrite reg values from file dump */
+		ret := read_regs_file(gregs_dump_file, &regs)
+		if ret != 0 {
+			return ret
+		}
+		drvinfo.regdump_len = regs.len
+	}
 
 	if dump_regs(gregs_dump_raw, gregs_dump_hex,
 		&drvinfo, &regs) < 0 {
@@ -1360,6 +1786,64 @@ func do_gregs(ctx *cmd_context) int {
 	return 0
 }
 
+/* do_decode_regs_file pretty prints a register dump saved with -d raw on
+ * without the device, the driver name picks the decoder.
+ */
+func do_decode_regs_file(ctx *cmd_context) int {
+	changed := 0
+	regs_dump_hex := 0
+	driver := ""
+	version := uint32(0)
+	version_seen := 0
+	cmdline_regs_file := []cmdline_info{
+		{
+			name:       "driver",
+			tp:         CMDL_STR,
+			wanted_val: uintptr(unsafe.Pointer(&driver)),
+		},
+		{
+			name:       "version",
+			tp:         CMDL_U32,
+			wanted_val: uintptr(unsafe.Pointer(&version)),
+			seen_val:   uintptr(unsafe.Pointer(&version_seen)),
+		},
+		{
+			name:       "hex",
+			tp:         CMDL_BOOL,
+			wanted_val: uintptr(unsafe.Pointer(&regs_dump_hex)),
+		},
+	}
+
+	if ctx.argc < 1 {
+		return -1
+	}
+	file := ctx.argp[0]
+	ctx.argc--
+	ctx.argp = ctx.argp[1:]
+	if parse_generic_cmdline(ctx, &changed, &cmdline_regs_file) < 0 ||
+		driver == "" {
+		return -1
+	}
+	if version_seen == 0 {
+		version = driver_dump_version(driver)
+	}
+
+	var drvinfo ethtool_drvinfo
+	copy(drvinfo.driver[:len(drvinfo.driver)-1], driver)
+	regs := ethtool_regs{version: version}
+	ret := read_regs_file(file, &regs)
+	if ret != 0 {
+		return ret
+	}
+	drvinfo.regdump_len = regs.len
+
+	if dump_regs(0, regs_dump_hex, &drvinfo, &regs) < 0 {
+		fmt.Printf("Cannot dump registers\n")
+		return 75
+	}
+	return 0
+}
+
 func do_geeprom(ctx *cmd_context) int {
 
 	geeprom_changed := 0
@@ -1419,46 +1903,212 @@ func do_geeprom(ctx *cmd_context) int {
 
 }
 
-func do_test(ctx *cmd_context) int {
-	const (
-		ONLINE    = 0
-		OFFLINE   = 1
-		EXTERN_LB = 2
-	)
-	test_type := -1
-
-	if ctx.argc > 1 {
-		return -1
-	}
-
-	if ctx.argc == 1 {
-		if ctx.argp[0] == "online" {
-			test_type = ONLINE
-		} else if ctx.argp[0] == "offline" {
-			test_type = OFFLINE
-		} else if ctx.argp[0] == "external_lb" {
-			test_type = EXTERN_LB
-		} else {
-			return -1
-		}
-	} else {
-		test_type = OFFLINE
+/* seeprom_read_stdin fills data with the block to write from stdin, it
+ * returns the number of bytes read */
+func seeprom_read_stdin(data []uint8) (uint32, error) {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return 0, err
 	}
-	drvinfo := ethtool_drvinfo{}
-	strings := get_stringset(ctx, ETH_SS_TEST,
-		unsafe.Offsetof(drvinfo.testinfo_len), 1)
-	if strings == nil {
-		fmt.Printf("Cannot get strings\n")
-		return 74
+	if fi.Mode()&os.ModeCharDevice != 0 {
+		return 0, fmt.Errorf("block write requires data on stdin")
 	}
-
-	test := ethtool_test{
-		cmd: ETHTOOL_TEST,
-		len: strings.len,
+	n, err := io.ReadFull(os.Stdin, data)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
 	}
+	return uint32(n), err
+}
 
-	if test_type == EXTERN_LB {
-		test.flags = (ETH_TEST_FL_OFFLINE | ETH_TEST_FL_EXTERNAL_LB)
+func do_seeprom(ctx *cmd_context) int {
+
+	seeprom_changed := 0
+	seeprom_magic := uint32(0)
+	seeprom_offset := uint32(0)
+	seeprom_length := uint32(0)
+	seeprom_length_seen := uint32(0)
+	seeprom_value := uint8(0)
+	seeprom_value_seen := uint32(0)
+	seeprom_verify := 0
+	cmdline_seeprom := []cmdline_info{
+		{
+			name:       "magic",
+			tp:         CMDL_U32,
+			wanted_val: uintptr(unsafe.Pointer(&seeprom_magic)),
+		},
+		{
+			name:       "offset",
+			tp:         CMDL_U32,
+			wanted_val: uintptr(unsafe.Pointer(&seeprom_offset)),
+		},
+		{
+			name:       "length",
+			tp:         CMDL_U32,
+			wanted_val: uintptr(unsafe.Pointer(&seeprom_length)),
+			seen_val:   uintptr(unsafe.Pointer(&seeprom_length_seen)),
+		},
+		{
+			name:       "value",
+			tp:         CMDL_U8,
+			wanted_val: uintptr(unsafe.Pointer(&seeprom_value)),
+			seen_val:   uintptr(unsafe.Pointer(&seeprom_value_seen)),
+		},
+		{
+			name:       "verify",
+			tp:         CMDL_BOOL,
+			wanted_val: uintptr(unsafe.Pointer(&seeprom_verify)),
+		},
+	}
+	if parse_generic_cmdline(ctx, &seeprom_changed,
+		&cmdline_seeprom) < 0 {
+		return -1
+	}
+
+	drvinfo := ethtool_drvinfo{cmd: ETHTOOL_GDRVINFO}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&drvinfo)))
+	if err != nil {
+		fmt.Printf("Cannot get driver information: %v\n", err)
+		return 74
+	}
+
+	if seeprom_value_seen != 0 && seeprom_length_seen == 0 {
+		seeprom_length = 1
+	} else if seeprom_length_seen == 0 {
+		seeprom_length = drvinfo.eedump_len - seeprom_offset
+	}
+
+	if seeprom_value_seen != 0 && seeprom_length != 1 {
+		fmt.Printf("value requires length 1\n")
+		return 1
+	}
+
+	if seeprom_offset > drvinfo.eedump_len ||
+		seeprom_length > drvinfo.eedump_len-seeprom_offset {
+		fmt.Printf("offset & length out of bounds (EEPROM is %d bytes)\n",
+			drvinfo.eedump_len)
+		return 1
+	}
+
+	if seeprom_length == 0 || seeprom_length > MAX_DATA_BUF {
+		fmt.Printf("Invalid EEPROM write length %d\n", seeprom_length)
+		return 1
+	}
+
+	/* The driver reports its magic with every read, refuse to write
+	 * unless the caller knows it */
+	eeprom := ethtool_eeprom{
+		cmd:    ETHTOOL_GEEPROM,
+		len:    seeprom_length,
+		offset: seeprom_offset,
+	}
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&eeprom)))
+	if err != nil {
+		fmt.Printf("Cannot get EEPROM data: %v\n", err)
+		return 74
+	}
+	if eeprom.magic != seeprom_magic {
+		fmt.Printf("Wrong EEPROM magic 0x%08x, the device expects 0x%08x\n",
+			seeprom_magic, eeprom.magic)
+		return 1
+	}
+
+	eeprom.cmd = ETHTOOL_SEEPROM
+	eeprom.magic = seeprom_magic
+	if seeprom_value_seen != 0 {
+		eeprom.data[0] = seeprom_value
+	} else {
+		n, err := seeprom_read_stdin(eeprom.data[:seeprom_length])
+		if err != nil {
+			fmt.Printf("Cannot write EEPROM: %v\n", err)
+			return 75
+		}
+		if n != seeprom_length {
+			fmt.Printf("Expected %d bytes on stdin, got %d\n",
+				seeprom_length, n)
+			return 75
+		}
+	}
+	want := make([]uint8, seeprom_length)
+	copy(want, eeprom.data[:seeprom_length])
+
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&eeprom)))
+	if err != nil {
+		fmt.Printf("Cannot set EEPROM data: %v\n", err)
+		return 87
+	}
+
+	if seeprom_verify == 0 {
+		return 0
+	}
+
+	eeprom = ethtool_eeprom{
+		cmd:    ETHTOOL_GEEPROM,
+		len:    seeprom_length,
+		offset: seeprom_offset,
+	}
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&eeprom)))
+	if err != nil {
+		fmt.Printf("Cannot read back EEPROM data: %v\n", err)
+		return 74
+	}
+	bad := 0
+	for i := uint32(0); i < seeprom_length; i++ {
+		if eeprom.data[i] != want[i] {
+			fmt.Printf("EEPROM verify failed at offset 0x%04x: "+
+				"wrote 0x%02x, read 0x%02x\n",
+				seeprom_offset+i, want[i], eeprom.data[i])
+			bad++
+		}
+	}
+	if bad != 0 {
+		fmt.Printf("%d of %d bytes differ\n", bad, seeprom_length)
+		return 87
+	}
+	fmt.Printf("Verified %d bytes at offset 0x%04x\n",
+		seeprom_length, seeprom_offset)
+	return 0
+}
+
+func do_test(ctx *cmd_context) int {
+	const (
+		ONLINE    = 0
+		OFFLINE   = 1
+		EXTERN_LB = 2
+	)
+	test_type := -1
+
+	if ctx.argc > 1 {
+		return -1
+	}
+
+	if ctx.argc == 1 {
+		if ctx.argp[0] == "online" {
+			test_type = ONLINE
+		} else if ctx.argp[0] == "offline" {
+			test_type = OFFLINE
+		} else if ctx.argp[0] == "external_lb" {
+			test_type = EXTERN_LB
+		} else {
+			return -1
+		}
+	} else {
+		test_type = OFFLINE
+	}
+	drvinfo := ethtool_drvinfo{}
+	strings := get_stringset(ctx, ETH_SS_TEST,
+		unsafe.Offsetof(drvinfo.testinfo_len), 1)
+	if strings == nil {
+		fmt.Printf("Cannot get strings\n")
+		return 74
+	}
+
+	test := ethtool_test{
+		cmd: ETHTOOL_TEST,
+		len: strings.len,
+	}
+
+	if test_type == EXTERN_LB {
+		test.flags = (ETH_TEST_FL_OFFLINE | ETH_TEST_FL_EXTERNAL_LB)
 	} else if test_type == OFFLINE {
 		test.flags = ETH_TEST_FL_OFFLINE
 	} else {
@@ -1492,49 +2142,136 @@ func do_phys_id(ctx *cmd_context) int {
 	edata.data = uint32(phys_id_time)
 	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&edata)))
 	if err != nil {
-		fmt.Printf("Cannot identify NIC: %v", err)
+		fmt.Printf("Cannot identify NIC: %v\n", err)
 		return -1
 	}
 
 	return 0
 }
 
-func do_gstats(ctx *cmd_context, cmd uint32, stringset uint32, name string) int {
-	stats := &ethtool_stats{}
+var reset_components = []struct {
+	name string
+	flag uint32
+}{
+	{"mgmt", ETH_RESET_MGMT},
+	{"irq", ETH_RESET_IRQ},
+	{"dma", ETH_RESET_DMA},
+	{"filter", ETH_RESET_FILTER},
+	{"offload", ETH_RESET_OFFLOAD},
+	{"mac", ETH_RESET_MAC},
+	{"phy", ETH_RESET_PHY},
+	{"ram", ETH_RESET_RAM},
+	{"ap", ETH_RESET_AP},
+}
+
+/* parse_reset_flags composes the ETH_RESET_* flags named on the command
+ * line, it returns false on an unknown component */
+func parse_reset_flags(argp []string) (uint32, bool) {
+	data := uint32(0)
+	for i := 0; i < len(argp); i++ {
+		switch argp[i] {
+		case "flags":
+			if i+1 >= len(argp) {
+				return 0, false
+			}
+			i++
+			val, err := strconv.ParseUint(strings.TrimPrefix(
+				strings.TrimPrefix(argp[i], "0x"), "0X"), 16, 32)
+			if err != nil {
+				return 0, false
+			}
+			data |= uint32(val)
+			continue
+		case "dedicated":
+			data |= ETH_RESET_DEDICATED
+			continue
+		case "all":
+			data |= ETH_RESET_ALL
+			continue
+		}
+		found := false
+		for _, c := range reset_components {
+			if argp[i] == c.name {
+				data |= c.flag
+				found = true
+			} else if argp[i] == c.name+"-shared" {
+				data |= c.flag << ETH_RESET_SHARED_SHIFT
+				found = true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return data, true
+}
+
+/* reset_flags_str names the components in an ETH_RESET_* mask */
+func reset_flags_str(data uint32) string {
+	s := ""
+	for _, c := range reset_components {
+		if data&c.flag != 0 {
+			s += " " + c.name
+		}
+		if data&(c.flag<<ETH_RESET_SHARED_SHIFT) != 0 {
+			s += " " + c.name + "-shared"
+		}
+	}
+	return s
+}
+
+func do_reset(ctx *cmd_context) int {
+	if ctx.argc == 0 {
+		return -1
+	}
+	data, ok := parse_reset_flags(ctx.argp)
+	if !ok {
+		return -1
+	}
 
+	resetinfo := ethtool_value{cmd: ETHTOOL_RESET, data: data}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&resetinfo)))
+	if err != nil {
+		fmt.Printf("Cannot issue ETHTOOL_RESET: %v\n", err)
+		return 1
+	}
+
+	/* The driver clears the flags of the components it did reset */
+	fmt.Printf("ETHTOOL_RESET 0x%x\n", resetinfo.data)
+	if done := reset_flags_str(data &^ resetinfo.data); done != "" {
+		fmt.Printf("Components reset:%s\n", done)
+	}
+	if left := reset_flags_str(resetinfo.data); left != "" {
+		fmt.Printf("Components not reset:%s\n", left)
+	}
+	return 0
+}
+
+func do_gstats(ctx *cmd_context, cmd uint32, stringset uint32, name string) int {
 	if ctx.argc != 0 {
 		return -1
 	}
-	drvinfo := ethtool_drvinfo{}
 
-	strings := get_stringset(ctx, stringset,
-		unsafe.Offsetof(drvinfo.n_stats), 0)
+	strings := get_stats_strings(ctx, stringset)
 	if strings == nil {
 		fmt.Printf("Cannot get stats strings information\n")
 		return 96
 	}
-
-	n_stats := strings.len
-	if n_stats < 1 {
+	if strings.len < 1 {
 		fmt.Printf("no stats available\n")
 		return 94
 	}
 
-	stats.cmd = cmd
-	stats.n_stats = n_stats
-	err := send_ioctl(ctx, uintptr(unsafe.Pointer(stats)))
+	stats, err := get_stats(ctx, cmd, strings)
 	if err != nil {
-		fmt.Printf("Cannot get stats information")
+		fmt.Printf("Cannot get stats information: %v\n", err)
 		return 97
 	}
 
 	/* todo - pretty-print the strings per-driver */
 	fmt.Printf("%s statistics:\n", name)
-	for i := 0; uint32(i) < n_stats; i++ {
-		fmt.Printf("     %.*s: %d\n",
-			ETH_GSTRING_LEN,
-			string(strings.data[i*ETH_GSTRING_LEN:(i+1)*ETH_GSTRING_LEN]),
-			stats.data[i])
+	for i := 0; i < len(stats); i++ {
+		fmt.Printf("     %s: %d\n", stats[i].Name, stats[i].Value)
 	}
 
 	return 0
@@ -1589,7 +2326,7 @@ func do_grxclass(ctx *cmd_context) int {
 		flow_rss := false
 
 		if ctx.argc == 4 {
-			if ctx.argp[2] == "context" {
+			if ctx.argp[2] != "context" {
 				return -1
 			}
 			flow_rss = true
@@ -1607,14 +2344,14 @@ func do_grxclass(ctx *cmd_context) int {
 		nfccmd.cmd = ETHTOOL_GRXFH
 		nfccmd.flow_type = uint32(rx_fhash_get)
 
-		if !flow_rss {
+		if flow_rss {
 			nfccmd.flow_type |= FLOW_RSS
 		}
-		err := send_ioctl(ctx, uintptr(unsafe.Pointer(&nfccmd)))
+		err = send_ioctl(ctx, uintptr(unsafe.Pointer(&nfccmd)))
 		if err != nil {
-			fmt.Print("Cannot get RX network flow hashing options\n")
+			fmt.Printf("Cannot get RX network flow hashing options: %v\n", err)
 		} else {
-			if !flow_rss {
+			if flow_rss {
 				fmt.Printf("For RSS context %d:\n", nfccmd.rule_cnt)
 			}
 			dump_rxfhash(rx_fhash_get, nfccmd.data)
@@ -1649,6 +2386,77 @@ func do_grxclass(ctx *cmd_context) int {
 	return 0
 }
 
+func do_srxclass(ctx *cmd_context) int {
+	if ctx.argc < 2 {
+		return -1
+	}
+
+	if ctx.argp[0] == "rx-flow-hash" {
+		nfccmd := ethtool_rxnfc{cmd: ETHTOOL_SRXFH}
+		flow_rss := false
+
+		if ctx.argc == 5 {
+			if ctx.argp[3] != "context" {
+				return -1
+			}
+			val, err := strconv.ParseUint(ctx.argp[4], 0, 32)
+			if err != nil {
+				return -1
+			}
+			flow_rss = true
+			nfccmd.rule_cnt = uint32(val)
+		} else if ctx.argc != 3 {
+			return -1
+		}
+
+		rx_fhash_set := rxflow_str_to_type(ctx.argp[1])
+		if rx_fhash_set == 0 {
+			return -1
+		}
+		rx_fhash_val, ok := parse_rxfhashopts(ctx.argp[2])
+		if !ok {
+			return -1
+		}
+
+		nfccmd.flow_type = uint32(rx_fhash_set)
+		nfccmd.data = rx_fhash_val
+		if flow_rss {
+			nfccmd.flow_type |= FLOW_RSS
+		}
+		err := send_ioctl(ctx, uintptr(unsafe.Pointer(&nfccmd)))
+		if err != nil {
+			fmt.Printf("Cannot change RX network flow hashing options: %v\n", err)
+			return 1
+		}
+	} else if ctx.argp[0] == "flow-type" {
+		var rx_rule_fs ethtool_rx_flow_spec
+		rss_context := uint32(0)
+
+		if rxclass_parse_ruleopts(ctx.argp[1:], &rx_rule_fs,
+			&rss_context) < 0 {
+			return -1
+		}
+		err := rxclass_rule_ins(ctx, &rx_rule_fs, rss_context)
+		if err != nil {
+			fmt.Printf("Cannot insert classification rule\n")
+			return 1
+		}
+	} else if ctx.argc == 2 && ctx.argp[0] == "delete" {
+		rx_class_rule_del, err := strconv.ParseUint(ctx.argp[1], 0, 31)
+		if err != nil {
+			return -1
+		}
+		err = rxclass_rule_del(ctx, uint32(rx_class_rule_del))
+		if err != nil {
+			fmt.Printf("Cannot delete classification rule\n")
+			return 1
+		}
+	} else {
+		return -1
+	}
+	return 0
+}
+
 func do_tsinfo(ctx *cmd_context) int {
 
 	if ctx.argc != 0 {
@@ -1787,27 +2595,329 @@ func do_grxfh(ctx *cmd_context) int {
 		}
 	}
 
-	fmt.Printf("RSS hash function:\n")
-	if rss.hfunc == 0 {
-		fmt.Printf("    Operation not supported\n")
-		return 0
+	fmt.Printf("RSS hash function:\n")
+	if rss.hfunc == 0 {
+		fmt.Printf("    Operation not supported\n")
+		return 0
+	}
+
+	hfuncs := get_stringset(ctx, ETH_SS_RSS_HASH_FUNCS, 0, 1)
+	if hfuncs == nil {
+		fmt.Printf("Cannot get hash functions names")
+		return 1
+	}
+	for i := uint32(0); i < hfuncs.len; i++ {
+		func_str := "off"
+		if rss.hfunc&(1<<i) != 0 {
+			func_str = "on"
+		}
+		fmt.Printf("    %s: %s\n",
+			cstring(hfuncs.data[i*ETH_GSTRING_LEN:(i+1)*ETH_GSTRING_LEN]),
+			func_str)
+	}
+
+	return 0
+}
+
+/* fill_indir_table spreads the indirection table evenly over the first
+ * equal rings, or over the rings in proportion to their weights */
+func fill_indir_table(indir []uint32, rxfhindir_equal uint32,
+	rxfhindir_weight []uint32) int {
+	indir_size := uint32(len(indir))
+
+	if rxfhindir_equal != 0 {
+		for i := uint32(0); i < indir_size; i++ {
+			indir[i] = i % rxfhindir_equal
+		}
+		return 0
+	}
+
+	sum := uint32(0)
+	for _, weight := range rxfhindir_weight {
+		sum += weight
+	}
+	if sum == 0 {
+		fmt.Printf("At least one weight must be non-zero\n")
+		return 2
+	}
+	if sum > indir_size {
+		fmt.Printf("Total weight exceeds the size of the indirection table\n")
+		return 2
+	}
+
+	/* ring j gets the entries up to its share of the running sum */
+	j := -1
+	partial := uint32(0)
+	for i := uint32(0); i < indir_size; i++ {
+		for uint64(i) >= uint64(indir_size)*uint64(partial)/uint64(sum) {
+			j++
+			partial += rxfhindir_weight[j]
+		}
+		indir[i] = uint32(j)
+	}
+	return 0
+}
+
+/* parse_hkey parses a colon separated hash key of exactly key_size bytes */
+func parse_hkey(key_size uint32, rss_hkey_string string) ([]byte, int) {
+	if key_size == 0 {
+		fmt.Printf("Cannot set RX flow hash configuration:\n" +
+			" Hash key setting not supported\n")
+		return nil, 1
+	}
+
+	parts := strings.Split(rss_hkey_string, ":")
+	if uint32(len(parts)) > key_size {
+		fmt.Printf("Key is too long for device (%d > %d)\n",
+			len(parts), key_size)
+		return nil, 2
+	}
+	if uint32(len(parts)) < key_size {
+		fmt.Printf("Key is too short for device (%d < %d)\n",
+			len(parts), key_size)
+		return nil, 2
+	}
+	hkey := make([]byte, key_size)
+	for i, part := range parts {
+		val, err := strconv.ParseUint(part, 16, 8)
+		if err != nil || len(part) > 2 {
+			fmt.Printf("Invalid RSS hash key format\n")
+			return nil, 2
+		}
+		hkey[i] = uint8(val)
+	}
+	return hkey, 0
+}
+
+func do_srxfhindir(ctx *cmd_context, rxfhindir_default bool,
+	rxfhindir_equal uint32, rxfhindir_weight []uint32) int {
+	if !rxfhindir_default && rxfhindir_equal == 0 && rxfhindir_weight == nil {
+		fmt.Printf("'equal', 'weight' or 'default' must be specified\n")
+		return 1
+	}
+
+	indir_head := ethtool_rxfh_indir{cmd: ETHTOOL_GRXFHINDIR}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&indir_head)))
+	if err != nil {
+		fmt.Printf("Cannot get RX flow hash indirection table size: %v\n", err)
+		return 1
+	}
+
+	indir := ethtool_rxfh_indir{cmd: ETHTOOL_SRXFHINDIR}
+	/* a zero size table resets the default spreading */
+	if !rxfhindir_default {
+		indir.size = indir_head.size
+		ret := fill_indir_table(indir.ring_index[:indir.size],
+			rxfhindir_equal, rxfhindir_weight)
+		if ret != 0 {
+			return ret
+		}
+	}
+
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&indir)))
+	if err != nil {
+		fmt.Printf("Cannot set RX flow hash indirection table: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func do_srxfh(ctx *cmd_context) int {
+	rxfhindir_equal := uint32(0)
+	rxfhindir_default := false
+	var rxfhindir_weight []uint32
+	rxfhindir_key := ""
+	req_hfunc_name := ""
+	rss_context := uint32(0)
+	delete := false
+
+	if ctx.argc < 1 {
+		return -1
+	}
+
+	for arg_num := 0; arg_num < ctx.argc; {
+		arg := ctx.argp[arg_num]
+		arg_num++
+		/* every option but default and delete takes a value */
+		if arg != "default" && arg != "delete" && arg != "weight" &&
+			arg_num >= ctx.argc {
+			return -1
+		}
+		switch arg {
+		case "equal":
+			val, err := strconv.ParseUint(ctx.argp[arg_num], 0, 31)
+			if err != nil || val == 0 {
+				return -1
+			}
+			rxfhindir_equal = uint32(val)
+			arg_num++
+		case "weight":
+			rxfhindir_weight = []uint32{}
+			for arg_num < ctx.argc {
+				val, err := strconv.ParseUint(ctx.argp[arg_num], 0, 32)
+				if err != nil {
+					break
+				}
+				rxfhindir_weight = append(rxfhindir_weight, uint32(val))
+				arg_num++
+			}
+			if len(rxfhindir_weight) == 0 {
+				return -1
+			}
+		case "hkey":
+			rxfhindir_key = ctx.argp[arg_num]
+			arg_num++
+		case "default":
+			rxfhindir_default = true
+		case "hfunc":
+			req_hfunc_name = ctx.argp[arg_num]
+			arg_num++
+		case "context":
+			if ctx.argp[arg_num] == "new" {
+				rss_context = ETH_RXFH_CONTEXT_ALLOC
+			} else {
+				val, err := strconv.ParseUint(ctx.argp[arg_num], 0, 31)
+				if err != nil || val == 0 {
+					return -1
+				}
+				rss_context = uint32(val)
+			}
+			arg_num++
+		case "delete":
+			delete = true
+		default:
+			return -1
+		}
+	}
+
+	if rxfhindir_equal != 0 && rxfhindir_weight != nil {
+		fmt.Printf("Equal and weight options are mutually exclusive\n")
+		return 1
+	}
+	if rxfhindir_equal != 0 && rxfhindir_default {
+		fmt.Printf("Equal and default options are mutually exclusive\n")
+		return 1
+	}
+	if rxfhindir_weight != nil && rxfhindir_default {
+		fmt.Printf("Weight and default options are mutually exclusive\n")
+		return 1
+	}
+	if rxfhindir_default && rss_context != 0 {
+		fmt.Printf("Default and context options are mutually exclusive\n")
+		return 1
+	}
+	if delete && (rss_context == 0 || rss_context == ETH_RXFH_CONTEXT_ALLOC) {
+		fmt.Printf("Delete option requires an existing context\n")
+		return 1
+	}
+	if delete && (rxfhindir_equal != 0 || rxfhindir_weight != nil ||
+		rxfhindir_default || rxfhindir_key != "" || req_hfunc_name != "") {
+		fmt.Printf("Delete option cannot be combined with other settings\n")
+		return 1
+	}
+
+	ring_count := ethtool_rxnfc{cmd: ETHTOOL_GRXRINGS}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&ring_count)))
+	if err != nil {
+		fmt.Printf("Cannot get RX ring count: %v\n", err)
+		return 1
+	}
+	if uint64(rxfhindir_equal) > ring_count.data {
+		fmt.Printf("Equal value %d exceeds number of rings %d\n",
+			rxfhindir_equal, ring_count.data)
+		return 1
+	}
+	if uint64(len(rxfhindir_weight)) > ring_count.data {
+		fmt.Printf("Number of weights %d exceeds number of rings %d\n",
+			len(rxfhindir_weight), ring_count.data)
+		return 1
+	}
+
+	rss_head := ethtool_rxfh{cmd: ETHTOOL_GRSSH}
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&rss_head)))
+	if err == syscall.EOPNOTSUPP && rxfhindir_key == "" &&
+		req_hfunc_name == "" && rss_context == 0 {
+		return do_srxfhindir(ctx, rxfhindir_default, rxfhindir_equal,
+			rxfhindir_weight)
+	} else if err != nil {
+		fmt.Printf("Cannot get RX flow hash indir size and key size: %v\n", err)
+		return 1
+	}
+
+	var hkey []byte
+	if rxfhindir_key != "" {
+		var ret int
+		hkey, ret = parse_hkey(rss_head.key_size, rxfhindir_key)
+		if ret != 0 {
+			return ret
+		}
+	}
+
+	req_hfunc := uint8(0)
+	if req_hfunc_name != "" {
+		if rss_head.hfunc == 0 {
+			fmt.Printf("Hash function selection not supported\n")
+			return 1
+		}
+		hfuncs := get_stringset(ctx, ETH_SS_RSS_HASH_FUNCS, 0, 1)
+		if hfuncs == nil {
+			fmt.Printf("Cannot get hash functions names\n")
+			return 1
+		}
+		for i := uint32(0); i < hfuncs.len && req_hfunc == 0; i++ {
+			name := cstring(hfuncs.data[i*ETH_GSTRING_LEN : (i+1)*ETH_GSTRING_LEN])
+			if name == req_hfunc_name {
+				req_hfunc = 1 << i
+			}
+		}
+		if req_hfunc == 0 {
+			fmt.Printf("Unknown hash function: %s\n", req_hfunc_name)
+			return 1
+		}
+	}
+
+	rss := ethtool_rxfh{
+		cmd:         ETHTOOL_SRSSH,
+		rss_context: rss_context,
+		hfunc:       req_hfunc,
+	}
+	/* the key follows the indirection table if one is sent */
+	indir_bytes := uint32(0)
+	switch {
+	case delete, rxfhindir_default:
+		/* a zero size table deletes a context, or resets the
+		 * default spreading of the main one */
+		rss.indir_size = 0
+	case rxfhindir_equal != 0 || rxfhindir_weight != nil:
+		if rss_head.indir_size == 0 {
+			fmt.Printf("Cannot set RX flow hash configuration:\n" +
+				" Indirection table setting not supported\n")
+			return 1
+		}
+		rss.indir_size = rss_head.indir_size
+		ret := fill_indir_table(rss.rss_config[:rss.indir_size],
+			rxfhindir_equal, rxfhindir_weight)
+		if ret != 0 {
+			return ret
+		}
+		indir_bytes = rss.indir_size * 4
+	default:
+		rss.indir_size = ETH_RXFH_INDIR_NO_CHANGE
+	}
+	if hkey != nil {
+		rss.key_size = rss_head.key_size
+		config := (*[MAX_DATA_BUF * 4]byte)(unsafe.Pointer(&rss.rss_config[0]))
+		copy(config[indir_bytes:], hkey)
 	}
 
-	hfuncs := get_stringset(ctx, ETH_SS_RSS_HASH_FUNCS, 0, 1)
-	if hfuncs == nil {
-		fmt.Printf("Cannot get hash functions names")
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&rss)))
+	if err != nil {
+		fmt.Printf("Cannot set RX flow hash configuration: %v\n", err)
 		return 1
 	}
-	func_str := "off"
-	for i := uint32(0); i < hfuncs.len; i++ {
-		if rss.hfunc&(1<<i) != 0 {
-			func_str = "on"
-		}
-		fmt.Printf("    %s: %s\n",
-			hfuncs.data[i*ETH_GSTRING_LEN:],
-			func_str)
+	if rss_context == ETH_RXFH_CONTEXT_ALLOC {
+		fmt.Printf("New RSS context is %d\n", rss.rss_context)
 	}
-
 	return 0
 }
 
@@ -1895,45 +3005,47 @@ func do_getfwdump(ctx *cmd_context) int {
 }
 
 func do_schannels(ctx *cmd_context) int {
-	var echannels ethtool_channels
+	var echannels Channels
 	gchannels_changed := 0
-	channels_rx_wanted := -1
-	channels_tx_wanted := -1
-	channels_other_wanted := -1
-	channels_combined_wanted := -1
+	channels_rx_wanted := int32(-1)
+	channels_tx_wanted := int32(-1)
+	channels_other_wanted := int32(-1)
+	channels_combined_wanted := int32(-1)
 	cmdline_channels := []cmdline_info{
 		{
 			name:       "rx",
 			tp:         CMDL_S32,
 			wanted_val: uintptr(unsafe.Pointer(&channels_rx_wanted)),
-			ioctl_val:  uintptr(unsafe.Pointer(&echannels.rx_count)),
+			ioctl_val:  uintptr(unsafe.Pointer(&echannels.RxCount)),
 		},
 		{
 			name:       "tx",
 			tp:         CMDL_S32,
 			wanted_val: uintptr(unsafe.Pointer(&channels_tx_wanted)),
-			ioctl_val:  uintptr(unsafe.Pointer(&echannels.tx_count)),
+			ioctl_val:  uintptr(unsafe.Pointer(&echannels.TxCount)),
 		},
 		{
 			name:       "other",
 			tp:         CMDL_S32,
 			wanted_val: uintptr(unsafe.Pointer(&channels_other_wanted)),
-			ioctl_val:  uintptr(unsafe.Pointer(&echannels.other_count)),
+			ioctl_val:  uintptr(unsafe.Pointer(&echannels.OtherCount)),
 		},
 		{
 			name:       "combined",
 			tp:         CMDL_S32,
 			wanted_val: uintptr(unsafe.Pointer(&channels_combined_wanted)),
-			ioctl_val:  uintptr(unsafe.Pointer(&echannels.combined_count)),
+			ioctl_val:  uintptr(unsafe.Pointer(&echannels.CombinedCount)),
 		},
 	}
 	changed := 0
 
-	parse_generic_cmdline(ctx, &gchannels_changed,
-		&cmdline_channels)
+	if parse_generic_cmdline(ctx, &gchannels_changed,
+		&cmdline_channels) != 0 {
+		return -1
+	}
 
-	echannels.cmd = ETHTOOL_GCHANNELS
-	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&echannels)))
+	var err error
+	echannels, err = get_channels(ctx)
 	if err != nil {
 		fmt.Printf("Cannot get device channel parameters: %v\n", err)
 		return 1
@@ -1944,14 +3056,13 @@ func do_schannels(ctx *cmd_context) int {
 	if changed == 0 {
 		fmt.Printf("no channel parameters changed.\n")
 		fmt.Printf("current values: rx %d tx %d other %d"+
-			" combined %d\n", echannels.rx_count,
-			echannels.tx_count, echannels.other_count,
-			echannels.combined_count)
+			" combined %d\n", echannels.RxCount,
+			echannels.TxCount, echannels.OtherCount,
+			echannels.CombinedCount)
 		return 0
 	}
 
-	echannels.cmd = ETHTOOL_SCHANNELS
-	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&echannels)))
+	err = set_channels(ctx, echannels)
 	if err != nil {
 		fmt.Printf("Cannot set device channel parameters: %v\n", err)
 		return 1
@@ -1962,14 +3073,13 @@ func do_schannels(ctx *cmd_context) int {
 
 func do_gchannels(ctx *cmd_context) int {
 
-	if ctx.argc < 1 {
+	if ctx.argc != 0 {
 		return -1
 	}
 
 	fmt.Printf("Channel parameters for %s:\n", ctx.devname)
 
-	echannels := ethtool_channels{cmd: ETHTOOL_GCHANNELS}
-	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&echannels)))
+	echannels, err := get_channels(ctx)
 	if err == nil {
 		dump_channels(&echannels)
 	} else {
@@ -1980,101 +3090,280 @@ func do_gchannels(ctx *cmd_context) int {
 
 }
 
-func do_gprivflags(ctx *cmd_context) int {
-
-	max_len, cur_len := 0, 0
-
-	if ctx.argc < 1 {
-		return -1
-	}
+/* get_privflag_names returns the private flag names of the device, at
+ * most the 32 that ETHTOOL_{G,S}PFLAGS can cover
+ */
+func get_privflag_names(ctx *cmd_context, verb string) ([]string, int) {
 	var drvinfo ethtool_drvinfo
 	strings := get_stringset(ctx, ETH_SS_PRIV_FLAGS,
 		unsafe.Offsetof(drvinfo.n_priv_flags), 1)
 	if strings == nil {
 		fmt.Printf("Cannot get private flag names\n")
-		return 1
+		return nil, 1
 	}
 	if strings.len == 0 {
 		fmt.Printf("No private flags defined\n")
-		return 1
+		return nil, 1
 	}
 	if strings.len > 32 {
-		/* ETHTOOL_GPFLAGS can only cover 32 flags */
-		fmt.Printf("Only showing first 32 private flags\n")
+		/* ETHTOOL_{G,S}PFLAGS can only cover 32 flags */
+		fmt.Printf("Only %s first 32 private flags\n", verb)
 		strings.len = 32
 	}
 
+	names := make([]string, strings.len)
+	for i := uint32(0); i < strings.len; i++ {
+		names[i] = cstring(strings.data[i*ETH_GSTRING_LEN : (i+1)*ETH_GSTRING_LEN])
+	}
+	return names, 0
+}
+
+func dump_privflags(ctx *cmd_context, names []string, test func(i int) bool) {
+	/* Find longest string and align all strings accordingly */
+	max_len := 0
+	for _, name := range names {
+		if len(name) > max_len {
+			max_len = len(name)
+		}
+	}
+
+	fmt.Printf("Private flags for %s:\n", ctx.devname)
+	for i, name := range names {
+		flag_str := "off"
+		if test(i) {
+			flag_str = "on"
+		}
+		fmt.Printf("%-*s: %s\n", max_len, name, flag_str)
+	}
+}
+
+/* parse_privflags parses FLAG on|off ... against the flag names of the
+ * device; wanted and seen are indexed like names.
+ */
+func parse_privflags(ctx *cmd_context, names []string) ([]bool, []bool, int) {
+	wanted := make([]bool, len(names))
+	seen := make([]bool, len(names))
+
+	if ctx.argc == 0 || ctx.argc%2 != 0 {
+		return nil, nil, -1
+	}
+	for i := 0; i < ctx.argc; i += 2 {
+		idx := -1
+		for j, name := range names {
+			if name == ctx.argp[i] {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			fmt.Printf("Unknown private flag %s\n", ctx.argp[i])
+			return nil, nil, 1
+		}
+		switch ctx.argp[i+1] {
+		case "on":
+			wanted[idx] = true
+		case "off":
+			wanted[idx] = false
+		default:
+			return nil, nil, -1
+		}
+		seen[idx] = true
+	}
+	return wanted, seen, 0
+}
+
+func do_gprivflags(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+	names, ret := get_privflag_names(ctx, "showing")
+	if names == nil {
+		return ret
+	}
+
 	flags := ethtool_value{
 		cmd: ETHTOOL_GPFLAGS,
 	}
 	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&flags)))
 	if err != nil {
-		fmt.Printf("Cannot get private flags: %v", err)
+		fmt.Printf("Cannot get private flags: %v\n", err)
 		return -1
 	}
 
-	/* Find longest string and align all strings accordingly */
-	for i := uint32(0); i < strings.len; i++ {
-		cur_len = len(string(strings.data[i*ETH_GSTRING_LEN]))
-		if cur_len > max_len {
-			max_len = cur_len
-		}
-	}
+	dump_privflags(ctx, names, func(i int) bool {
+		return flags.data&(1<<uint(i)) != 0
+	})
+	return 0
+}
 
-	fmt.Printf("Private flags for %s:\n", ctx.devname)
-	flag_str := "off"
+func do_sprivflags(ctx *cmd_context) int {
+	var wanted_flags, seen_flags uint32
 
-	for i := uint32(0); i < strings.len; i++ {
-		if (flags.data & (1 << i)) != 0 {
-			flag_str = "on"
+	names, ret := get_privflag_names(ctx, "setting")
+	if names == nil {
+		return ret
+	}
+	wanted, seen, ret := parse_privflags(ctx, names)
+	if ret != 0 {
+		return ret
+	}
+	for i := range names {
+		if seen[i] {
+			seen_flags |= 1 << uint(i)
+		}
+		if wanted[i] {
+			wanted_flags |= 1 << uint(i)
 		}
-		fmt.Printf("%-*s: %s\n",
-			max_len,
-			string(strings.data[i*ETH_GSTRING_LEN]),
-			flag_str)
 	}
 
+	flags := ethtool_value{
+		cmd: ETHTOOL_GPFLAGS,
+	}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&flags)))
+	if err != nil {
+		fmt.Printf("Cannot get private flags: %v\n", err)
+		return 1
+	}
+
+	flags.cmd = ETHTOOL_SPFLAGS
+	flags.data = (flags.data & ^seen_flags) | wanted_flags
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&flags)))
+	if err != nil {
+		fmt.Printf("Cannot set private flags: %v\n", err)
+		return 1
+	}
 	return 0
 }
 
-func do_getmodule(ctx *cmd_context) int {
-
-	geeprom_offset := uint32(0)
-	geeprom_length := uint32(0)
-	// geeprom_changed := 0
-	geeprom_dump_raw := 0
-	geeprom_dump_hex := 0
-	geeprom_length_seen := 0
+/* getmodule_params holds the -m arguments, shared by the ioctl and the
+ * netlink handlers
+ */
+type getmodule_params struct {
+	offset      uint32
+	length      uint32
+	page        uint8
+	bank        uint8
+	i2c_address uint8
+	dump_raw    int
+	dump_hex    int
+
+	offset_seen int
+	length_seen int
+	page_seen   int
+	bank_seen   int
+	i2c_seen    int
+}
 
-	// parse_generic_cmdline(ctx, &geeprom_changed,
-	// 		      cmdline_geeprom, ARRAY_SIZE(cmdline_geeprom));
+func parse_getmodule_cmdline(ctx *cmd_context) (*getmodule_params, int) {
+	params := &getmodule_params{i2c_address: ETH_I2C_ADDRESS_LOW}
+	changed := 0
+	cmdline_getmodule := []cmdline_info{
+		{
+			name:       "raw",
+			tp:         CMDL_BOOL,
+			wanted_val: uintptr(unsafe.Pointer(&params.dump_raw)),
+		},
+		{
+			name:       "hex",
+			tp:         CMDL_BOOL,
+			wanted_val: uintptr(unsafe.Pointer(&params.dump_hex)),
+		},
+		{
+			name:       "offset",
+			tp:         CMDL_U32,
+			wanted_val: uintptr(unsafe.Pointer(&params.offset)),
+			seen_val:   uintptr(unsafe.Pointer(&params.offset_seen)),
+		},
+		{
+			name:       "length",
+			tp:         CMDL_U32,
+			wanted_val: uintptr(unsafe.Pointer(&params.length)),
+			seen_val:   uintptr(unsafe.Pointer(&params.length_seen)),
+		},
+		{
+			name:       "page",
+			tp:         CMDL_U8,
+			wanted_val: uintptr(unsafe.Pointer(&params.page)),
+			seen_val:   uintptr(unsafe.Pointer(&params.page_seen)),
+		},
+		{
+			name:       "bank",
+			tp:         CMDL_U8,
+			wanted_val: uintptr(unsafe.Pointer(&params.bank)),
+			seen_val:   uintptr(unsafe.Pointer(&params.bank_seen)),
+		},
+		{
+			name:       "i2c",
+			tp:         CMDL_U8,
+			wanted_val: uintptr(unsafe.Pointer(&params.i2c_address)),
+			seen_val:   uintptr(unsafe.Pointer(&params.i2c_seen)),
+		},
+	}
+	if parse_generic_cmdline(ctx, &changed, &cmdline_getmodule) != 0 {
+		return nil, -1
+	}
 
-	if geeprom_dump_raw != 0 && geeprom_dump_hex != 0 {
+	if params.dump_raw != 0 && params.dump_hex != 0 {
 		fmt.Printf("Hex and raw dump cannot be specified together\n")
+		return nil, 1
+	}
+	if params.i2c_address > ETH_I2C_MAX_ADDRESS {
+		fmt.Printf("Invalid I2C address 0x%x\n", params.i2c_address)
+		return nil, 1
+	}
+	return params, 0
+}
+
+/* dump_module_data writes module memory read at offset as raw bytes or
+ * as a hex dump
+ */
+func dump_module_data(params *getmodule_params, data []byte, offset uint32) {
+	if params.dump_raw != 0 {
+		os.Stdout.Write(data)
+		return
+	}
+	dump_hex(os.Stdout, data, uint32(len(data)), offset)
+}
+
+func do_getmodule(ctx *cmd_context) int {
+
+	params, ret := parse_getmodule_cmdline(ctx)
+	if params == nil {
+		return ret
+	}
+
+	/* ETHTOOL_GMODULEEEPROM has no notion of pages */
+	if params.page_seen != 0 || params.bank_seen != 0 ||
+		params.i2c_seen != 0 {
+		fmt.Printf("Cannot select page, bank or i2c address: " +
+			"paged module EEPROM access not supported\n")
 		return 1
 	}
+	geeprom_offset := params.offset
+	geeprom_length := params.length
+	geeprom_dump_hex := params.dump_hex
 
 	modinfo := ethtool_modinfo{cmd: ETHTOOL_GMODULEINFO}
 	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&modinfo)))
 	if err != nil {
-		fmt.Printf("Cannot get module EEPROM information")
+		fmt.Printf("Cannot get module EEPROM information: %v\n", err)
 		return 1
 	}
 
-	if geeprom_length_seen == 0 {
+	if geeprom_offset > modinfo.eeprom_len {
+		fmt.Printf("Offset %d is beyond the %d bytes of module EEPROM\n",
+			geeprom_offset, modinfo.eeprom_len)
+		return 1
+	}
+	if params.length_seen == 0 {
 		geeprom_length = modinfo.eeprom_len
 	}
 
-	if modinfo.eeprom_len < uint32(geeprom_offset+geeprom_length) {
+	if modinfo.eeprom_len < geeprom_offset+geeprom_length {
 		geeprom_length = modinfo.eeprom_len - geeprom_offset
 	}
 
-	// eeprom = calloc(1, sizeof(*eeprom)+geeprom_length);
-	// if (!eeprom) {
-	// 	fmt.Printf("Cannot allocate memory for Module EEPROM data");
-	// 	return 1;
-	// }
-
 	eeprom := ethtool_eeprom{
 		cmd:    ETHTOOL_GMODULEEEPROM,
 		len:    geeprom_length,
@@ -2099,9 +3388,11 @@ func do_getmodule(ctx *cmd_context) int {
 	 * The current kernel implementation returns a blob, which contains:
 	 *  - ETH_MODULE_SFF_8079 => The A0 page only.
 	 *  - ETH_MODULE_SFF_8472 => The A0 and A2 page concatenated.
+	 *  - ETH_MODULE_SFF_8436/8636 => The lower page followed by the
+	 *    upper pages, see qsfp.go.
 	 */
-	if geeprom_dump_raw != 0 {
-		// fwrite(eeprom.data, 1, eeprom.len)
+	if params.dump_raw != 0 {
+		dump_module_data(params, eeprom.data[:eeprom.len], eeprom.offset)
 	} else {
 		if eeprom.offset != 0 ||
 			(eeprom.len != modinfo.eeprom_len) {
@@ -2109,31 +3400,76 @@ func do_getmodule(ctx *cmd_context) int {
 		} else if geeprom_dump_hex == 0 {
 			switch modinfo.tp {
 			case ETH_MODULE_SFF_8079:
-				fallthrough
+				sff8079_show_all(eeprom.data[:])
 			case ETH_MODULE_SFF_8472:
-				fallthrough
+				sff8079_show_all(eeprom.data[:])
+				sff8472_show_all(eeprom.data[:])
 			case ETH_MODULE_SFF_8436:
 				fallthrough
 			case ETH_MODULE_SFF_8636:
-				fallthrough
-
+				/* Drivers report CMIS modules as SFF-8636 too */
+				if module_decoder_name(eeprom.data[0]) == "cmis" {
+					err = cmis_decode(image_module_reader(
+						eeprom.data[:eeprom.len],
+						module_cmis_ioctl_pages))
+					if err != nil {
+						fmt.Printf("Cannot decode CMIS module EEPROM: %v\n", err)
+						geeprom_dump_hex = 1
+					}
+					break
+				}
+				sff8636_show_all(eeprom.data[:], modinfo.eeprom_len)
 			default:
 				geeprom_dump_hex = 1
-				break
 			}
 		}
 		if geeprom_dump_hex != 0 {
-			dump_hex(os.Stdout, eeprom.data[:],
-				eeprom.len, eeprom.offset)
+			dump_module_data(params, eeprom.data[:eeprom.len],
+				eeprom.offset)
+		}
+	}
+
+	return 0
+}
+
+func do_decode_module_file(ctx *cmd_context) int {
+
+	decoder := ""
+	if ctx.argc != 1 && ctx.argc != 3 {
+		return -1
+	}
+	if ctx.argc == 3 {
+		if ctx.argp[1] != "type" {
+			return -1
+		}
+		decoder = ctx.argp[2]
+		if _, ok := module_decoders[decoder]; !ok {
+			fmt.Printf("Unknown module EEPROM type %s\n", decoder)
+			return 1
 		}
 	}
 
+	image, err := ioutil.ReadFile(ctx.argp[0])
+	if err != nil {
+		fmt.Printf("Cannot read module EEPROM image: %v\n", err)
+		return 1
+	}
+	if len(image) == 0 {
+		fmt.Printf("Module EEPROM image %s is empty\n", ctx.argp[0])
+		return 1
+	}
+
+	err = module_decode_image(image, decoder)
+	if err != nil {
+		fmt.Printf("Cannot decode module EEPROM image: %v\n", err)
+		return 1
+	}
 	return 0
 }
 
 func do_geee(ctx *cmd_context) int {
 
-	if ctx.argc < 1 {
+	if ctx.argc != 0 {
 		return -1
 	}
 
@@ -2219,7 +3555,7 @@ func do_stunable(ctx *cmd_context) int {
 		// copy(tuna.data, &tinfo[i].wanted)
 		ret := send_ioctl(ctx, uintptr(unsafe.Pointer(&tuna)))
 		if ret != nil {
-			fmt.Printf(tunable_strings[tuna.id])
+			fmt.Printf("%s: Cannot set tunable: %v\n", tunable_strings[tuna.id], ret)
 			return -1
 		}
 	}
@@ -2486,14 +3822,8 @@ type options struct {
 }
 
 var (
-	rootCmd = &cobra.Command{
-		Use: "ethtool",
-		Short: "ethtool DEVNAME	Display standard information about device",
-		Run: Do_actions,
-	}
-
 	opt_args = []options{
-		{"change", "s", false, "Change generic options", true, nil, nil,
+		{"change", "s", false, "Change generic options", true, do_sset, nil,
 			"		[ speed %d ]\n" +
 				"		[ duplex half|full ]\n" +
 				"		[ port tp|aui|bnc|mii|fibre|da ]\n" +
@@ -2506,13 +3836,13 @@ var (
 				"		[ sopass %x:%x:%x:%x:%x:%x ]\n" +
 				"		[ msglvl %d[/%d] | type on|off ... [--] ]\n" +
 				"		[ master-slave master-preferred|slave-preferred|master-force|slave-force ]\n"},
-		{"show-pause", "a", false, "Show pause options", true, do_gpause, nil, ""},
+		{"show-pause", "a", false, "Show pause options", true, do_gpause, nl_gpause, ""},
 		{"pause", "A", false, "Set pause options", true, do_spause, nil,
 			"		[ autoneg on|off ]\n" +
 				"		[ rx on|off ]\n" +
 				"		[ tx on|off ]\n"},
-		{"show-coalesce", "c", false, "Show coalesce options", true, do_gcoalesce, nil, ""},
-		{"coalesce", "C", false, "Set coalesce options", true, nil, nil,
+		{"show-coalesce", "c", false, "Show coalesce options", true, do_gcoalesce, nl_gcoalesce, ""},
+		{"coalesce", "C", false, "Set coalesce options", true, do_scoalesce, nl_scoalesce,
 			"		[adaptive-rx on|off]\n" +
 				"		[adaptive-tx on|off]\n" +
 				"		[rx-usecs N]\n" +
@@ -2535,31 +3865,37 @@ var (
 				"		[tx-usecs-high N]\n" +
 				"		[tx-frames-high N]\n" +
 				"		[sample-interval N]\n"},
-		{"show-ring", "g", false, "Query RX/TX ring parameters", true, do_gring, nil, ""},
+		{"show-ring", "g", false, "Query RX/TX ring parameters", true, do_gring, nl_gring, ""},
 
 		{"set-ring", "G", false, "Set RX/TX ring parameters", true, do_sring, nil,
 			"		[ rx N ]\n" +
 				"		[ rx-mini N ]\n" +
 				"		[ rx-jumbo N ]\n" +
 				"		[ tx N ]\n"},
-		{"show-features", "k", false, "Get state of protocol offload and other features", true, do_gfeatures, nil, ""},
-		{"features", "K", false, "Set protocol offload and other features", true, nil, nil,
+		{"show-features", "k", false, "Get state of protocol offload and other features", true, do_gfeatures, nl_gfeatures, ""},
+		{"features", "K", false, "Set protocol offload and other features", true, do_sfeatures, nil,
 			"		FEATURE on|off ...\n"},
 
 		{"driver", "i", false, "Show driver information", true, do_gdrv, nil, ""},
 		{"register-dump", "d", false, "Do a register dump", true, do_gregs, nil,
 			"		[ raw on|off ]\n" +
+				"		[ hex on|off ]\n" +
 				"		[ file FILENAME ]\n"},
 		{"eeprom-dump", "e", false, "Do a EEPROM dump", true, do_geeprom, nil,
 			"		[ raw on|off ]\n" +
 				"		[ offset N ]\n" +
-				"		[ length N ]\n"},
-		{"change-eeprom", "E", false, "Change bytes in device EEPROM", true, nil, nil,
+				"		[ length N ]\n" +
+				"		[ page N ]\n" +
+				"		[ bank N ]\n" +
+				"		[ i2c N ]\n"},
+		{"change-eeprom", "E", false, "Change bytes in device EEPROM", true, do_seeprom, nil,
 			"		[ magic N ]\n" +
 				"		[ offset N ]\n" +
 				"		[ length N ]\n" +
-				"		[ value N ]\n"},
-		{"negotiate", "r", false, "Restart N-WAY negotiation", true, nil, nil, ""},
+				"		[ value N ]\n" +
+				"		[ verify on|off ]\n"},
+		{"negotiate", "r", false, "Restart N-WAY negotiation", true, do_nway_rst, nil,
+			"		[ wait N ]\n"},
 		{"identify", "p", false, "Show visible port identification (e.g. blinking)", true, do_phys_id, nil,
 			"               [ TIME-IN-SECONDS ]\n"},
 		{"test", "t", false, "Execute adapter self test", true, do_test, nil,
@@ -2570,7 +3906,7 @@ var (
 			"		[ rx-flow-hash tcp4|udp4|ah4|esp4|sctp4|" +
 				"tcp6|udp6|ah6|esp6|sctp6 [context %d] |\n" +
 				"		  rule %d ]\n"},
-		{"config-ntuple", "N", false, "Configure Rx network flow classification options or rules", true, nil, nil,
+		{"config-ntuple", "N", false, "Configure Rx network flow classification options or rules", true, do_srxclass, nil,
 			"		rx-flow-hash tcp4|udp4|ah4|esp4|sctp4|" +
 				"tcp6|udp6|ah6|esp6|sctp6 m|v|t|s|d|f|n|r... [context %d] |\n" +
 				"		flow-type ether|ip4|tcp4|udp4|sctp4|ah4|esp4|" +
@@ -2592,12 +3928,12 @@ var (
 				"			[ dst-mac %x:%x:%x:%x:%x:%x [m %x:%x:%x:%x:%x:%x] ]\n" +
 				"			[ action %d ] | [ vf %d queue %d ]\n" +
 				"			[ context %d ]\n" +
-				"			[ loc %d]] |\n" +
+				"			[ loc %d|any|first|last ]] |\n" +
 				"		delete %d\n"},
-		{"show-time-stamping", "T", false, "Show time stamping capabilities", true, do_tsinfo, nil, ""},
+		{"show-time-stamping", "T", false, "Show time stamping capabilities", true, do_tsinfo, nl_tsinfo, ""},
 		{"show-rxfh", "x", false, "Show Rx flow hash indirection table and/or RSS hash key", true, do_grxfh, nil,
 			"		[ context %d ]\n"},
-		{"rxfh", "X", false, "Set Rx flow hash indirection table and/or RSS hash key", true, nil, nil,
+		{"rxfh", "X", false, "Set Rx flow hash indirection table and/or RSS hash key", true, do_srxfh, nil,
 			"		[ context %d|new ]\n" +
 				"		[ equal N | weight W0 W1 ... | default ]\n" +
 				"		[ hkey %x:%x:%x:%x:%x:.... ]\n" +
@@ -2608,19 +3944,36 @@ var (
 		{"show-permaddr", "P", false, "Show permanent hardware address", true, do_permaddr, nil, ""},
 		{"get-dump", "w", false, "Get dump flag, data", true, do_getfwdump, nil, "		[ data FILENAME ]\n"},
 		{"set-dump", "W", false, "Set dump flag of the device", true, nil, nil, "		N\n"},
-		{"show-channels", "l", false, "Query Channels", true, do_gchannels, nil, ""},
-		{"set-channels", "L", false, "Set Channels", true, nil, nil, "               [ rx N ]\n" +
+		{"show-channels", "l", false, "Query Channels", true, do_gchannels, nl_gchannels, ""},
+		{"set-channels", "L", false, "Set Channels", true, do_schannels, nil, "               [ rx N ]\n" +
 			"               [ tx N ]\n" +
 			"               [ other N ]\n" +
 			"               [ combined N ]\n"},
-		{"show-priv-flags", "", false, "Query private flags", true, do_gprivflags, nil, ""},
-		{"set-priv-flag", "", false, "Set private flags", true, nil, nil, "		FLAG on|off ...\n"},
-		{"module-info", "m", false, "Query/Decode Module EEPROM information and optical diagnostics if available", true, do_getmodule, nil,
+		{"show-priv-flags", "", false, "Query private flags", true, do_gprivflags, nl_gprivflags, ""},
+		{"set-priv-flag", "", false, "Set private flags", true, do_sprivflags, nl_sprivflags, "		FLAG on|off ...\n"},
+		{"module-info", "m", false, "Query/Decode Module EEPROM information and optical diagnostics if available", true, do_getmodule, nl_getmodule,
 			"		[ raw on|off ]\n" +
 				"		[ hex on|off ]\n" +
 				"		[ offset N ]\n" +
-				"		[ length N ]\n"},
-		{"show-eee", "", false, "Show EEE settings", true, do_geee, nil, ""},
+				"		[ length N ]\n" +
+				"		[ page N ]\n" +
+				"		[ bank N ]\n" +
+				"		[ i2c N ]\n"},
+		{"decode-regs-file", "", false, "Decode a register dump saved with -d raw on", false, do_decode_regs_file, nil,
+			"		FILE driver NAME [ version N ] [ hex on|off ]\n"},
+		{"rss-lookup", "", false, "Predict the RX queue of a flow from the RSS configuration", false, do_rss_lookup, nil,
+			"		DEVNAME | file FILE\n" +
+				"		tcp4|udp4|ah4|esp4|sctp4|tcp6|udp6|ah6|esp6|sctp6\n" +
+				"		[ src-ip IP-ADDRESS ]\n" +
+				"		[ dst-ip IP-ADDRESS ]\n" +
+				"		[ src-port N ]\n" +
+				"		[ dst-port N ]\n" +
+				"		[ context N ]\n" +
+				"		[ fields m|v|t|s|d|f|n... ]\n" +
+				"		[ hfunc toeplitz|xor ]\n"},
+		{"decode-module-file", "", false, "Decode a module EEPROM image saved with -m raw on", false, do_decode_module_file, nil,
+			"		FILE [ type sff8079|sff8472|sff8636|cmis ]\n"},
+		{"show-eee", "", false, "Show EEE settings", true, do_geee, nl_geee, ""},
 		{"set-eee", "", false, "Set EEE settings", true, nil, nil,
 			"		[ eee on|off ]\n" +
 				"		[ advertise %x ]\n" +
@@ -2642,7 +3995,7 @@ var (
 			"		[ rx-copybreak ]\n" +
 				"		[ tx-copybreak ]\n" +
 				"		[ pfc-precention-tout ]\n"},
-		{"reset", "", false, "Reset components", true, nil, nil,
+		{"reset", "", false, "Reset components", true, do_reset, nil,
 			"		[ flags %x ]\n" +
 				"		[ mgmt ]\n" +
 				"		[ mgmt-shared ]\n" +
@@ -2667,9 +4020,10 @@ var (
 		{"show-fec", "", false, "Show FEC setting", true, do_gfec, nil, ""},
 		{"set-fec", "", false, "Set FEC setting", true, do_sfec, nil,
 			"		[ encoding auto|off|rs|baser|llrs [...]]\n"},
-		{"per-queue", "Q", false, "Apply per-queue command.", true, nil, nil,
-			"The supported sub commands include --show-coalesce, --coalesce" +
-				"             [queue_mask %x] SUB_COMMAND\n"},
+		{"per-queue", "Q", false, "Apply per-queue command. " +
+			"The supported sub commands include --show-coalesce, --coalesce",
+			true, do_perqueue, nil,
+			"		[queue_mask %x] SUB_COMMAND\n"},
 		{"cable-test", "", false, "Perform a cable test", true, nil, nil, ""},
 		{"cable-test-tdr", "", false, "Print cable test time domain reflectrometery data", true, nil, nil,
 			"		[ first N ]\n" +
@@ -2677,10 +4031,20 @@ var (
 				"		[ step N ]\n" +
 				"		[ pair N ]\n"},
 		{"show-tunnels", "", false, "Show NIC tunnel offload information", true, nil, nil, ""},
+		{"monitor", "", false, "Show kernel notifications", false, nil, nl_monitor,
+			"		[ --all | --change | --show-features | --show-priv-flags\n" +
+				"		  | --show-ring | --show-channels | --show-coalesce\n" +
+				"		  | --show-pause | --show-eee | --cable-test\n" +
+				"		  | --cable-test-tdr | --show-fec ]\n" +
+				"		[ DEVNAME | * ]\n"},
 		{"version", "", false, "Show version number", false, do_version, nil, ""},
 	}
 )
 
+/* ethtool DEVNAME without any option */
+var default_opt = options{"", "", false, "Display standard information about device",
+	true, do_gset, nl_gset, ""}
+
 // Show_usage
 func show_usage() {
 	fmt.Printf("ethtool version %s\n", "1.0.0")
@@ -2696,6 +4060,95 @@ func show_usage() {
 
 }
 
+func do_perqueue(ctx *cmd_context) int {
+	var sub_command string
+	var args []string
+
+	/* the sub command may come before or after the queue mask */
+	for i := 0; i < ctx.argc; i++ {
+		switch ctx.argp[i] {
+		case "--show-coalesce", "-c", "--coalesce", "-C":
+			if sub_command != "" {
+				return -1
+			}
+			sub_command = ctx.argp[i]
+		default:
+			args = append(args, ctx.argp[i])
+		}
+	}
+	if sub_command == "" {
+		return -1
+	}
+
+	var queue_mask []uint32
+	if len(args) > 0 && args[0] == "queue_mask" {
+		if len(args) < 2 {
+			return -1
+		}
+		var err error
+		queue_mask, err = parse_hex_bitmap(args[1], MAX_NUM_QUEUE)
+		if err != nil || link_mode_is_empty(queue_mask) {
+			return -1
+		}
+		args = args[2:]
+	} else {
+		n_queues := find_max_num_queues(ctx)
+		if n_queues < 0 {
+			fmt.Printf("Cannot get the number of queues\n")
+			return 1
+		}
+		if n_queues == 0 || n_queues > MAX_NUM_QUEUE {
+			fmt.Printf("The number of queues is out of range\n")
+			return 1
+		}
+		queue_mask = make([]uint32, (MAX_NUM_QUEUE+31)/32)
+		for i := 0; i < n_queues; i++ {
+			queue_mask[i/32] |= 1 << (i % 32)
+		}
+	}
+
+	queues, err := get_per_queue_coalesce(ctx, queue_mask)
+	if err != nil {
+		fmt.Printf("Cannot get device per queue parameters: %v\n", err)
+		return 1
+	}
+
+	if sub_command == "--show-coalesce" || sub_command == "-c" {
+		if len(args) != 0 {
+			return -1
+		}
+		dump_per_queue_coalesce(queues, queue_mask)
+		return 0
+	}
+
+	sub := *ctx
+	sub.argc = len(args)
+	sub.argp = args
+	var ecoal Coalesce
+	cl, ret := parse_coalesce_cmdline(&sub, &ecoal)
+	if ret != 0 {
+		return ret
+	}
+
+	changed := 0
+	for i := range queues {
+		ecoal = queues[i]
+		do_generic_set(&cl.info, &changed)
+		queues[i] = ecoal
+	}
+	if changed == 0 {
+		fmt.Printf("no coalesce parameters changed, aborting\n")
+		return 80
+	}
+
+	err = set_per_queue_coalesce(ctx, queue_mask, queues)
+	if err != nil {
+		fmt.Printf("Cannot set device per queue parameters: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
 func find_max_num_queues(ctx *cmd_context) int {
 	var echannels ethtool_channels
 	echannels.cmd = ETHTOOL_GCHANNELS
@@ -2706,64 +4159,102 @@ func find_max_num_queues(ctx *cmd_context) int {
 	return int(math.Max(float64(echannels.rx_count), float64(echannels.tx_count))) + int(echannels.combined_count)
 }
 
-func init() {
+
+// Option describes one action of the ethtool command line.
+type Option struct {
+	Name    string /* long flag name, e.g. "show-ring" */
+	Short   string /* one letter flag, may be empty */
+	Help    string
+	Xhelp   string /* usage of the sub-command arguments */
+	NeedDev bool   /* first argument is the device name */
+}
+
+// Options lists every action Run understands, in usage order.
+func Options() []Option {
+	opts := make([]Option, 0, len(opt_args))
 	for i := 0; i < len(opt_args); i++ {
-		if opt_args[i].short == "" {
-			rootCmd.Flags().Bool(opt_args[i].name, opt_args[i].value, opt_args[i].help)
-		} else {
-			rootCmd.Flags().BoolP(opt_args[i].name, opt_args[i].short, opt_args[i].value, opt_args[i].help)
-		}
-	}
+		opts = append(opts, Option{
+			Name:    opt_args[i].name,
+			Short:   opt_args[i].short,
+			Help:    opt_args[i].help,
+			Xhelp:   opt_args[i].xhelp,
+			NeedDev: opt_args[i].no_dev,
+		})
+	}
+	return opts
 }
 
-// Do_actions will call ioctl to get or set infos
-func Do_actions(cmd *cobra.Command, args []string) {
+var debug_mask uint64
+
+// SetDebug sets the debugging mask used by Run, see the DEBUG_* bits.
+func SetDebug(mask uint64) {
+	debug_mask = mask
+}
+
+// Run executes the action called name with the positional arguments of
+// the command line (device name first, when the action needs one) and
+// returns the process exit code. An empty name shows the standard
+// settings of the device.
+func Run(name string, args []string) int {
 
 	var ctx cmd_context
-	no_dev := true
-	i := 0
-
-	for ; i < len(opt_args); i++ {
-		v := cmd.Flag(opt_args[i].name)
-		if v.Value.String() == "true" {
-			if opt_args[i].no_dev == false {
-				no_dev = false
-			}
-			break
+	var opt *options
 
+	if name == "" {
+		opt = &default_opt
+	}
+	for i := 0; opt == nil && i < len(opt_args); i++ {
+		if opt_args[i].name == name {
+			opt = &opt_args[i]
 		}
 	}
-	if i >= len(opt_args) {
-		return
+	if opt == nil {
+		fmt.Printf("ethtool: unknown option %s\n", name)
+		return 1
 	}
+	no_dev := opt.no_dev
 
 	if no_dev == true {
 		if len(args) == 0 {
 			fmt.Printf("ethtool: bad command line argument(s)\n" +
 				"For more information run ethtool -h\n")
-			return
+			return 1
 		}
 		ctx.devname = args[0]
+		args = args[1:]
 	}
-	if len(args) > 1 {
-		ctx.argc = len(args) - 1
-		ctx.argp = args[1:]
+	if len(args) > 0 {
+		ctx.argc = len(args)
+		ctx.argp = args
 	}
-	if opt_args[i].ioctlfunc == nil {
+	if opt.ioctlfunc == nil && opt.nlfunc == nil {
 		fmt.Printf("Function not supported yet\n")
-		return
+		return 1
 	}
+	ctx.debug = debug_mask
 	err := init_ioctl(&ctx, no_dev)
 	if err != 0 {
-		return
+		return err
 	}
 	defer uninit_ioctl(&ctx)
-	opt_args[i].ioctlfunc(&ctx)
-}
 
-func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	/* prefer netlink, use ioctl if it is missing or cannot do the job */
+	ret := nl_fallback
+	if opt.nlfunc != nil && netlink_init(&ctx) == nil {
+		ret = opt.nlfunc(&ctx)
+		netlink_done(&ctx)
+	}
+	if ret == nl_fallback {
+		if opt.ioctlfunc == nil {
+			fmt.Printf("ethtool: --%s requires the netlink interface\n", name)
+			return 1
+		}
+		ret = opt.ioctlfunc(&ctx)
+	}
+	if ret == -1 {
+		fmt.Printf("ethtool: bad command line argument(s)\n" +
+			"For more information run ethtool -h\n")
+		return 1
 	}
+	return ret
 }
@@ -0,0 +1,390 @@
+package ethtool
+
+const (
+	ETHTOOL_GENL_NAME    = "ethtool"
+	ETHTOOL_GENL_VERSION = 1
+
+	ETHTOOL_MCGRP_MONITOR_NAME = "monitor"
+)
+
+/* message types - userspace to kernel */
+const (
+	ETHTOOL_MSG_USER_NONE = iota
+	ETHTOOL_MSG_STRSET_GET
+	ETHTOOL_MSG_LINKINFO_GET
+	ETHTOOL_MSG_LINKINFO_SET
+	ETHTOOL_MSG_LINKMODES_GET
+	ETHTOOL_MSG_LINKMODES_SET
+	ETHTOOL_MSG_LINKSTATE_GET
+	ETHTOOL_MSG_DEBUG_GET
+	ETHTOOL_MSG_DEBUG_SET
+	ETHTOOL_MSG_WOL_GET
+	ETHTOOL_MSG_WOL_SET
+	ETHTOOL_MSG_FEATURES_GET
+	ETHTOOL_MSG_FEATURES_SET
+	ETHTOOL_MSG_PRIVFLAGS_GET
+	ETHTOOL_MSG_PRIVFLAGS_SET
+	ETHTOOL_MSG_RINGS_GET
+	ETHTOOL_MSG_RINGS_SET
+	ETHTOOL_MSG_CHANNELS_GET
+	ETHTOOL_MSG_CHANNELS_SET
+	ETHTOOL_MSG_COALESCE_GET
+	ETHTOOL_MSG_COALESCE_SET
+	ETHTOOL_MSG_PAUSE_GET
+	ETHTOOL_MSG_PAUSE_SET
+	ETHTOOL_MSG_EEE_GET
+	ETHTOOL_MSG_EEE_SET
+	ETHTOOL_MSG_TSINFO_GET
+	ETHTOOL_MSG_CABLE_TEST_ACT
+	ETHTOOL_MSG_CABLE_TEST_TDR_ACT
+	ETHTOOL_MSG_TUNNEL_INFO_GET
+	ETHTOOL_MSG_FEC_GET
+	ETHTOOL_MSG_FEC_SET
+	ETHTOOL_MSG_MODULE_EEPROM_GET
+)
+
+/* message types - kernel to userspace */
+const (
+	ETHTOOL_MSG_KERNEL_NONE = iota
+	ETHTOOL_MSG_STRSET_GET_REPLY
+	ETHTOOL_MSG_LINKINFO_GET_REPLY
+	ETHTOOL_MSG_LINKINFO_NTF
+	ETHTOOL_MSG_LINKMODES_GET_REPLY
+	ETHTOOL_MSG_LINKMODES_NTF
+	ETHTOOL_MSG_LINKSTATE_GET_REPLY
+	ETHTOOL_MSG_DEBUG_GET_REPLY
+	ETHTOOL_MSG_DEBUG_NTF
+	ETHTOOL_MSG_WOL_GET_REPLY
+	ETHTOOL_MSG_WOL_NTF
+	ETHTOOL_MSG_FEATURES_GET_REPLY
+	ETHTOOL_MSG_FEATURES_SET_REPLY
+	ETHTOOL_MSG_FEATURES_NTF
+	ETHTOOL_MSG_PRIVFLAGS_GET_REPLY
+	ETHTOOL_MSG_PRIVFLAGS_NTF
+	ETHTOOL_MSG_RINGS_GET_REPLY
+	ETHTOOL_MSG_RINGS_NTF
+	ETHTOOL_MSG_CHANNELS_GET_REPLY
+	ETHTOOL_MSG_CHANNELS_NTF
+	ETHTOOL_MSG_COALESCE_GET_REPLY
+	ETHTOOL_MSG_COALESCE_NTF
+	ETHTOOL_MSG_PAUSE_GET_REPLY
+	ETHTOOL_MSG_PAUSE_NTF
+	ETHTOOL_MSG_EEE_GET_REPLY
+	ETHTOOL_MSG_EEE_NTF
+	ETHTOOL_MSG_TSINFO_GET_REPLY
+	ETHTOOL_MSG_CABLE_TEST_NTF
+	ETHTOOL_MSG_CABLE_TEST_TDR_NTF
+	ETHTOOL_MSG_TUNNEL_INFO_GET_REPLY
+	ETHTOOL_MSG_FEC_GET_REPLY
+	ETHTOOL_MSG_FEC_NTF
+	ETHTOOL_MSG_MODULE_EEPROM_GET_REPLY
+)
+
+/* request header */
+const (
+	ETHTOOL_FLAG_COMPACT_BITSETS = 1 << 0 /* use compact bitsets in reply */
+	ETHTOOL_FLAG_OMIT_REPLY      = 1 << 1 /* provide optional reply for SET or ACT requests */
+	ETHTOOL_FLAG_STATS           = 1 << 2 /* request statistics, if supported by the driver */
+)
+
+const (
+	ETHTOOL_A_HEADER_UNSPEC    = iota
+	ETHTOOL_A_HEADER_DEV_INDEX /* u32 */
+	ETHTOOL_A_HEADER_DEV_NAME  /* string */
+	ETHTOOL_A_HEADER_FLAGS     /* u32 - ETHTOOL_FLAG_* */
+	ETHTOOL_A_HEADER_MAX       = ETHTOOL_A_HEADER_FLAGS
+)
+
+/* bit sets */
+const (
+	ETHTOOL_A_BITSET_BIT_UNSPEC = iota
+	ETHTOOL_A_BITSET_BIT_INDEX  /* u32 */
+	ETHTOOL_A_BITSET_BIT_NAME   /* string */
+	ETHTOOL_A_BITSET_BIT_VALUE  /* flag */
+	ETHTOOL_A_BITSET_BIT_MAX    = ETHTOOL_A_BITSET_BIT_VALUE
+)
+
+const (
+	ETHTOOL_A_BITSET_BITS_UNSPEC = iota
+	ETHTOOL_A_BITSET_BITS_BIT    /* nest - _A_BITSET_BIT_* */
+)
+
+const (
+	ETHTOOL_A_BITSET_UNSPEC = iota
+	ETHTOOL_A_BITSET_NOMASK /* flag */
+	ETHTOOL_A_BITSET_SIZE   /* u32 */
+	ETHTOOL_A_BITSET_BITS   /* nest - _A_BITSET_BITS_* */
+	ETHTOOL_A_BITSET_VALUE  /* binary */
+	ETHTOOL_A_BITSET_MASK   /* binary */
+	ETHTOOL_A_BITSET_MAX    = ETHTOOL_A_BITSET_MASK
+)
+
+/* LINKINFO */
+const (
+	ETHTOOL_A_LINKINFO_UNSPEC       = iota
+	ETHTOOL_A_LINKINFO_HEADER       /* nest - _A_HEADER_* */
+	ETHTOOL_A_LINKINFO_PORT         /* u8 */
+	ETHTOOL_A_LINKINFO_PHYADDR      /* u8 */
+	ETHTOOL_A_LINKINFO_TP_MDIX      /* u8 */
+	ETHTOOL_A_LINKINFO_TP_MDIX_CTRL /* u8 */
+	ETHTOOL_A_LINKINFO_TRANSCEIVER  /* u8 */
+	ETHTOOL_A_LINKINFO_MAX          = ETHTOOL_A_LINKINFO_TRANSCEIVER
+)
+
+/* LINKMODES */
+const (
+	ETHTOOL_A_LINKMODES_UNSPEC             = iota
+	ETHTOOL_A_LINKMODES_HEADER             /* nest - _A_HEADER_* */
+	ETHTOOL_A_LINKMODES_AUTONEG            /* u8 */
+	ETHTOOL_A_LINKMODES_OURS               /* bitset */
+	ETHTOOL_A_LINKMODES_PEER               /* bitset */
+	ETHTOOL_A_LINKMODES_SPEED              /* u32 */
+	ETHTOOL_A_LINKMODES_DUPLEX             /* u8 */
+	ETHTOOL_A_LINKMODES_MASTER_SLAVE_CFG   /* u8 */
+	ETHTOOL_A_LINKMODES_MASTER_SLAVE_STATE /* u8 */
+	ETHTOOL_A_LINKMODES_MAX                = ETHTOOL_A_LINKMODES_MASTER_SLAVE_STATE
+)
+
+/* LINKSTATE */
+const (
+	ETHTOOL_A_LINKSTATE_UNSPEC       = iota
+	ETHTOOL_A_LINKSTATE_HEADER       /* nest - _A_HEADER_* */
+	ETHTOOL_A_LINKSTATE_LINK         /* u8 */
+	ETHTOOL_A_LINKSTATE_SQI          /* u32 */
+	ETHTOOL_A_LINKSTATE_SQI_MAX      /* u32 */
+	ETHTOOL_A_LINKSTATE_EXT_STATE    /* u8 */
+	ETHTOOL_A_LINKSTATE_EXT_SUBSTATE /* u8 */
+	ETHTOOL_A_LINKSTATE_MAX          = ETHTOOL_A_LINKSTATE_EXT_SUBSTATE
+)
+
+/* DEBUG */
+const (
+	ETHTOOL_A_DEBUG_UNSPEC  = iota
+	ETHTOOL_A_DEBUG_HEADER  /* nest - _A_HEADER_* */
+	ETHTOOL_A_DEBUG_MSGMASK /* bitset */
+	ETHTOOL_A_DEBUG_MAX     = ETHTOOL_A_DEBUG_MSGMASK
+)
+
+/* WOL */
+const (
+	ETHTOOL_A_WOL_UNSPEC = iota
+	ETHTOOL_A_WOL_HEADER /* nest - _A_HEADER_* */
+	ETHTOOL_A_WOL_MODES  /* bitset */
+	ETHTOOL_A_WOL_SOPASS /* binary */
+	ETHTOOL_A_WOL_MAX    = ETHTOOL_A_WOL_SOPASS
+)
+
+/* FEATURES */
+const (
+	ETHTOOL_A_FEATURES_UNSPEC   = iota
+	ETHTOOL_A_FEATURES_HEADER   /* nest - _A_HEADER_* */
+	ETHTOOL_A_FEATURES_HW       /* bitset */
+	ETHTOOL_A_FEATURES_WANTED   /* bitset */
+	ETHTOOL_A_FEATURES_ACTIVE   /* bitset */
+	ETHTOOL_A_FEATURES_NOCHANGE /* bitset */
+	ETHTOOL_A_FEATURES_MAX      = ETHTOOL_A_FEATURES_NOCHANGE
+)
+
+/* PRIVFLAGS */
+const (
+	ETHTOOL_A_PRIVFLAGS_UNSPEC = iota
+	ETHTOOL_A_PRIVFLAGS_HEADER /* nest - _A_HEADER_* */
+	ETHTOOL_A_PRIVFLAGS_FLAGS  /* bitset */
+	ETHTOOL_A_PRIVFLAGS_MAX    = ETHTOOL_A_PRIVFLAGS_FLAGS
+)
+
+/* RINGS */
+const (
+	ETHTOOL_A_RINGS_UNSPEC       = iota
+	ETHTOOL_A_RINGS_HEADER       /* nest - _A_HEADER_* */
+	ETHTOOL_A_RINGS_RX_MAX       /* u32 */
+	ETHTOOL_A_RINGS_RX_MINI_MAX  /* u32 */
+	ETHTOOL_A_RINGS_RX_JUMBO_MAX /* u32 */
+	ETHTOOL_A_RINGS_TX_MAX       /* u32 */
+	ETHTOOL_A_RINGS_RX           /* u32 */
+	ETHTOOL_A_RINGS_RX_MINI      /* u32 */
+	ETHTOOL_A_RINGS_RX_JUMBO     /* u32 */
+	ETHTOOL_A_RINGS_TX           /* u32 */
+	ETHTOOL_A_RINGS_MAX          = ETHTOOL_A_RINGS_TX
+)
+
+/* CHANNELS */
+const (
+	ETHTOOL_A_CHANNELS_UNSPEC         = iota
+	ETHTOOL_A_CHANNELS_HEADER         /* nest - _A_HEADER_* */
+	ETHTOOL_A_CHANNELS_RX_MAX         /* u32 */
+	ETHTOOL_A_CHANNELS_TX_MAX         /* u32 */
+	ETHTOOL_A_CHANNELS_OTHER_MAX      /* u32 */
+	ETHTOOL_A_CHANNELS_COMBINED_MAX   /* u32 */
+	ETHTOOL_A_CHANNELS_RX_COUNT       /* u32 */
+	ETHTOOL_A_CHANNELS_TX_COUNT       /* u32 */
+	ETHTOOL_A_CHANNELS_OTHER_COUNT    /* u32 */
+	ETHTOOL_A_CHANNELS_COMBINED_COUNT /* u32 */
+	ETHTOOL_A_CHANNELS_MAX            = ETHTOOL_A_CHANNELS_COMBINED_COUNT
+)
+
+/* COALESCE */
+const (
+	ETHTOOL_A_COALESCE_UNSPEC               = iota
+	ETHTOOL_A_COALESCE_HEADER               /* nest - _A_HEADER_* */
+	ETHTOOL_A_COALESCE_RX_USECS             /* u32 */
+	ETHTOOL_A_COALESCE_RX_MAX_FRAMES        /* u32 */
+	ETHTOOL_A_COALESCE_RX_USECS_IRQ         /* u32 */
+	ETHTOOL_A_COALESCE_RX_MAX_FRAMES_IRQ    /* u32 */
+	ETHTOOL_A_COALESCE_TX_USECS             /* u32 */
+	ETHTOOL_A_COALESCE_TX_MAX_FRAMES        /* u32 */
+	ETHTOOL_A_COALESCE_TX_USECS_IRQ         /* u32 */
+	ETHTOOL_A_COALESCE_TX_MAX_FRAMES_IRQ    /* u32 */
+	ETHTOOL_A_COALESCE_STATS_BLOCK_USECS    /* u32 */
+	ETHTOOL_A_COALESCE_USE_ADAPTIVE_RX      /* u8 */
+	ETHTOOL_A_COALESCE_USE_ADAPTIVE_TX      /* u8 */
+	ETHTOOL_A_COALESCE_PKT_RATE_LOW         /* u32 */
+	ETHTOOL_A_COALESCE_RX_USECS_LOW         /* u32 */
+	ETHTOOL_A_COALESCE_RX_MAX_FRAMES_LOW    /* u32 */
+	ETHTOOL_A_COALESCE_TX_USECS_LOW         /* u32 */
+	ETHTOOL_A_COALESCE_TX_MAX_FRAMES_LOW    /* u32 */
+	ETHTOOL_A_COALESCE_PKT_RATE_HIGH        /* u32 */
+	ETHTOOL_A_COALESCE_RX_USECS_HIGH        /* u32 */
+	ETHTOOL_A_COALESCE_RX_MAX_FRAMES_HIGH   /* u32 */
+	ETHTOOL_A_COALESCE_TX_USECS_HIGH        /* u32 */
+	ETHTOOL_A_COALESCE_TX_MAX_FRAMES_HIGH   /* u32 */
+	ETHTOOL_A_COALESCE_RATE_SAMPLE_INTERVAL /* u32 */
+	ETHTOOL_A_COALESCE_MAX                  = ETHTOOL_A_COALESCE_RATE_SAMPLE_INTERVAL
+)
+
+/* PAUSE */
+const (
+	ETHTOOL_A_PAUSE_UNSPEC  = iota
+	ETHTOOL_A_PAUSE_HEADER  /* nest - _A_HEADER_* */
+	ETHTOOL_A_PAUSE_AUTONEG /* u8 */
+	ETHTOOL_A_PAUSE_RX      /* u8 */
+	ETHTOOL_A_PAUSE_TX      /* u8 */
+	ETHTOOL_A_PAUSE_STATS   /* nest - _PAUSE_STAT_* */
+	ETHTOOL_A_PAUSE_MAX     = ETHTOOL_A_PAUSE_STATS
+)
+
+/* EEE */
+const (
+	ETHTOOL_A_EEE_UNSPEC         = iota
+	ETHTOOL_A_EEE_HEADER         /* nest - _A_HEADER_* */
+	ETHTOOL_A_EEE_MODES_OURS     /* bitset */
+	ETHTOOL_A_EEE_MODES_PEER     /* bitset */
+	ETHTOOL_A_EEE_ACTIVE         /* u8 */
+	ETHTOOL_A_EEE_ENABLED        /* u8 */
+	ETHTOOL_A_EEE_TX_LPI_ENABLED /* u8 */
+	ETHTOOL_A_EEE_TX_LPI_TIMER   /* u32 */
+	ETHTOOL_A_EEE_MAX            = ETHTOOL_A_EEE_TX_LPI_TIMER
+)
+
+/* TSINFO */
+const (
+	ETHTOOL_A_TSINFO_UNSPEC       = iota
+	ETHTOOL_A_TSINFO_HEADER       /* nest - _A_HEADER_* */
+	ETHTOOL_A_TSINFO_TIMESTAMPING /* bitset */
+	ETHTOOL_A_TSINFO_TX_TYPES     /* bitset */
+	ETHTOOL_A_TSINFO_RX_FILTERS   /* bitset */
+	ETHTOOL_A_TSINFO_PHC_INDEX    /* u32 */
+	ETHTOOL_A_TSINFO_MAX          = ETHTOOL_A_TSINFO_PHC_INDEX
+)
+
+/* CABLE TEST */
+const (
+	ETHTOOL_A_CABLE_RESULT_CODE_UNSPEC = iota
+	ETHTOOL_A_CABLE_RESULT_CODE_OK
+	ETHTOOL_A_CABLE_RESULT_CODE_OPEN
+	ETHTOOL_A_CABLE_RESULT_CODE_SAME_SHORT
+	ETHTOOL_A_CABLE_RESULT_CODE_CROSS_SHORT
+)
+
+const (
+	ETHTOOL_A_CABLE_PAIR_A = iota
+	ETHTOOL_A_CABLE_PAIR_B
+	ETHTOOL_A_CABLE_PAIR_C
+	ETHTOOL_A_CABLE_PAIR_D
+)
+
+const (
+	ETHTOOL_A_CABLE_RESULT_UNSPEC = iota
+	ETHTOOL_A_CABLE_RESULT_PAIR   /* u8 ETHTOOL_A_CABLE_PAIR_ */
+	ETHTOOL_A_CABLE_RESULT_CODE   /* u8 ETHTOOL_A_CABLE_RESULT_CODE_ */
+	ETHTOOL_A_CABLE_RESULT_MAX    = ETHTOOL_A_CABLE_RESULT_CODE
+)
+
+const (
+	ETHTOOL_A_CABLE_FAULT_LENGTH_UNSPEC = iota
+	ETHTOOL_A_CABLE_FAULT_LENGTH_PAIR   /* u8 ETHTOOL_A_CABLE_PAIR_ */
+	ETHTOOL_A_CABLE_FAULT_LENGTH_CM     /* u32 */
+	ETHTOOL_A_CABLE_FAULT_LENGTH_MAX    = ETHTOOL_A_CABLE_FAULT_LENGTH_CM
+)
+
+const (
+	ETHTOOL_A_CABLE_TEST_NTF_STATUS_UNSPEC = iota
+	ETHTOOL_A_CABLE_TEST_NTF_STATUS_STARTED
+	ETHTOOL_A_CABLE_TEST_NTF_STATUS_COMPLETED
+)
+
+const (
+	ETHTOOL_A_CABLE_NEST_UNSPEC       = iota
+	ETHTOOL_A_CABLE_NEST_RESULT       /* nest - ETHTOOL_A_CABLE_RESULT_ */
+	ETHTOOL_A_CABLE_NEST_FAULT_LENGTH /* nest - ETHTOOL_A_CABLE_FAULT_LENGTH_ */
+	ETHTOOL_A_CABLE_NEST_MAX          = ETHTOOL_A_CABLE_NEST_FAULT_LENGTH
+)
+
+const (
+	ETHTOOL_A_CABLE_TEST_NTF_UNSPEC = iota
+	ETHTOOL_A_CABLE_TEST_NTF_HEADER /* nest - ETHTOOL_A_HEADER_* */
+	ETHTOOL_A_CABLE_TEST_NTF_STATUS /* u8 - _STARTED/_COMPLETE */
+	ETHTOOL_A_CABLE_TEST_NTF_NEST   /* nest - of results: */
+	ETHTOOL_A_CABLE_TEST_NTF_MAX    = ETHTOOL_A_CABLE_TEST_NTF_NEST
+)
+
+/* CABLE TEST TDR */
+const (
+	ETHTOOL_A_CABLE_AMPLITUDE_UNSPEC = iota
+	ETHTOOL_A_CABLE_AMPLITUDE_PAIR   /* u8 ETHTOOL_A_CABLE_PAIR_ */
+	ETHTOOL_A_CABLE_AMPLITUDE_mV     /* s16 */
+	ETHTOOL_A_CABLE_AMPLITUDE_MAX    = ETHTOOL_A_CABLE_AMPLITUDE_mV
+)
+
+const (
+	ETHTOOL_A_CABLE_PULSE_UNSPEC = iota
+	ETHTOOL_A_CABLE_PULSE_mV     /* s16 */
+	ETHTOOL_A_CABLE_PULSE_MAX    = ETHTOOL_A_CABLE_PULSE_mV
+)
+
+const (
+	ETHTOOL_A_CABLE_STEP_UNSPEC         = iota
+	ETHTOOL_A_CABLE_STEP_FIRST_DISTANCE /* u32 */
+	ETHTOOL_A_CABLE_STEP_LAST_DISTANCE  /* u32 */
+	ETHTOOL_A_CABLE_STEP_STEP_DISTANCE  /* u32 */
+	ETHTOOL_A_CABLE_STEP_MAX            = ETHTOOL_A_CABLE_STEP_STEP_DISTANCE
+)
+
+const (
+	ETHTOOL_A_CABLE_TDR_NEST_UNSPEC    = iota
+	ETHTOOL_A_CABLE_TDR_NEST_STEP      /* nest - ETHTOOL_A_CABLE_STEP_ */
+	ETHTOOL_A_CABLE_TDR_NEST_AMPLITUDE /* nest - ETHTOOL_A_CABLE_AMPLITUDE_ */
+	ETHTOOL_A_CABLE_TDR_NEST_PULSE     /* nest - ETHTOOL_A_CABLE_PULSE_ */
+	ETHTOOL_A_CABLE_TDR_NEST_MAX       = ETHTOOL_A_CABLE_TDR_NEST_PULSE
+)
+
+const (
+	ETHTOOL_A_CABLE_TEST_TDR_NTF_UNSPEC = iota
+	ETHTOOL_A_CABLE_TEST_TDR_NTF_HEADER /* nest - ETHTOOL_A_HEADER_* */
+	ETHTOOL_A_CABLE_TEST_TDR_NTF_STATUS /* u8 - _STARTED/_COMPLETE */
+	ETHTOOL_A_CABLE_TEST_TDR_NTF_NEST   /* nest - of results: */
+	ETHTOOL_A_CABLE_TEST_TDR_NTF_MAX    = ETHTOOL_A_CABLE_TEST_TDR_NTF_NEST
+)
+
+/* MODULE EEPROM */
+const (
+	ETHTOOL_A_MODULE_EEPROM_UNSPEC      = iota
+	ETHTOOL_A_MODULE_EEPROM_HEADER      /* nest - _A_HEADER_* */
+	ETHTOOL_A_MODULE_EEPROM_OFFSET      /* u32 */
+	ETHTOOL_A_MODULE_EEPROM_LENGTH      /* u32 */
+	ETHTOOL_A_MODULE_EEPROM_PAGE        /* u8 */
+	ETHTOOL_A_MODULE_EEPROM_BANK        /* u8 */
+	ETHTOOL_A_MODULE_EEPROM_I2C_ADDRESS /* u8 */
+	ETHTOOL_A_MODULE_EEPROM_DATA        /* binary */
+	ETHTOOL_A_MODULE_EEPROM_MAX         = ETHTOOL_A_MODULE_EEPROM_DATA
+)
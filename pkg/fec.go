@@ -0,0 +1,192 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * Register dump of the fec driver: the register space with each register
+ * at its own offset, registers the driver does not dump are left zero.
+ */
+
+const (
+	FEC_ECR_RESET   = 0x00000001
+	FEC_ECR_ETHEREN = 0x00000002
+	FEC_ECR_MAGICEN = 0x00000004
+	FEC_ECR_SLEEP   = 0x00000008
+	FEC_ECR_EN1588  = 0x00000010
+	FEC_ECR_SPEED   = 0x00000020 /* 1000 Mbps */
+	FEC_ECR_DBSWP   = 0x00000100
+
+	FEC_RCR_LOOP      = 0x00000001
+	FEC_RCR_DRT       = 0x00000002
+	FEC_RCR_MII_MODE  = 0x00000004
+	FEC_RCR_PROM      = 0x00000008
+	FEC_RCR_BC_REJ    = 0x00000010
+	FEC_RCR_FCE       = 0x00000020
+	FEC_RCR_RGMII_EN  = 0x00000040
+	FEC_RCR_RMII_MODE = 0x00000100
+	FEC_RCR_RMII_10T  = 0x00000200
+	FEC_RCR_PADEN     = 0x00001000
+	FEC_RCR_PAUFWD    = 0x00002000
+	FEC_RCR_CRCFWD    = 0x00004000
+	FEC_RCR_CFEN      = 0x00008000
+	FEC_RCR_MAX_FL    = 0x3fff0000
+	FEC_RCR_NLC       = 0x40000000
+	FEC_RCR_GRS       = 0x80000000
+
+	FEC_TCR_GTS       = 0x00000001
+	FEC_TCR_FDEN      = 0x00000004
+	FEC_TCR_TFC_PAUSE = 0x00000008
+	FEC_TCR_RFC_PAUSE = 0x00000010
+	FEC_TCR_ADDINS    = 0x00000100
+	FEC_TCR_CRCFWD    = 0x00000200
+
+	FEC_MSCR_MII_SPEED = 0x0000007e
+	FEC_MSCR_DIS_PRE   = 0x00000080
+	FEC_MSCR_HOLDTIME  = 0x00000700
+
+	FEC_XDAR_ACTIVE = 0x01000000
+)
+
+var fec_eir_bits = []struct {
+	mask uint32
+	name string
+}{
+	{0x80000000, "HBERR"},
+	{0x40000000, "BABR"},
+	{0x20000000, "BABT"},
+	{0x10000000, "GRA"},
+	{0x08000000, "TXF"},
+	{0x04000000, "TXB"},
+	{0x02000000, "RXF"},
+	{0x01000000, "RXB"},
+	{0x00800000, "MII"},
+	{0x00400000, "EBERR"},
+	{0x00200000, "LC"},
+	{0x00100000, "RL"},
+	{0x00080000, "UN"},
+	{0x00040000, "PLR"},
+	{0x00020000, "WAKEUP"},
+	{0x00010000, "TS_AVAIL"},
+	{0x00008000, "TS_TIMER"},
+}
+
+var fec_regs = []struct {
+	offset uint32
+	name   string
+}{
+	{0x004, "EIR (Interrupt event)"},
+	{0x008, "EIMR (Interrupt mask)"},
+	{0x010, "RDAR (Receive descriptor active)"},
+	{0x014, "TDAR (Transmit descriptor active)"},
+	{0x024, "ECR (Ethernet control)"},
+	{0x040, "MMFR (MII management frame)"},
+	{0x044, "MSCR (MII speed control)"},
+	{0x064, "MIBC (MIB control)"},
+	{0x084, "RCR (Receive control)"},
+	{0x0c4, "TCR (Transmit control)"},
+	{0x0e4, "PALR (Physical address lower)"},
+	{0x0e8, "PAUR (Physical address upper)"},
+	{0x0ec, "OPD (Opcode/pause duration)"},
+	{0x118, "IAUR (Individual hash upper)"},
+	{0x11c, "IALR (Individual hash lower)"},
+	{0x120, "GAUR (Group hash upper)"},
+	{0x124, "GALR (Group hash lower)"},
+	{0x144, "TFWR (Transmit FIFO watermark)"},
+	{0x14c, "FRBR (FIFO receive bound)"},
+	{0x150, "FRSR (FIFO receive start)"},
+	{0x180, "RDSR (Receive descriptor ring start)"},
+	{0x184, "TDSR (Transmit descriptor ring start)"},
+	{0x188, "MRBR (Maximum receive buffer size)"},
+	{0x190, "RSFL (Receive FIFO section full)"},
+	{0x194, "RSEM (Receive FIFO section empty)"},
+	{0x198, "RAEM (Receive FIFO almost empty)"},
+	{0x19c, "RAFL (Receive FIFO almost full)"},
+	{0x1a0, "TSEM (Transmit FIFO section empty)"},
+	{0x1a4, "TAEM (Transmit FIFO almost empty)"},
+	{0x1a8, "TAFL (Transmit FIFO almost full)"},
+	{0x1ac, "TIPG (Transmit inter-packet gap)"},
+	{0x1b0, "FTRL (Frame truncation length)"},
+	{0x1c0, "TACC (Transmit accelerator control)"},
+	{0x1c4, "RACC (Receive accelerator control)"},
+}
+
+func fec_dump_field(name string, val string) {
+	fmt.Printf("    %-26s %s\n", name+":", val)
+}
+
+func fec_dump_events(val uint32) {
+	s := ""
+	for _, b := range fec_eir_bits {
+		if val&b.mask != 0 {
+			s += " " + b.name
+		}
+	}
+	if s == "" {
+		s = " none"
+	}
+	fec_dump_field("Events", s[1:])
+}
+
+func fec_dump_fields(offset uint32, val uint32) {
+	switch offset {
+	case 0x004, 0x008: /* EIR, EIMR */
+		fec_dump_events(val)
+	case 0x010, 0x014: /* RDAR, TDAR */
+		fec_dump_field("Descriptor active", yesno(val&FEC_XDAR_ACTIVE != 0))
+	case 0x024: /* ECR */
+		fec_dump_field("Reset", yesno(val&FEC_ECR_RESET != 0))
+		fec_dump_field("Ethernet", endis(val&FEC_ECR_ETHEREN != 0))
+		fec_dump_field("Magic packet detection", endis(val&FEC_ECR_MAGICEN != 0))
+		fec_dump_field("Sleep mode", endis(val&FEC_ECR_SLEEP != 0))
+		fec_dump_field("IEEE 1588", endis(val&FEC_ECR_EN1588 != 0))
+		fec_dump_field("Speed", regs_bit(val&FEC_ECR_SPEED != 0, "1000 Mbps", "10/100 Mbps"))
+		fec_dump_field("Descriptor byte swapping", endis(val&FEC_ECR_DBSWP != 0))
+	case 0x044: /* MSCR */
+		fec_dump_field("MII speed divider", fmt.Sprintf("%d", (val&FEC_MSCR_MII_SPEED)>>1))
+		fec_dump_field("Preamble", regs_bit(val&FEC_MSCR_DIS_PRE != 0, "disabled", "enabled"))
+		fec_dump_field("Hold time", fmt.Sprintf("%d cycles", (val&FEC_MSCR_HOLDTIME)>>8+1))
+	case 0x084: /* RCR */
+		mode := "MII"
+		if val&FEC_RCR_RGMII_EN != 0 {
+			mode = "RGMII"
+		} else if val&FEC_RCR_RMII_MODE != 0 {
+			mode = "RMII"
+			if val&FEC_RCR_RMII_10T != 0 {
+				mode = "RMII 10 Mbps"
+			}
+		}
+		fec_dump_field("Internal loopback", endis(val&FEC_RCR_LOOP != 0))
+		fec_dump_field("Disable receive on transmit", yesno(val&FEC_RCR_DRT != 0))
+		fec_dump_field("Interface mode", regs_bit(val&FEC_RCR_MII_MODE != 0, mode, "7-wire"))
+		fec_dump_field("Promiscuous", endis(val&FEC_RCR_PROM != 0))
+		fec_dump_field("Broadcast reject", yesno(val&FEC_RCR_BC_REJ != 0))
+		fec_dump_field("Flow control", endis(val&FEC_RCR_FCE != 0))
+		fec_dump_field("Remove padding", yesno(val&FEC_RCR_PADEN != 0))
+		fec_dump_field("Forward pause frames", yesno(val&FEC_RCR_PAUFWD != 0))
+		fec_dump_field("Forward received CRC", regs_bit(val&FEC_RCR_CRCFWD != 0, "No", "Yes"))
+		fec_dump_field("MAC control frames", regs_bit(val&FEC_RCR_CFEN != 0, "discarded", "accepted"))
+		fec_dump_field("Maximum frame length", fmt.Sprintf("%d", (val&FEC_RCR_MAX_FL)>>16))
+		fec_dump_field("Payload length check", endis(val&FEC_RCR_NLC != 0))
+		fec_dump_field("Graceful receive stopped", yesno(val&FEC_RCR_GRS != 0))
+	case 0x0c4: /* TCR */
+		fec_dump_field("Graceful transmit stop", yesno(val&FEC_TCR_GTS != 0))
+		fec_dump_field("Duplex", regs_bit(val&FEC_TCR_FDEN != 0, "full", "half"))
+		fec_dump_field("Transmit pause frame", yesno(val&FEC_TCR_TFC_PAUSE != 0))
+		fec_dump_field("Paused by pause frame", yesno(val&FEC_TCR_RFC_PAUSE != 0))
+		fec_dump_field("MAC address insertion", endis(val&FEC_TCR_ADDINS != 0))
+		fec_dump_field("Forward frame CRC", regs_bit(val&FEC_TCR_CRCFWD != 0, "Yes", "No"))
+	}
+}
+
+func fec_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	if regs.len < 0x1c8 {
+		return -1
+	}
+
+	for _, r := range fec_regs {
+		val := regs_u32(regs, r.offset/4)
+		fmt.Printf("0x%03x: %-40s 0x%08x\n", r.offset, r.name, val)
+		fec_dump_fields(r.offset, val)
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * Register dump of the i40e driver: the registers of i40e_reg_list, in
+ * order, each array register dumped element after element.
+ * regs->version is 1.
+ */
+
+type i40e_reg struct {
+	offset   uint32
+	elements uint32
+	stride   uint32
+	name     string
+}
+
+var i40e_regs = []i40e_reg{
+	{0x00104000, 1, 4, "QTX_CTL"},
+	{0x00038000, 3, 0x80, "PFINT_ITR0"},
+	{0x00030000, 1, 4, "PFINT_ITRN(0)"},
+	{0x00030800, 1, 4, "PFINT_ITRN(1)"},
+	{0x00031000, 1, 4, "PFINT_ITRN(2)"},
+	{0x00038400, 1, 0, "PFINT_STAT_CTL0"},
+	{0x00038500, 1, 0, "PFINT_LNKLST0"},
+	{0x00035000, 1, 4, "PFINT_LNKLSTN"},
+	{0x0003C000, 1, 4, "QINT_TQCTL"},
+	{0x0003A000, 1, 4, "QINT_RQCTL"},
+	{0x00038800, 1, 0, "PFINT_ICR0_ENA"},
+}
+
+const (
+	I40E_QTX_CTL_PFVF_Q_MASK = 0x00000003
+	I40E_QINT_QCTL_MSIX_MASK = 0x000000FF /* MSI-X vector index */
+	I40E_QINT_QCTL_ITR_MASK  = 0x00001800
+	I40E_QINT_QCTL_CAUSE_ENA = 0x40000000
+	I40E_PFINT_ITR_INTERVAL  = 0x00000FFF
+	I40E_LNKLST_FIRSTQ_INDX  = 0x000007FF
+	I40E_LNKLST_FIRSTQ_TYPE  = 0x00001800
+)
+
+func i40e_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	n := uint32(0)
+	for _, r := range i40e_regs {
+		n += r.elements
+	}
+	if regs.version != 1 || regs.len < n*4 {
+		return -1
+	}
+
+	n = 0
+	for _, r := range i40e_regs {
+		for j := uint32(0); j < r.elements; j++ {
+			val := regs_u32(regs, n)
+			n++
+			name := r.name
+			if r.elements > 1 {
+				name = fmt.Sprintf("%s[%d]", r.name, j)
+			}
+			fmt.Printf("0x%08X: %-24s 0x%08X\n",
+				r.offset+j*r.stride, name, val)
+
+			switch r.name {
+			case "QTX_CTL":
+				pfvf := []string{"VF", "VM", "PF", "reserved"}
+				fmt.Printf("      %-28s %s\n", "Queue type:",
+					pfvf[val&I40E_QTX_CTL_PFVF_Q_MASK])
+			case "PFINT_ITR0", "PFINT_ITRN(0)", "PFINT_ITRN(1)",
+				"PFINT_ITRN(2)":
+				fmt.Printf("      %-28s %d usecs\n", "Interval:",
+					(val&I40E_PFINT_ITR_INTERVAL)*2)
+			case "PFINT_LNKLST0", "PFINT_LNKLSTN":
+				qtype := []string{"Rx", "Tx", "reserved", "reserved"}
+				fmt.Printf("      %-28s %d (%s)\n", "First queue:",
+					val&I40E_LNKLST_FIRSTQ_INDX,
+					qtype[(val&I40E_LNKLST_FIRSTQ_TYPE)>>11])
+			case "QINT_TQCTL", "QINT_RQCTL":
+				fmt.Printf("      %-28s %d\n"+
+					"      %-28s %d\n"+
+					"      %-28s %s\n",
+					"MSI-X vector:", val&I40E_QINT_QCTL_MSIX_MASK,
+					"ITR index:", (val&I40E_QINT_QCTL_ITR_MASK)>>11,
+					"Interrupt cause:",
+					endis(val&I40E_QINT_QCTL_CAUSE_ENA != 0))
+			}
+		}
+	}
+	return 0
+}
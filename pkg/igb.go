@@ -0,0 +1,214 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * Register dump of the igb driver: general, NVM, interrupt, flow control,
+ * receive, transmit, wake up and PCS registers, then statistics and the
+ * per queue registers. regs->version is 1 << 24 | revision id << 16 |
+ * device id.
+ */
+
+const (
+	IGB_DUMP_LEN = 54 * 4
+
+	/* Device Control */
+	IGB_CTRL_FD       = 0x00000001
+	IGB_CTRL_GIO_MDIS = 0x00000004 /* GIO master disable */
+	IGB_CTRL_SLU      = 0x00000040
+	IGB_CTRL_ILOS     = 0x00000080
+	IGB_CTRL_SPD_SEL  = 0x00000300
+	IGB_CTRL_FRCSPD   = 0x00000800
+	IGB_CTRL_FRCDPX   = 0x00001000
+	IGB_CTRL_RST      = 0x04000000
+	IGB_CTRL_RFCE     = 0x08000000
+	IGB_CTRL_TFCE     = 0x10000000
+	IGB_CTRL_VME      = 0x40000000
+	IGB_CTRL_PHY_RST  = 0x80000000
+
+	/* Device Status */
+	IGB_STATUS_FD         = 0x00000001
+	IGB_STATUS_LU         = 0x00000002
+	IGB_STATUS_LAN_ID     = 0x0000000C
+	IGB_STATUS_TXOFF      = 0x00000010
+	IGB_STATUS_SPEED_MASK = 0x000000C0
+	IGB_STATUS_GIO_M_ENA  = 0x00080000
+	IGB_STATUS_DEV_RST    = 0x00200000 /* device reset set */
+
+	/* Extended Device Control */
+	IGB_CTRL_EXT_PFRSTD    = 0x00004000 /* PF reset done */
+	IGB_CTRL_EXT_LINK_MODE = 0x00C00000
+	IGB_CTRL_EXT_DRV_LOAD  = 0x10000000 /* Driver loaded */
+
+	/* Receive Control */
+	IGB_RCTL_EN    = 0x00000002
+	IGB_RCTL_SBP   = 0x00000004
+	IGB_RCTL_UPE   = 0x00000008
+	IGB_RCTL_MPE   = 0x00000010
+	IGB_RCTL_LPE   = 0x00000020
+	IGB_RCTL_LBM   = 0x000000C0
+	IGB_RCTL_BAM   = 0x00008000
+	IGB_RCTL_SZ    = 0x00030000
+	IGB_RCTL_VFE   = 0x00040000
+	IGB_RCTL_CFIEN = 0x00080000
+	IGB_RCTL_DPF   = 0x00400000
+	IGB_RCTL_PMCF  = 0x00800000
+	IGB_RCTL_SECRC = 0x04000000
+
+	/* Transmit Control */
+	IGB_TCTL_EN     = 0x00000002
+	IGB_TCTL_PSP    = 0x00000008
+	IGB_TCTL_CT     = 0x00000ff0
+	IGB_TCTL_COLD   = 0x003ff000
+	IGB_TCTL_SWXOFF = 0x00400000
+	IGB_TCTL_RTLC   = 0x01000000
+
+	/* PCS Link Status */
+	IGB_PCS_LSTS_LINK_OK = 0x00000001
+	IGB_PCS_LSTS_SPEED   = 0x00000006
+	IGB_PCS_LSTS_DUPLEX  = 0x00000008
+	IGB_PCS_LSTS_SYNC_OK = 0x00000010
+	IGB_PCS_LSTS_AN_DONE = 0x00010000
+)
+
+type igb_reg struct {
+	offset uint32
+	name   string
+}
+
+/* The registers in the order igb_get_regs() dumps them */
+var igb_regs = []igb_reg{
+	{0x00000, "CTRL (Device control register)"},
+	{0x00008, "STATUS (Device status register)"},
+	{0x00018, "CTRL_EXT (Extended device control)"},
+	{0x00020, "MDIC (MDI control register)"},
+	{0x00024, "SCTL (SerDes ANA)"},
+	{0x00034, "CONNSW (Copper/Fiber switch control)"},
+	{0x00038, "VET (VLAN Ether type)"},
+	{0x00E00, "LEDCTL (LED control)"},
+	{0x01000, "PBA (Packet buffer allocation)"},
+	{0x01008, "PBS (Packet buffer size)"},
+	{0x01048, "FRTIMER (Free running timer)"},
+	{0x0104C, "TCPTIMER (TCP timer)"},
+	{0x00010, "EECD (EEPROM/Flash control)"},
+	{0x01580, "EICR (Extended interrupt cause)"},
+	{0x01520, "EICS (Extended interrupt cause set)"},
+	{0x01524, "EIMS (Extended interrupt mask set)"},
+	{0x01528, "EIMC (Extended interrupt mask clear)"},
+	{0x0152C, "EIAC (Extended interrupt auto clear)"},
+	{0x01530, "EIAM (Extended interrupt auto mask)"},
+	{0x000C0, "ICR (Interrupt cause read)"},
+	{0x000C8, "ICS (Interrupt cause set)"},
+	{0x000D0, "IMS (Interrupt mask set)"},
+	{0x000D8, "IMC (Interrupt mask clear)"},
+	{0x04100, "IAC (Interrupt assertion count)"},
+	{0x000E0, "IAM (Interrupt acknowledge auto mask)"},
+	{0x05AC0, "IMIRVP (Immed. interrupt rx VLAN priority)"},
+	{0x00028, "FCAL (Flow control address low)"},
+	{0x0002C, "FCAH (Flow control address high)"},
+	{0x00170, "FCTTV (Flow control tx timer value)"},
+	{0x02160, "FCRTL (Flow control rx threshold low)"},
+	{0x02168, "FCRTH (Flow control rx threshold high)"},
+	{0x02460, "FCRTV (Flow control refresh threshold)"},
+	{0x00100, "RCTL (Receive control register)"},
+	{0x05000, "RXCSUM (Receive checksum control)"},
+	{0x05004, "RLPML (Receive long packet max length)"},
+	{0x05008, "RFCTL (Receive filter control)"},
+	{0x05818, "MRQC (Multiple rx queues command)"},
+	{0x0581C, "VT_CTL (VMDq control)"},
+	{0x00400, "TCTL (Transmit ctrl register)"},
+	{0x00404, "TCTL_EXT (Transmit ctrl register extended)"},
+	{0x00410, "TIPG (Transmit IPG)"},
+	{0x03590, "DTXCTL (DMA tx control)"},
+	{0x05800, "WUC (Wake up control)"},
+	{0x05808, "WUFC (Wake up filter control)"},
+	{0x05810, "WUS (Wake up status)"},
+	{0x05838, "IPAV (IP address valid)"},
+	{0x05900, "WUPL (Wake up packet length)"},
+	{0x04200, "PCS_CFG (PCS configuration 0)"},
+	{0x04208, "PCS_LCTL (PCS link control)"},
+	{0x0420C, "PCS_LSTS (PCS link status)"},
+	{0x04218, "PCS_ANADV (AN advertisement)"},
+	{0x0421C, "PCS_LPAB (Link partner ability)"},
+	{0x04220, "PCS_NPTX (Next page transmit)"},
+	{0x04224, "PCS_LPABNP (Link partner ability next page)"},
+}
+
+var igb_speeds = []string{"10Mb/s", "100Mb/s", "1000Mb/s", "1000Mb/s"}
+
+func igb_dump_field(name string, val string) {
+	fmt.Printf("      %-38s %s\n", name+":", val)
+}
+
+func igb_dump_reg(r *igb_reg, val uint32) {
+	fmt.Printf("0x%05X: %-44s 0x%08X\n", r.offset, r.name, val)
+
+	switch r.offset {
+	case 0x00000: /* CTRL */
+		igb_dump_field("Invert Loss-Of-Signal", yesno(val&IGB_CTRL_ILOS != 0))
+		igb_dump_field("Receive flow control", endis(val&IGB_CTRL_RFCE != 0))
+		igb_dump_field("Transmit flow control", endis(val&IGB_CTRL_TFCE != 0))
+		igb_dump_field("VLAN mode", endis(val&IGB_CTRL_VME != 0))
+		igb_dump_field("Set link up", yesno(val&IGB_CTRL_SLU != 0))
+		igb_dump_field("Speed select", igb_speeds[(val&IGB_CTRL_SPD_SEL)>>8])
+		igb_dump_field("Force speed", yesno(val&IGB_CTRL_FRCSPD != 0))
+		igb_dump_field("Force duplex", yesno(val&IGB_CTRL_FRCDPX != 0))
+		igb_dump_field("Duplex", regs_bit(val&IGB_CTRL_FD != 0, "full", "half"))
+		igb_dump_field("GIO master disable", yesno(val&IGB_CTRL_GIO_MDIS != 0))
+		igb_dump_field("Device reset", yesno(val&IGB_CTRL_RST != 0))
+		igb_dump_field("PHY reset", yesno(val&IGB_CTRL_PHY_RST != 0))
+	case 0x00008: /* STATUS */
+		igb_dump_field("Duplex", regs_bit(val&IGB_STATUS_FD != 0, "full", "half"))
+		igb_dump_field("Link up", regs_bit(val&IGB_STATUS_LU != 0, "link config", "no link config"))
+		igb_dump_field("Port number", fmt.Sprintf("%d", (val&IGB_STATUS_LAN_ID)>>2))
+		igb_dump_field("Transmission paused", yesno(val&IGB_STATUS_TXOFF != 0))
+		igb_dump_field("Link speed", igb_speeds[(val&IGB_STATUS_SPEED_MASK)>>6])
+		igb_dump_field("GIO master", endis(val&IGB_STATUS_GIO_M_ENA != 0))
+		igb_dump_field("Device reset pending", yesno(val&IGB_STATUS_DEV_RST != 0))
+	case 0x00018: /* CTRL_EXT */
+		link_modes := []string{"direct copper", "1000Base-KX",
+			"SGMII", "SerDes"}
+		igb_dump_field("PF reset done", yesno(val&IGB_CTRL_EXT_PFRSTD != 0))
+		igb_dump_field("Link mode", link_modes[(val&IGB_CTRL_EXT_LINK_MODE)>>22])
+		igb_dump_field("Driver loaded", yesno(val&IGB_CTRL_EXT_DRV_LOAD != 0))
+	case 0x00100: /* RCTL */
+		sizes := []string{"2048", "1024", "512", "256"}
+		igb_dump_field("Receiver", endis(val&IGB_RCTL_EN != 0))
+		igb_dump_field("Store bad packets", endis(val&IGB_RCTL_SBP != 0))
+		igb_dump_field("Unicast promiscuous", endis(val&IGB_RCTL_UPE != 0))
+		igb_dump_field("Multicast promiscuous", endis(val&IGB_RCTL_MPE != 0))
+		igb_dump_field("Long packet", endis(val&IGB_RCTL_LPE != 0))
+		igb_dump_field("Loopback", regs_bit(val&IGB_RCTL_LBM != 0, "MAC", "none"))
+		igb_dump_field("Broadcast accept mode", regs_bit(val&IGB_RCTL_BAM != 0, "accept", "ignore"))
+		igb_dump_field("VLAN filter", endis(val&IGB_RCTL_VFE != 0))
+		igb_dump_field("Canonical form indicator", endis(val&IGB_RCTL_CFIEN != 0))
+		igb_dump_field("Discard pause frames", regs_bit(val&IGB_RCTL_DPF != 0, "ignored", "filtered"))
+		igb_dump_field("Pass MAC control frames", regs_bit(val&IGB_RCTL_PMCF != 0, "pass", "don't pass"))
+		igb_dump_field("Strip Ethernet CRC", yesno(val&IGB_RCTL_SECRC != 0))
+		igb_dump_field("Receive buffer size", sizes[(val&IGB_RCTL_SZ)>>16])
+	case 0x00400: /* TCTL */
+		igb_dump_field("Transmitter", endis(val&IGB_TCTL_EN != 0))
+		igb_dump_field("Pad short packets", endis(val&IGB_TCTL_PSP != 0))
+		igb_dump_field("Collision threshold", fmt.Sprintf("%d", (val&IGB_TCTL_CT)>>4))
+		igb_dump_field("Collision distance", fmt.Sprintf("%d", (val&IGB_TCTL_COLD)>>12))
+		igb_dump_field("Software XOFF Transmission", yesno(val&IGB_TCTL_SWXOFF != 0))
+		igb_dump_field("Re-transmit on late collision", endis(val&IGB_TCTL_RTLC != 0))
+	case 0x0420C: /* PCS_LSTS */
+		igb_dump_field("Link", regs_bit(val&IGB_PCS_LSTS_LINK_OK != 0, "up", "down"))
+		igb_dump_field("Speed", igb_speeds[(val&IGB_PCS_LSTS_SPEED)>>1])
+		igb_dump_field("Duplex", regs_bit(val&IGB_PCS_LSTS_DUPLEX != 0, "full", "half"))
+		igb_dump_field("Synchronized", yesno(val&IGB_PCS_LSTS_SYNC_OK != 0))
+		igb_dump_field("Autonegotiation complete", yesno(val&IGB_PCS_LSTS_AN_DONE != 0))
+	}
+}
+
+func igb_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	if regs.len < IGB_DUMP_LEN || regs.version>>24 != 1 {
+		return -1
+	}
+
+	for i := range igb_regs {
+		igb_dump_reg(&igb_regs[i], regs_u32(regs, uint32(i)))
+	}
+	return 0
+}
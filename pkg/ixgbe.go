@@ -0,0 +1,237 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * Register dump of the ixgbe driver, in the order ixgbe_get_regs() reads
+ * them. Per queue registers come as arrays, statistics follow the DCB
+ * registers and are left to -S. regs->version is 1 << 24 |
+ * revision id << 16 | device id.
+ */
+
+const (
+	IXGBE_DUMP_LEN = 881 * 4
+
+	IXGBE_CTRL_GIO_DIS = 0x00000004 /* Global IO Master Disable bit */
+	IXGBE_CTRL_LNK_RST = 0x00000008 /* Link Reset. Resets everything. */
+	IXGBE_CTRL_RST     = 0x04000000 /* Reset (SW) */
+
+	IXGBE_STATUS_LAN_ID = 0x0000000C /* LAN ID */
+	IXGBE_STATUS_GIO    = 0x00080000 /* GIO Master Enable Status */
+
+	IXGBE_CTRL_EXT_PFRSTD   = 0x00004000 /* Physical Function Reset Done */
+	IXGBE_CTRL_EXT_NS_DIS   = 0x00010000 /* No Snoop disable */
+	IXGBE_CTRL_EXT_RO_DIS   = 0x00020000 /* Relaxed Ordering disable */
+	IXGBE_CTRL_EXT_DRV_LOAD = 0x10000000 /* Driver loaded bit for FW */
+
+	IXGBE_EEC_PRES = 0x00000100 /* EEPROM Present */
+	IXGBE_EEC_ARD  = 0x00000200 /* EEPROM Auto Read Done */
+
+	IXGBE_RXCTRL_RXEN = 0x00000001 /* Enable Receiver */
+
+	IXGBE_FCTRL_SBP   = 0x00000002 /* Store Bad Packet */
+	IXGBE_FCTRL_MPE   = 0x00000100 /* Multicast Promiscuous Ena*/
+	IXGBE_FCTRL_UPE   = 0x00000200 /* Unicast Promiscuous Ena */
+	IXGBE_FCTRL_BAM   = 0x00000400 /* Broadcast Accept Mode */
+	IXGBE_FCTRL_PMCF  = 0x00001000 /* Pass MAC Control Frames */
+	IXGBE_FCTRL_DPF   = 0x00002000 /* Discard Pause Frame */
+	IXGBE_FCTRL_RPFCE = 0x00004000 /* Receive Priority FC Enable */
+	IXGBE_FCTRL_RFCE  = 0x00008000 /* Receive FC Enable */
+
+	IXGBE_VLNCTRL_VET   = 0x0000FFFF /* bits 0-15 */
+	IXGBE_VLNCTRL_CFI   = 0x10000000 /* bit 28 */
+	IXGBE_VLNCTRL_CFIEN = 0x20000000 /* bit 29 */
+	IXGBE_VLNCTRL_VFE   = 0x40000000 /* bit 30 */
+	IXGBE_VLNCTRL_VME   = 0x80000000 /* bit 31 */
+
+	IXGBE_RXCSUM_IPPCSE = 0x00001000 /* IP payload checksum enable */
+	IXGBE_RXCSUM_PCSD   = 0x00002000 /* packet checksum disabled */
+
+	IXGBE_XDCTL_ENABLE = 0x02000000 /* Rx/Tx queue enable */
+)
+
+type ixgbe_reg struct {
+	offset uint32
+	count  uint32 /* registers in the array, 1 for plain registers */
+	stride uint32
+	name   string
+}
+
+var ixgbe_regs = []ixgbe_reg{
+	/* General Registers */
+	{0x00000, 1, 0, "CTRL"},
+	{0x00008, 1, 0, "STATUS"},
+	{0x00018, 1, 0, "CTRL_EXT"},
+	{0x00020, 1, 0, "ESDP"},
+	{0x00028, 1, 0, "EODSDP"},
+	{0x00200, 1, 0, "LEDCTL"},
+	{0x00048, 1, 0, "FRTIMER"},
+	{0x0004C, 1, 0, "TCPTIMER"},
+	/* NVM Registers */
+	{0x10010, 1, 0, "EEC"},
+	{0x10014, 1, 0, "EERD"},
+	{0x1001C, 1, 0, "FLA"},
+	{0x10110, 1, 0, "EEMNGCTL"},
+	{0x10114, 1, 0, "EEMNGDATA"},
+	{0x10118, 1, 0, "FLMNGCTL"},
+	{0x1011C, 1, 0, "FLMNGDATA"},
+	{0x10120, 1, 0, "FLMNGCNT"},
+	{0x1013C, 1, 0, "FLOP"},
+	{0x10200, 1, 0, "GRC"},
+	/* Interrupt */
+	{0x00800, 1, 0, "EICR"},
+	{0x00808, 1, 0, "EICS"},
+	{0x00880, 1, 0, "EIMS"},
+	{0x00888, 1, 0, "EIMC"},
+	{0x00810, 1, 0, "EIAC"},
+	{0x00890, 1, 0, "EIAM"},
+	{0x00820, 1, 0, "EITR0"},
+	{0x00900, 1, 0, "IVAR0"},
+	{0x00000, 1, 0, "MSIXT"},
+	{0x02000, 1, 0, "MSIXPBA"},
+	{0x11068, 1, 0, "PBACL0"},
+	{0x00898, 1, 0, "GPIE"},
+	/* Flow Control */
+	{0x03008, 1, 0, "PFCTOP"},
+	{0x03200, 4, 4, "FCTTV"},
+	{0x03220, 8, 4, "FCRTL"},
+	{0x03260, 8, 4, "FCRTH"},
+	{0x032A0, 1, 0, "FCRTV"},
+	{0x0CE00, 1, 0, "TFCS"},
+	/* Receive DMA */
+	{0x01000, 64, 0x40, "RDBAL"},
+	{0x01004, 64, 0x40, "RDBAH"},
+	{0x01008, 64, 0x40, "RDLEN"},
+	{0x01010, 64, 0x40, "RDH"},
+	{0x01018, 64, 0x40, "RDT"},
+	{0x01028, 64, 0x40, "RXDCTL"},
+	{0x02100, 16, 4, "SRRCTL"},
+	{0x02200, 16, 4, "DCA_RXCTRL"},
+	{0x02F00, 1, 0, "RDRXCTL"},
+	{0x03C00, 8, 4, "RXPBSIZE"},
+	{0x03000, 1, 0, "RXCTRL"},
+	{0x03D04, 1, 0, "DROPEN"},
+	/* Receive */
+	{0x05000, 1, 0, "RXCSUM"},
+	{0x05008, 1, 0, "RFCTL"},
+	{0x05400, 16, 8, "RAL"},
+	{0x05404, 16, 8, "RAH"},
+	{0x05480, 1, 0, "PSRTYPE"},
+	{0x05080, 1, 0, "FCTRL"},
+	{0x05088, 1, 0, "VLNCTRL"},
+	{0x05090, 1, 0, "MCSTCTRL"},
+	{0x05818, 1, 0, "MRQC"},
+	{0x0581C, 1, 0, "VMD_CTL"},
+	{0x05A80, 8, 4, "IMIR"},
+	{0x05AA0, 8, 4, "IMIREXT"},
+	{0x05AC0, 1, 0, "IMIRVP"},
+	/* Transmit */
+	{0x06000, 32, 0x40, "TDBAL"},
+	{0x06004, 32, 0x40, "TDBAH"},
+	{0x06008, 32, 0x40, "TDLEN"},
+	{0x06010, 32, 0x40, "TDH"},
+	{0x06018, 32, 0x40, "TDT"},
+	{0x06028, 32, 0x40, "TXDCTL"},
+	{0x06038, 32, 0x40, "TDWBAL"},
+	{0x0603C, 32, 0x40, "TDWBAH"},
+	{0x07E00, 1, 0, "DTXCTL"},
+	{0x07200, 16, 4, "DCA_TXCTRL"},
+	{0x0CB00, 1, 0, "TIPG"},
+	{0x0CC00, 8, 4, "TXPBSIZE"},
+	{0x0CD10, 1, 0, "MNGTXMAP"},
+	/* Wake Up */
+	{0x05800, 1, 0, "WUC"},
+	{0x05808, 1, 0, "WUFC"},
+	{0x05810, 1, 0, "WUS"},
+	{0x05838, 1, 0, "IPAV"},
+	{0x05840, 1, 0, "IP4AT"},
+	{0x05880, 1, 0, "IP6AT"},
+	{0x05900, 1, 0, "WUPL"},
+	{0x05A00, 1, 0, "WUPM"},
+	{0x09000, 1, 0, "FHFT"},
+	/* DCB */
+	{0x03D00, 1, 0, "RMCS"},
+	{0x07F40, 1, 0, "DPMCS"},
+	{0x0CD00, 1, 0, "PDPMCS"},
+	{0x050A0, 1, 0, "RUPPBMR"},
+	{0x03C20, 8, 4, "RT2CR"},
+	{0x03C40, 8, 4, "RT2SR"},
+	{0x0602C, 8, 0x40, "TDTQ2TCCR"},
+	{0x0622C, 8, 0x40, "TDTQ2TCSR"},
+	{0x0CD20, 8, 4, "TDPT2TCCR"},
+	{0x0CD40, 8, 4, "TDPT2TCSR"},
+}
+
+func ixgbe_dump_field(name string, val string) {
+	fmt.Printf("       %-32s %s\n", name+":", val)
+}
+
+func ixgbe_dump_fields(name string, val uint32) {
+	switch name {
+	case "CTRL":
+		ixgbe_dump_field("GIO master disable", yesno(val&IXGBE_CTRL_GIO_DIS != 0))
+		ixgbe_dump_field("Link reset", yesno(val&IXGBE_CTRL_LNK_RST != 0))
+		ixgbe_dump_field("Device reset", yesno(val&IXGBE_CTRL_RST != 0))
+	case "STATUS":
+		ixgbe_dump_field("LAN ID", fmt.Sprintf("%d", (val&IXGBE_STATUS_LAN_ID)>>2))
+		ixgbe_dump_field("GIO master", endis(val&IXGBE_STATUS_GIO != 0))
+	case "CTRL_EXT":
+		ixgbe_dump_field("PF reset done", yesno(val&IXGBE_CTRL_EXT_PFRSTD != 0))
+		ixgbe_dump_field("No snoop", regs_bit(val&IXGBE_CTRL_EXT_NS_DIS != 0, "disabled", "enabled"))
+		ixgbe_dump_field("Relaxed ordering", regs_bit(val&IXGBE_CTRL_EXT_RO_DIS != 0, "disabled", "enabled"))
+		ixgbe_dump_field("Driver loaded", yesno(val&IXGBE_CTRL_EXT_DRV_LOAD != 0))
+	case "EEC":
+		ixgbe_dump_field("EEPROM present", yesno(val&IXGBE_EEC_PRES != 0))
+		ixgbe_dump_field("EEPROM auto read done", yesno(val&IXGBE_EEC_ARD != 0))
+	case "RXCTRL":
+		ixgbe_dump_field("Receive", endis(val&IXGBE_RXCTRL_RXEN != 0))
+	case "RXCSUM":
+		ixgbe_dump_field("IP payload checksum", endis(val&IXGBE_RXCSUM_IPPCSE != 0))
+		ixgbe_dump_field("Packet checksum", regs_bit(val&IXGBE_RXCSUM_PCSD != 0, "disabled", "enabled"))
+	case "FCTRL":
+		ixgbe_dump_field("Store bad packets", endis(val&IXGBE_FCTRL_SBP != 0))
+		ixgbe_dump_field("Multicast promiscuous", endis(val&IXGBE_FCTRL_MPE != 0))
+		ixgbe_dump_field("Unicast promiscuous", endis(val&IXGBE_FCTRL_UPE != 0))
+		ixgbe_dump_field("Broadcast accept", endis(val&IXGBE_FCTRL_BAM != 0))
+		ixgbe_dump_field("Pass MAC control frames", yesno(val&IXGBE_FCTRL_PMCF != 0))
+		ixgbe_dump_field("Discard pause frames", yesno(val&IXGBE_FCTRL_DPF != 0))
+		ixgbe_dump_field("Receive priority flow control", endis(val&IXGBE_FCTRL_RPFCE != 0))
+		ixgbe_dump_field("Receive flow control", endis(val&IXGBE_FCTRL_RFCE != 0))
+	case "VLNCTRL":
+		ixgbe_dump_field("VLAN Ethertype", fmt.Sprintf("0x%04x", val&IXGBE_VLNCTRL_VET))
+		ixgbe_dump_field("Canonical form indicator", regs_bit(val&IXGBE_VLNCTRL_CFI != 0, "1", "0"))
+		ixgbe_dump_field("CFI check", endis(val&IXGBE_VLNCTRL_CFIEN != 0))
+		ixgbe_dump_field("VLAN filter", endis(val&IXGBE_VLNCTRL_VFE != 0))
+		ixgbe_dump_field("VLAN mode", endis(val&IXGBE_VLNCTRL_VME != 0))
+	}
+}
+
+func ixgbe_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	if regs.len < IXGBE_DUMP_LEN || regs.version>>24 != 1 {
+		return -1
+	}
+
+	n := uint32(0)
+	for _, r := range ixgbe_regs {
+		for i := uint32(0); i < r.count; i++ {
+			val := regs_u32(regs, n)
+			n++
+			name := r.name
+			if r.count > 1 {
+				name = fmt.Sprintf("%s[%d]", r.name, i)
+			}
+			fmt.Printf("0x%05X: %-16s 0x%08X\n",
+				r.offset+i*r.stride, name, val)
+
+			/* Queue enables are the interesting part of the
+			 * descriptor control registers
+			 */
+			if r.name == "RXDCTL" || r.name == "TXDCTL" {
+				ixgbe_dump_field("Queue", endis(val&IXGBE_XDCTL_ENABLE != 0))
+			} else {
+				ixgbe_dump_fields(r.name, val)
+			}
+		}
+	}
+	return 0
+}
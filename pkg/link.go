@@ -0,0 +1,1043 @@
+package ethtool
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+/* ETHTOOL_GLINKSETTINGS request with room for the three link mode masks */
+type ethtool_link_settings_req struct {
+	req            ethtool_link_settings
+	link_mode_data [3 * ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32]uint32
+}
+
+func dump_link_caps(prefix string, an_prefix string, mask []uint32,
+	link_mode_only bool) {
+
+	/* Indent just like the separate functions used to */
+	indent := len(prefix) + 14
+	if indent < 24 {
+		indent = 24
+	}
+
+	fmt.Printf("	%s link modes:%*s", prefix, indent-len(prefix)-12, "")
+	did1 := 0
+	new_line_pend := false
+	for i := 0; i < len(link_mode_defs); i++ {
+		if did1 != 0 && link_mode_defs[i].same_line == 0 {
+			new_line_pend = true
+		}
+		if link_mode_test_bit(link_mode_defs[i].bit_index, mask) {
+			if new_line_pend {
+				fmt.Printf("\n")
+				fmt.Printf("	%*s", indent, "")
+				new_line_pend = false
+			}
+			did1++
+			fmt.Printf("%s ", link_mode_defs[i].name)
+		}
+	}
+	if did1 == 0 {
+		fmt.Printf("Not reported")
+	}
+	fmt.Printf("\n")
+
+	if link_mode_only {
+		return
+	}
+
+	fmt.Printf("	%s pause frame use: ", prefix)
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_Pause_BIT, mask) {
+		fmt.Printf("Symmetric")
+		if link_mode_test_bit(ETHTOOL_LINK_MODE_Asym_Pause_BIT, mask) {
+			fmt.Printf(" Receive-only")
+		}
+		fmt.Printf("\n")
+	} else if link_mode_test_bit(ETHTOOL_LINK_MODE_Asym_Pause_BIT, mask) {
+		fmt.Printf("Transmit-only\n")
+	} else {
+		fmt.Printf("No\n")
+	}
+
+	fmt.Printf("	%s auto-negotiation: ", an_prefix)
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_Autoneg_BIT, mask) {
+		fmt.Printf("Yes\n")
+	} else {
+		fmt.Printf("No\n")
+	}
+
+	fecreported := false
+	fmt.Printf("	%s FEC modes:", prefix)
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_FEC_NONE_BIT, mask) {
+		fmt.Printf(" None")
+		fecreported = true
+	}
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_FEC_BASER_BIT, mask) {
+		fmt.Printf(" BaseR")
+		fecreported = true
+	}
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_FEC_RS_BIT, mask) {
+		fmt.Printf(" RS")
+		fecreported = true
+	}
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_FEC_LLRS_BIT, mask) {
+		fmt.Printf(" LLRS")
+		fecreported = true
+	}
+	if !fecreported {
+		fmt.Printf(" Not reported")
+	}
+	fmt.Printf("\n")
+}
+
+func dump_supported(lus *ethtool_link_usettings) {
+	mask := lus.link_modes.supported[:]
+
+	fmt.Printf("	Supported ports: [ ")
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_TP_BIT, mask) {
+		fmt.Printf("TP ")
+	}
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_AUI_BIT, mask) {
+		fmt.Printf("AUI ")
+	}
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_BNC_BIT, mask) {
+		fmt.Printf("BNC ")
+	}
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_MII_BIT, mask) {
+		fmt.Printf("MII ")
+	}
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_FIBRE_BIT, mask) {
+		fmt.Printf("FIBRE ")
+	}
+	if link_mode_test_bit(ETHTOOL_LINK_MODE_Backplane_BIT, mask) {
+		fmt.Printf("Backplane ")
+	}
+	fmt.Printf("]\n")
+
+	dump_link_caps("Supported", "Supports", mask, false)
+}
+
+func dump_link_usettings(lus *ethtool_link_usettings) int {
+	dump_supported(lus)
+	dump_link_caps("Advertised", "Advertised",
+		lus.link_modes.advertising[:], false)
+	if !link_mode_is_empty(lus.link_modes.lp_advertising[:]) {
+		dump_link_caps("Link partner advertised",
+			"Link partner advertised",
+			lus.link_modes.lp_advertising[:], false)
+		common := link_mode_common(lus.link_modes.advertising[:],
+			lus.link_modes.lp_advertising[:])
+		if common != nil {
+			fmt.Printf("	Common link mode: %s\n", common.name)
+		}
+	}
+
+	fmt.Printf("	Speed: ")
+	if lus.base.speed == 0 || lus.base.speed == math.MaxUint16 ||
+		lus.base.speed == math.MaxUint32 {
+		fmt.Printf("Unknown!\n")
+	} else {
+		fmt.Printf("%dMb/s\n", lus.base.speed)
+	}
+
+	fmt.Printf("	Duplex: ")
+	switch lus.base.duplex {
+	case DUPLEX_HALF:
+		fmt.Printf("Half\n")
+	case DUPLEX_FULL:
+		fmt.Printf("Full\n")
+	default:
+		fmt.Printf("Unknown! (%d)\n", lus.base.duplex)
+	}
+
+	fmt.Printf("	Port: ")
+	switch lus.base.port {
+	case PORT_TP:
+		fmt.Printf("Twisted Pair\n")
+	case PORT_AUI:
+		fmt.Printf("AUI\n")
+	case PORT_BNC:
+		fmt.Printf("BNC\n")
+	case PORT_MII:
+		fmt.Printf("MII\n")
+	case PORT_FIBRE:
+		fmt.Printf("FIBRE\n")
+	case PORT_DA:
+		fmt.Printf("Direct Attach Copper\n")
+	case PORT_NONE:
+		fmt.Printf("None\n")
+	case PORT_OTHER:
+		fmt.Printf("Other\n")
+	default:
+		fmt.Printf("Unknown! (%d)\n", lus.base.port)
+	}
+
+	fmt.Printf("	PHYAD: %d\n", lus.base.phy_address)
+	fmt.Printf("	Transceiver: ")
+	switch lus.base.transceiver {
+	case XCVR_INTERNAL:
+		fmt.Printf("internal\n")
+	case XCVR_EXTERNAL:
+		fmt.Printf("external\n")
+	default:
+		fmt.Printf("Unknown!\n")
+	}
+
+	if lus.base.autoneg == AUTONEG_DISABLE {
+		fmt.Printf("	Auto-negotiation: off\n")
+	} else {
+		fmt.Printf("	Auto-negotiation: on\n")
+	}
+
+	if lus.base.port == PORT_TP {
+		dump_mdix(lus.base.eth_tp_mdix, lus.base.eth_tp_mdix_ctrl)
+	}
+	return 0
+}
+
+func dump_msglvl(msglvl uint32) {
+	fmt.Printf("	Current message level: 0x%08x (%d)\n"+
+		"			       ", msglvl, msglvl)
+	print_flags(flags_msglvl, uint32(n_flags_msglvl), msglvl)
+	fmt.Printf("\n")
+}
+
+func dump_link(link uint32) {
+	if link != 0 {
+		fmt.Printf("	Link detected: yes\n")
+	} else {
+		fmt.Printf("	Link detected: no\n")
+	}
+}
+
+func do_ioctl_glinksettings(ctx *cmd_context) (*ethtool_link_usettings, error) {
+	var ecmd ethtool_link_settings_req
+
+	/* Handshake with kernel to determine number of words for link
+	 * mode bitmaps. When requested number of bitmap words is not
+	 * the one expected by kernel, the latter returns the integer
+	 * opposite of what it is expecting. We request length 0 below
+	 * (aka. invalid bitmap length) to get this info.
+	 */
+	ecmd.req.cmd = ETHTOOL_GLINKSETTINGS
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&ecmd)))
+	if err != nil {
+		return nil, err
+	}
+
+	/* see above: we expect a strictly negative value from kernel. */
+	if ecmd.req.link_mode_masks_nwords >= 0 ||
+		ecmd.req.cmd != ETHTOOL_GLINKSETTINGS {
+		return nil, syscall.EOPNOTSUPP
+	}
+
+	/* got the real ecmd.req.link_mode_masks_nwords,
+	 * now send the real request
+	 */
+	ecmd.req.cmd = ETHTOOL_GLINKSETTINGS
+	ecmd.req.link_mode_masks_nwords = -ecmd.req.link_mode_masks_nwords
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&ecmd)))
+	if err != nil {
+		return nil, err
+	}
+	nwords := int(ecmd.req.link_mode_masks_nwords)
+	if nwords <= 0 || nwords > ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32 ||
+		ecmd.req.cmd != ETHTOOL_GLINKSETTINGS {
+		return nil, syscall.EOPNOTSUPP
+	}
+
+	lus := &ethtool_link_usettings{base: ecmd.req}
+	copy(lus.link_modes.supported[:], ecmd.link_mode_data[0:nwords])
+	copy(lus.link_modes.advertising[:], ecmd.link_mode_data[nwords:2*nwords])
+	copy(lus.link_modes.lp_advertising[:], ecmd.link_mode_data[2*nwords:3*nwords])
+	return lus, nil
+}
+
+func do_ioctl_gset(ctx *cmd_context) (*ethtool_link_usettings, error) {
+	ecmd := ethtool_cmd{cmd: ETHTOOL_GSET}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&ecmd)))
+	if err != nil {
+		return nil, err
+	}
+
+	lus := &ethtool_link_usettings{}
+	/* remember that ETHTOOL_GSET was used */
+	lus.base.cmd = ETHTOOL_GSET
+	lus.base.speed = uint32(ecmd.speed_hi)<<16 | uint32(ecmd.speed)
+	lus.base.duplex = ecmd.duplex
+	lus.base.port = ecmd.port
+	lus.base.phy_address = ecmd.phy_address
+	lus.base.autoneg = ecmd.autoneg
+	lus.base.mdio_support = ecmd.mdio_support
+	lus.base.eth_tp_mdix = uint8(ecmd.eth_tp_mdix)
+	lus.base.eth_tp_mdix_ctrl = uint8(ecmd.eth_tp_mdix_ctrl)
+	lus.base.transceiver = ecmd.transceiver
+	lus.base.link_mode_masks_nwords = 1
+	lus.link_modes.supported[0] = ecmd.supported
+	lus.link_modes.advertising[0] = ecmd.advertising
+	lus.link_modes.lp_advertising[0] = ecmd.lp_advertising
+	return lus, nil
+}
+
+/* get_link_usettings prefers ETHTOOL_GLINKSETTINGS, the legacy
+ * ETHTOOL_GSET is used on drivers or kernels without it.
+ */
+func get_link_usettings(ctx *cmd_context) (*ethtool_link_usettings, error) {
+	lus, err := do_ioctl_glinksettings(ctx)
+	if err == nil {
+		return lus, nil
+	}
+	return do_ioctl_gset(ctx)
+}
+
+/* dump_gset_part prints one section of the settings dump if it is
+ * available, it returns false if the section was skipped.
+ */
+func dump_gset_part(err error, what string, dump func()) bool {
+	if err == nil {
+		dump()
+		return true
+	}
+	if !errors.Is(err, syscall.EOPNOTSUPP) {
+		fmt.Printf("Cannot get %s: %v\n", what, err)
+	}
+	return false
+}
+
+func do_gset(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	fmt.Printf("Settings for %s:\n", ctx.devname)
+	allfail := true
+
+	lus, err := get_link_usettings(ctx)
+	if dump_gset_part(err, "device settings", func() { dump_link_usettings(lus) }) {
+		allfail = false
+	}
+
+	wolinfo := ethtool_wolinfo{cmd: ETHTOOL_GWOL}
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&wolinfo)))
+	if dump_gset_part(err, "wake-on-lan settings", func() { dump_wol(&wolinfo) }) {
+		allfail = false
+	}
+
+	edata := ethtool_value{cmd: ETHTOOL_GMSGLVL}
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&edata)))
+	if dump_gset_part(err, "message level", func() { dump_msglvl(edata.data) }) {
+		allfail = false
+	}
+
+	edata = ethtool_value{cmd: ETHTOOL_GLINK}
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&edata)))
+	if dump_gset_part(err, "link status", func() { dump_link(edata.data) }) {
+		allfail = false
+	}
+
+	if allfail {
+		fmt.Printf("No data available\n")
+		return 75
+	}
+	return 0
+}
+
+const NWAY_POLL_INTERVAL = 100 * time.Millisecond
+
+/* nway_wait_link polls ETHTOOL_GLINK until the link has been seen going
+ * down and coming back up after a restart, or the timeout expires. It
+ * returns the time it took.
+ */
+func nway_wait_link(ctx *cmd_context, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	went_down := false
+	for {
+		edata := ethtool_value{cmd: ETHTOOL_GLINK}
+		err := send_ioctl(ctx, uintptr(unsafe.Pointer(&edata)))
+		if err != nil {
+			return 0, err
+		}
+		elapsed := time.Since(start)
+		if edata.data == 0 {
+			went_down = true
+		} else if went_down {
+			return elapsed, nil
+		}
+		if elapsed >= timeout {
+			return elapsed, syscall.ETIMEDOUT
+		}
+		time.Sleep(NWAY_POLL_INTERVAL)
+	}
+}
+
+func do_nway_rst(ctx *cmd_context) int {
+	nway_changed := 0
+	nway_wait := uint32(0)
+	cmdline_nway := []cmdline_info{
+		{
+			name:       "wait",
+			tp:         CMDL_U32,
+			wanted_val: uintptr(unsafe.Pointer(&nway_wait)),
+		},
+	}
+	if parse_generic_cmdline(ctx, &nway_changed, &cmdline_nway) < 0 {
+		return -1
+	}
+
+	edata := ethtool_value{cmd: ETHTOOL_NWAY_RST}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&edata)))
+	if err != nil {
+		fmt.Printf("Cannot restart autonegotiation: %v\n", err)
+		return 80
+	}
+	if nway_wait == 0 {
+		return 0
+	}
+
+	elapsed, err := nway_wait_link(ctx, time.Duration(nway_wait)*time.Second)
+	if errors.Is(err, syscall.ETIMEDOUT) {
+		fmt.Printf("Link did not come up within %d seconds\n", nway_wait)
+		return 1
+	}
+	if err != nil {
+		fmt.Printf("Cannot get link status: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Link up after %.1fs", elapsed.Seconds())
+	lus, err := get_link_usettings(ctx)
+	if err == nil {
+		fmt.Printf(", speed %s, %s duplex", speed_str(lus.base.speed),
+			duplex_str(lus.base.duplex))
+	}
+	fmt.Printf("\n")
+	return 0
+}
+
+/* parse_hex_bitmap parses a hex number of any length into 32 bit words,
+ * least significant word first.
+ */
+func parse_hex_bitmap(s string, nbits int) ([]uint32, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if len(s) == 0 {
+		return nil, syscall.EINVAL
+	}
+	bitmap := make([]uint32, (nbits+31)/32)
+	for i := 0; len(s) > 0; i++ {
+		start := len(s) - 8
+		if start < 0 {
+			start = 0
+		}
+		v, err := strconv.ParseUint(s[start:], 16, 32)
+		if err != nil {
+			return nil, err
+		}
+		if i >= len(bitmap) {
+			if v != 0 {
+				return nil, syscall.ERANGE
+			}
+		} else {
+			bitmap[i] = uint32(v)
+		}
+		s = s[:start]
+	}
+	return bitmap, nil
+}
+
+func do_ioctl_slinksettings(ctx *cmd_context, lus *ethtool_link_usettings) error {
+	/* refuse to send ETHTOOL_SLINKSETTINGS if the settings were
+	 * retrieved with ETHTOOL_GSET
+	 */
+	if lus.base.cmd != ETHTOOL_GLINKSETTINGS {
+		return syscall.EOPNOTSUPP
+	}
+	nwords := int(lus.base.link_mode_masks_nwords)
+	if nwords <= 0 {
+		return syscall.EINVAL
+	}
+
+	var ecmd ethtool_link_settings_req
+	ecmd.req = lus.base
+	ecmd.req.cmd = ETHTOOL_SLINKSETTINGS
+	copy(ecmd.link_mode_data[0:nwords], lus.link_modes.supported[:nwords])
+	copy(ecmd.link_mode_data[nwords:2*nwords], lus.link_modes.advertising[:nwords])
+	copy(ecmd.link_mode_data[2*nwords:3*nwords], lus.link_modes.lp_advertising[:nwords])
+	return send_ioctl(ctx, uintptr(unsafe.Pointer(&ecmd)))
+}
+
+func do_ioctl_sset(ctx *cmd_context, lus *ethtool_link_usettings) error {
+	/* the legacy interface only knows the first 32 link modes */
+	for i := 1; i < ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32; i++ {
+		if lus.link_modes.supported[i] != 0 ||
+			lus.link_modes.advertising[i] != 0 ||
+			lus.link_modes.lp_advertising[i] != 0 {
+			return syscall.EINVAL
+		}
+	}
+
+	ecmd := ethtool_cmd{
+		cmd:              ETHTOOL_SSET,
+		supported:        lus.link_modes.supported[0],
+		advertising:      lus.link_modes.advertising[0],
+		lp_advertising:   lus.link_modes.lp_advertising[0],
+		speed:            uint16(lus.base.speed),
+		speed_hi:         uint16(lus.base.speed >> 16),
+		duplex:           lus.base.duplex,
+		port:             lus.base.port,
+		phy_address:      lus.base.phy_address,
+		transceiver:      lus.base.transceiver,
+		autoneg:          lus.base.autoneg,
+		mdio_support:     lus.base.mdio_support,
+		eth_tp_mdix:      uint16(lus.base.eth_tp_mdix),
+		eth_tp_mdix_ctrl: uint16(lus.base.eth_tp_mdix_ctrl),
+	}
+	return send_ioctl(ctx, uintptr(unsafe.Pointer(&ecmd)))
+}
+
+func onoff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func speed_str(speed uint32) string {
+	if speed == 0 || speed == math.MaxUint16 || speed == math.MaxUint32 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dMb/s", speed)
+}
+
+func duplex_str(duplex uint8) string {
+	switch duplex {
+	case DUPLEX_HALF:
+		return "half"
+	case DUPLEX_FULL:
+		return "full"
+	}
+	return "unknown"
+}
+
+func port_str(port uint8) string {
+	switch port {
+	case PORT_TP:
+		return "tp"
+	case PORT_AUI:
+		return "aui"
+	case PORT_BNC:
+		return "bnc"
+	case PORT_MII:
+		return "mii"
+	case PORT_FIBRE:
+		return "fibre"
+	case PORT_DA:
+		return "da"
+	case PORT_NONE:
+		return "none"
+	}
+	return "other"
+}
+
+func mdix_ctrl_str(mdix uint8) string {
+	switch mdix {
+	case ETH_TP_MDI:
+		return "off"
+	case ETH_TP_MDI_X:
+		return "on"
+	case ETH_TP_MDI_AUTO:
+		return "auto"
+	}
+	return "unknown"
+}
+
+func xcvr_str(xcvr uint8) string {
+	switch xcvr {
+	case XCVR_INTERNAL:
+		return "internal"
+	case XCVR_EXTERNAL:
+		return "external"
+	}
+	return "unknown"
+}
+
+func master_slave_cfg_str(cfg uint8) string {
+	switch cfg {
+	case MASTER_SLAVE_CFG_MASTER_PREFERRED:
+		return "master-preferred"
+	case MASTER_SLAVE_CFG_SLAVE_PREFERRED:
+		return "slave-preferred"
+	case MASTER_SLAVE_CFG_MASTER_FORCE:
+		return "master-force"
+	case MASTER_SLAVE_CFG_SLAVE_FORCE:
+		return "slave-force"
+	}
+	return "unknown"
+}
+
+/* sset_change records one setting of -s and whether it differs from the
+ * current value, so that only real changes are reported.
+ */
+type sset_change struct {
+	name    string
+	old     string
+	new     string
+	changed bool
+}
+
+func sset_compare(changes *[]sset_change, name string, old, new string) {
+	c := sset_change{
+		name:    name,
+		old:     old,
+		new:     new,
+		changed: old != new,
+	}
+	if !c.changed {
+		fmt.Printf("%s unmodified, ignoring\n", name)
+	}
+	*changes = append(*changes, c)
+}
+
+func sset_changed(changes []sset_change) bool {
+	for _, c := range changes {
+		if c.changed {
+			return true
+		}
+	}
+	return false
+}
+
+/* sset_report prints the settings that were changed, or the ones that
+ * could not be set when err is not nil.
+ */
+func sset_report(changes []sset_change, err error) int {
+	n := 0
+	for _, c := range changes {
+		if !c.changed {
+			continue
+		}
+		if err != nil {
+			fmt.Printf("  not setting %s\n", c.name)
+		} else {
+			fmt.Printf("%s changed: %s => %s\n", c.name, c.old, c.new)
+		}
+		n++
+	}
+	return n
+}
+
+func do_sset(ctx *cmd_context) int {
+	speed_wanted := -1
+	duplex_wanted := -1
+	port_wanted := -1
+	mdix_wanted := -1
+	autoneg_wanted := -1
+	phyad_wanted := -1
+	xcvr_wanted := -1
+	master_slave_wanted := -1
+	var full_advertising_wanted []uint32 /* advertise MASK */
+	var advertising_wanted []uint32      /* modes from speed and duplex */
+	var advertising_on, advertising_off []uint32
+	gset_changed := 0
+	wol_wanted := uint32(0)
+	wol_mask := uint32(0)
+	wol_change := false
+	var sopass_wanted net.HardwareAddr
+	gwol_changed := 0
+	msglvl_changed := 0
+	msglvl_wanted := uint32(0)
+	msglvl_mask := uint32(0)
+	ret := 0
+
+	cmdline_msglvl := make([]cmdline_info, n_flags_msglvl)
+	for i := 0; i < n_flags_msglvl; i++ {
+		flag_to_cmdline_info(flags_msglvl[i].name, flags_msglvl[i].value,
+			&msglvl_wanted, &msglvl_mask, &cmdline_msglvl[i])
+	}
+
+	argc := ctx.argc
+	argp := ctx.argp
+	for i := 0; i < argc; i++ {
+		opt := argp[i]
+		i++
+		if i >= argc {
+			return -1
+		}
+		switch opt {
+		case "speed":
+			gset_changed = 1
+			v, err := strconv.ParseUint(argp[i], 10, 31)
+			if err != nil {
+				return -1
+			}
+			speed_wanted = int(v)
+		case "duplex":
+			gset_changed = 1
+			switch argp[i] {
+			case "half":
+				duplex_wanted = DUPLEX_HALF
+			case "full":
+				duplex_wanted = DUPLEX_FULL
+			default:
+				return -1
+			}
+		case "port":
+			gset_changed = 1
+			switch argp[i] {
+			case "tp":
+				port_wanted = PORT_TP
+			case "aui":
+				port_wanted = PORT_AUI
+			case "bnc":
+				port_wanted = PORT_BNC
+			case "mii":
+				port_wanted = PORT_MII
+			case "fibre":
+				port_wanted = PORT_FIBRE
+			case "da":
+				port_wanted = PORT_DA
+			default:
+				return -1
+			}
+		case "mdix":
+			gset_changed = 1
+			switch argp[i] {
+			case "auto":
+				mdix_wanted = ETH_TP_MDI_AUTO
+			case "on":
+				mdix_wanted = ETH_TP_MDI_X
+			case "off":
+				mdix_wanted = ETH_TP_MDI
+			default:
+				return -1
+			}
+		case "autoneg":
+			gset_changed = 1
+			switch argp[i] {
+			case "on":
+				autoneg_wanted = AUTONEG_ENABLE
+			case "off":
+				autoneg_wanted = AUTONEG_DISABLE
+			default:
+				return -1
+			}
+		case "advertise":
+			gset_changed = 1
+			if _, ok := link_mode_by_name(argp[i]); !ok {
+				var err error
+				full_advertising_wanted, err = parse_hex_bitmap(argp[i],
+					__ETHTOOL_LINK_MODE_MASK_NBITS)
+				if err != nil {
+					return -1
+				}
+				break
+			}
+			/* advertise MODE on|off ... [--] */
+			advertising_on = make([]uint32, ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32)
+			advertising_off = make([]uint32, ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32)
+			for ; i < argc; i += 2 {
+				bit, ok := link_mode_by_name(argp[i])
+				if !ok {
+					break
+				}
+				if i+1 >= argc {
+					return -1
+				}
+				switch argp[i+1] {
+				case "on":
+					link_mode_set_bit(bit, advertising_on)
+				case "off":
+					link_mode_set_bit(bit, advertising_off)
+				default:
+					return -1
+				}
+			}
+			if i < argc && argp[i] != "--" {
+				i--
+			}
+		case "phyad":
+			gset_changed = 1
+			v, err := strconv.ParseUint(argp[i], 0, 8)
+			if err != nil {
+				return -1
+			}
+			phyad_wanted = int(v)
+		case "xcvr":
+			gset_changed = 1
+			switch argp[i] {
+			case "internal":
+				xcvr_wanted = XCVR_INTERNAL
+			case "external":
+				xcvr_wanted = XCVR_EXTERNAL
+			default:
+				return -1
+			}
+		case "master-slave":
+			gset_changed = 1
+			switch argp[i] {
+			case "master-preferred":
+				master_slave_wanted = MASTER_SLAVE_CFG_MASTER_PREFERRED
+			case "slave-preferred":
+				master_slave_wanted = MASTER_SLAVE_CFG_SLAVE_PREFERRED
+			case "master-force":
+				master_slave_wanted = MASTER_SLAVE_CFG_MASTER_FORCE
+			case "slave-force":
+				master_slave_wanted = MASTER_SLAVE_CFG_SLAVE_FORCE
+			default:
+				return -1
+			}
+		case "wol":
+			gwol_changed = 1
+			wol_change = true
+			var err error
+			wol_wanted, wol_mask, err = parse_wolopts(argp[i])
+			if err != nil {
+				return -1
+			}
+		case "sopass":
+			gwol_changed = 1
+			var err error
+			sopass_wanted, err = net.ParseMAC(argp[i])
+			if err != nil || len(sopass_wanted) != SOPASS_MAX {
+				return -1
+			}
+		case "msglvl":
+			if argp[i] == "" {
+				return -1
+			}
+			if argp[i][0] >= '0' && argp[i][0] <= '9' {
+				msglvl_changed = 1
+				v, m, err := parse_value_mask(argp[i])
+				if err != nil {
+					return -1
+				}
+				msglvl_wanted = v
+				msglvl_mask = m
+				break
+			}
+			/* type on|off ... [--] */
+			j := i
+			for ; j+1 < argc && argp[j] != "--"; j += 2 {
+				found := false
+				for _, f := range flags_msglvl {
+					if f.name == argp[j] {
+						found = true
+					}
+				}
+				if !found {
+					break
+				}
+			}
+			sub := cmd_context{argc: j - i, argp: argp[i:j]}
+			if j == i || parse_generic_cmdline(&sub, &msglvl_changed, &cmdline_msglvl) != 0 {
+				return -1
+			}
+			i = j - 1
+			if j < argc && argp[j] == "--" {
+				i = j
+			}
+		default:
+			return -1
+		}
+	}
+
+	if gset_changed != 0 {
+		lus, err := get_link_usettings(ctx)
+		if err != nil {
+			fmt.Printf("Cannot get current device settings: %v\n", err)
+			return 1
+		}
+		var changes []sset_change
+
+		/* Change everything the user specified. */
+		if speed_wanted != -1 {
+			sset_compare(&changes, "speed", speed_str(lus.base.speed), speed_str(uint32(speed_wanted)))
+			lus.base.speed = uint32(speed_wanted)
+		}
+		if duplex_wanted != -1 {
+			sset_compare(&changes, "duplex", duplex_str(lus.base.duplex), duplex_str(uint8(duplex_wanted)))
+			lus.base.duplex = uint8(duplex_wanted)
+		}
+		if port_wanted != -1 {
+			sset_compare(&changes, "port", port_str(lus.base.port), port_str(uint8(port_wanted)))
+			lus.base.port = uint8(port_wanted)
+		}
+		if mdix_wanted != -1 {
+			/* check driver supports MDI-X */
+			if lus.base.eth_tp_mdix_ctrl != ETH_TP_MDI_INVALID {
+				sset_compare(&changes, "mdix", mdix_ctrl_str(lus.base.eth_tp_mdix_ctrl),
+					mdix_ctrl_str(uint8(mdix_wanted)))
+				lus.base.eth_tp_mdix_ctrl = uint8(mdix_wanted)
+			} else {
+				fmt.Printf("setting MDI not supported\n")
+			}
+		}
+		if autoneg_wanted != -1 {
+			sset_compare(&changes, "autoneg", onoff(lus.base.autoneg != 0), onoff(autoneg_wanted != 0))
+			lus.base.autoneg = uint8(autoneg_wanted)
+		}
+		if phyad_wanted != -1 {
+			sset_compare(&changes, "phyad", fmt.Sprint(lus.base.phy_address), fmt.Sprint(phyad_wanted))
+			lus.base.phy_address = uint8(phyad_wanted)
+		}
+		if xcvr_wanted != -1 {
+			sset_compare(&changes, "xcvr", xcvr_str(lus.base.transceiver), xcvr_str(uint8(xcvr_wanted)))
+			lus.base.transceiver = uint8(xcvr_wanted)
+		}
+		if master_slave_wanted != -1 {
+			if lus.base.cmd == ETHTOOL_GLINKSETTINGS &&
+				lus.base.master_slave_cfg != MASTER_SLAVE_CFG_UNSUPPORTED {
+				sset_compare(&changes, "master-slave", master_slave_cfg_str(lus.base.master_slave_cfg),
+					master_slave_cfg_str(uint8(master_slave_wanted)))
+				lus.base.master_slave_cfg = uint8(master_slave_wanted)
+			} else {
+				fmt.Printf("setting master-slave not supported\n")
+			}
+		}
+		/* If the user specified speed or duplex with autoneg on but
+		 * no advertisement, advertise the supported modes matching
+		 * them.
+		 */
+		if full_advertising_wanted == nil && advertising_on == nil &&
+			(speed_wanted != -1 || duplex_wanted != -1) &&
+			lus.base.autoneg != 0 {
+			advertising_wanted = link_modes_matching(
+				lus.link_modes.supported[:], speed_wanted, duplex_wanted)
+			if link_mode_is_empty(advertising_wanted) {
+				advertising_wanted = nil
+			}
+		}
+		if (speed_wanted != -1 || duplex_wanted != -1) &&
+			lus.base.autoneg != 0 && advertising_wanted == nil {
+			fmt.Printf("Cannot advertise")
+			if speed_wanted >= 0 {
+				fmt.Printf(" speed %d", speed_wanted)
+			}
+			if duplex_wanted == DUPLEX_FULL {
+				fmt.Printf(" duplex full")
+			} else if duplex_wanted == DUPLEX_HALF {
+				fmt.Printf(" duplex half")
+			}
+			fmt.Printf("\n")
+		}
+
+		old_adv := lus.link_modes.advertising
+		all := all_advertised_modes()
+		adv := lus.link_modes.advertising[:]
+		sup := lus.link_modes.supported[:]
+		if autoneg_wanted == AUTONEG_ENABLE && advertising_wanted == nil &&
+			full_advertising_wanted == nil && advertising_on == nil {
+			/* Auto negotiation enabled, but with
+			 * unspecified speed and duplex: enable all
+			 * supported speeds and duplexes.
+			 */
+			for i := range adv {
+				adv[i] = (adv[i] & ^all[i]) | (sup[i] & all[i])
+			}
+		} else if advertising_wanted != nil {
+			/* Enable all requested modes */
+			for i := range adv {
+				adv[i] = (adv[i] & ^all[i]) | advertising_wanted[i]
+			}
+		} else if full_advertising_wanted != nil {
+			for i := range adv {
+				adv[i] = 0
+				if i < len(full_advertising_wanted) {
+					adv[i] = full_advertising_wanted[i]
+				}
+			}
+		} else if advertising_on != nil {
+			for i := range adv {
+				adv[i] = (adv[i] | advertising_on[i]) & ^advertising_off[i]
+			}
+		}
+		if full_advertising_wanted != nil || advertising_on != nil {
+			sset_compare(&changes, "advertise",
+				link_mode_mask_str(old_adv[:]), link_mode_mask_str(adv))
+		} else if old_adv != lus.link_modes.advertising {
+			changes = append(changes, sset_change{"advertise",
+				link_mode_mask_str(old_adv[:]), link_mode_mask_str(adv), true})
+		}
+
+		if !sset_changed(changes) {
+			fmt.Printf("no link settings changed\n")
+		} else {
+			/* Try to perform the update. */
+			if lus.base.cmd == ETHTOOL_GLINKSETTINGS {
+				err = do_ioctl_slinksettings(ctx, lus)
+			} else {
+				err = do_ioctl_sset(ctx, lus)
+			}
+			if err != nil {
+				fmt.Printf("Cannot set new settings: %v\n", err)
+				ret = 1
+			}
+			sset_report(changes, err)
+		}
+	}
+
+	if gwol_changed != 0 {
+		var changes []sset_change
+		wol := ethtool_wolinfo{cmd: ETHTOOL_GWOL}
+		err := send_ioctl(ctx, uintptr(unsafe.Pointer(&wol)))
+		if err != nil {
+			fmt.Printf("Cannot get current wake-on-lan settings: %v\n", err)
+			return 1
+		}
+		/* Change everything the user specified. */
+		if wol_change {
+			wolopts := (wol.wolopts & ^wol_mask) | wol_wanted
+			sset_compare(&changes, "wol", string(unparse_wolopts(wol.wolopts)),
+				string(unparse_wolopts(wolopts)))
+			wol.wolopts = wolopts
+		}
+		if sopass_wanted != nil {
+			sset_compare(&changes, "sopass", net.HardwareAddr(wol.sopass[:]).String(),
+				sopass_wanted.String())
+			copy(wol.sopass[:], sopass_wanted)
+		}
+		if sset_changed(changes) {
+			wol.cmd = ETHTOOL_SWOL
+			err = send_ioctl(ctx, uintptr(unsafe.Pointer(&wol)))
+			if err != nil {
+				fmt.Printf("Cannot set new wake-on-lan settings: %v\n", err)
+				ret = 1
+			}
+			sset_report(changes, err)
+		}
+	}
+
+	if msglvl_changed != 0 {
+		var changes []sset_change
+		edata := ethtool_value{cmd: ETHTOOL_GMSGLVL}
+		err := send_ioctl(ctx, uintptr(unsafe.Pointer(&edata)))
+		if err != nil {
+			fmt.Printf("Cannot get msglvl: %v\n", err)
+			return 1
+		}
+		msglvl := (edata.data & ^msglvl_mask) | msglvl_wanted
+		sset_compare(&changes, "msglvl", fmt.Sprintf("0x%08x", edata.data),
+			fmt.Sprintf("0x%08x", msglvl))
+		if sset_changed(changes) {
+			edata.cmd = ETHTOOL_SMSGLVL
+			edata.data = msglvl
+			err = send_ioctl(ctx, uintptr(unsafe.Pointer(&edata)))
+			if err != nil {
+				fmt.Printf("Cannot set new msglvl: %v\n", err)
+				ret = 1
+			}
+			sset_report(changes, err)
+		}
+	}
+
+	return ret
+}
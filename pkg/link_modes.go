@@ -0,0 +1,227 @@
+package ethtool
+
+import (
+	"strings"
+)
+
+/* link_mode_def describes one speed/duplex link mode bit */
+type link_mode_def struct {
+	same_line int /* print on same line as previous */
+	bit_index uint32
+	speed     uint32
+	duplex    uint8
+	media     string
+	name      string
+}
+
+var link_mode_defs = []link_mode_def{
+	{0, ETHTOOL_LINK_MODE_10baseT_Half_BIT, 10, DUPLEX_HALF, "T", "10baseT/Half"},
+	{1, ETHTOOL_LINK_MODE_10baseT_Full_BIT, 10, DUPLEX_FULL, "T", "10baseT/Full"},
+	{0, ETHTOOL_LINK_MODE_100baseT_Half_BIT, 100, DUPLEX_HALF, "T", "100baseT/Half"},
+	{1, ETHTOOL_LINK_MODE_100baseT_Full_BIT, 100, DUPLEX_FULL, "T", "100baseT/Full"},
+	{0, ETHTOOL_LINK_MODE_100baseT1_Full_BIT, 100, DUPLEX_FULL, "T1", "100baseT1/Full"},
+	{0, ETHTOOL_LINK_MODE_1000baseT_Half_BIT, 1000, DUPLEX_HALF, "T", "1000baseT/Half"},
+	{1, ETHTOOL_LINK_MODE_1000baseT_Full_BIT, 1000, DUPLEX_FULL, "T", "1000baseT/Full"},
+	{0, ETHTOOL_LINK_MODE_1000baseT1_Full_BIT, 1000, DUPLEX_FULL, "T1", "1000baseT1/Full"},
+	{0, ETHTOOL_LINK_MODE_1000baseKX_Full_BIT, 1000, DUPLEX_FULL, "KX", "1000baseKX/Full"},
+	{0, ETHTOOL_LINK_MODE_1000baseX_Full_BIT, 1000, DUPLEX_FULL, "X", "1000baseX/Full"},
+	{0, ETHTOOL_LINK_MODE_2500baseT_Full_BIT, 2500, DUPLEX_FULL, "T", "2500baseT/Full"},
+	{0, ETHTOOL_LINK_MODE_2500baseX_Full_BIT, 2500, DUPLEX_FULL, "X", "2500baseX/Full"},
+	{0, ETHTOOL_LINK_MODE_5000baseT_Full_BIT, 5000, DUPLEX_FULL, "T", "5000baseT/Full"},
+	{0, ETHTOOL_LINK_MODE_10000baseT_Full_BIT, 10000, DUPLEX_FULL, "T", "10000baseT/Full"},
+	{0, ETHTOOL_LINK_MODE_10000baseKX4_Full_BIT, 10000, DUPLEX_FULL, "KX4", "10000baseKX4/Full"},
+	{0, ETHTOOL_LINK_MODE_10000baseKR_Full_BIT, 10000, DUPLEX_FULL, "KR", "10000baseKR/Full"},
+	/* a FEC capability rather than a link mode, speed 0 keeps it out of
+	 * the speed/duplex matching */
+	{0, ETHTOOL_LINK_MODE_10000baseR_FEC_BIT, 0, 0, "R", "10000baseR_FEC"},
+	{0, ETHTOOL_LINK_MODE_10000baseCR_Full_BIT, 10000, DUPLEX_FULL, "CR", "10000baseCR/Full"},
+	{0, ETHTOOL_LINK_MODE_10000baseSR_Full_BIT, 10000, DUPLEX_FULL, "SR", "10000baseSR/Full"},
+	{0, ETHTOOL_LINK_MODE_10000baseLR_Full_BIT, 10000, DUPLEX_FULL, "LR", "10000baseLR/Full"},
+	{0, ETHTOOL_LINK_MODE_10000baseLRM_Full_BIT, 10000, DUPLEX_FULL, "LRM", "10000baseLRM/Full"},
+	{0, ETHTOOL_LINK_MODE_10000baseER_Full_BIT, 10000, DUPLEX_FULL, "ER", "10000baseER/Full"},
+	{0, ETHTOOL_LINK_MODE_20000baseMLD2_Full_BIT, 20000, DUPLEX_FULL, "MLD2", "20000baseMLD2/Full"},
+	{0, ETHTOOL_LINK_MODE_20000baseKR2_Full_BIT, 20000, DUPLEX_FULL, "KR2", "20000baseKR2/Full"},
+	{0, ETHTOOL_LINK_MODE_25000baseCR_Full_BIT, 25000, DUPLEX_FULL, "CR", "25000baseCR/Full"},
+	{0, ETHTOOL_LINK_MODE_25000baseKR_Full_BIT, 25000, DUPLEX_FULL, "KR", "25000baseKR/Full"},
+	{0, ETHTOOL_LINK_MODE_25000baseSR_Full_BIT, 25000, DUPLEX_FULL, "SR", "25000baseSR/Full"},
+	{0, ETHTOOL_LINK_MODE_40000baseKR4_Full_BIT, 40000, DUPLEX_FULL, "KR4", "40000baseKR4/Full"},
+	{0, ETHTOOL_LINK_MODE_40000baseCR4_Full_BIT, 40000, DUPLEX_FULL, "CR4", "40000baseCR4/Full"},
+	{0, ETHTOOL_LINK_MODE_40000baseSR4_Full_BIT, 40000, DUPLEX_FULL, "SR4", "40000baseSR4/Full"},
+	{0, ETHTOOL_LINK_MODE_40000baseLR4_Full_BIT, 40000, DUPLEX_FULL, "LR4", "40000baseLR4/Full"},
+	{0, ETHTOOL_LINK_MODE_50000baseCR2_Full_BIT, 50000, DUPLEX_FULL, "CR2", "50000baseCR2/Full"},
+	{0, ETHTOOL_LINK_MODE_50000baseKR2_Full_BIT, 50000, DUPLEX_FULL, "KR2", "50000baseKR2/Full"},
+	{0, ETHTOOL_LINK_MODE_50000baseSR2_Full_BIT, 50000, DUPLEX_FULL, "SR2", "50000baseSR2/Full"},
+	{0, ETHTOOL_LINK_MODE_50000baseKR_Full_BIT, 50000, DUPLEX_FULL, "KR", "50000baseKR/Full"},
+	{0, ETHTOOL_LINK_MODE_50000baseSR_Full_BIT, 50000, DUPLEX_FULL, "SR", "50000baseSR/Full"},
+	{0, ETHTOOL_LINK_MODE_50000baseCR_Full_BIT, 50000, DUPLEX_FULL, "CR", "50000baseCR/Full"},
+	{0, ETHTOOL_LINK_MODE_50000baseLR_ER_FR_Full_BIT, 50000, DUPLEX_FULL, "LR_ER_FR", "50000baseLR_ER_FR/Full"},
+	{0, ETHTOOL_LINK_MODE_50000baseDR_Full_BIT, 50000, DUPLEX_FULL, "DR", "50000baseDR/Full"},
+	{0, ETHTOOL_LINK_MODE_56000baseKR4_Full_BIT, 56000, DUPLEX_FULL, "KR4", "56000baseKR4/Full"},
+	{0, ETHTOOL_LINK_MODE_56000baseCR4_Full_BIT, 56000, DUPLEX_FULL, "CR4", "56000baseCR4/Full"},
+	{0, ETHTOOL_LINK_MODE_56000baseSR4_Full_BIT, 56000, DUPLEX_FULL, "SR4", "56000baseSR4/Full"},
+	{0, ETHTOOL_LINK_MODE_56000baseLR4_Full_BIT, 56000, DUPLEX_FULL, "LR4", "56000baseLR4/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseKR4_Full_BIT, 100000, DUPLEX_FULL, "KR4", "100000baseKR4/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseSR4_Full_BIT, 100000, DUPLEX_FULL, "SR4", "100000baseSR4/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseCR4_Full_BIT, 100000, DUPLEX_FULL, "CR4", "100000baseCR4/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseLR4_ER4_Full_BIT, 100000, DUPLEX_FULL, "LR4_ER4", "100000baseLR4_ER4/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseKR2_Full_BIT, 100000, DUPLEX_FULL, "KR2", "100000baseKR2/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseSR2_Full_BIT, 100000, DUPLEX_FULL, "SR2", "100000baseSR2/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseCR2_Full_BIT, 100000, DUPLEX_FULL, "CR2", "100000baseCR2/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseLR2_ER2_FR2_Full_BIT, 100000, DUPLEX_FULL, "LR2_ER2_FR2", "100000baseLR2_ER2_FR2/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseDR2_Full_BIT, 100000, DUPLEX_FULL, "DR2", "100000baseDR2/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseKR_Full_BIT, 100000, DUPLEX_FULL, "KR", "100000baseKR/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseSR_Full_BIT, 100000, DUPLEX_FULL, "SR", "100000baseSR/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseLR_ER_FR_Full_BIT, 100000, DUPLEX_FULL, "LR_ER_FR", "100000baseLR_ER_FR/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseCR_Full_BIT, 100000, DUPLEX_FULL, "CR", "100000baseCR/Full"},
+	{0, ETHTOOL_LINK_MODE_100000baseDR_Full_BIT, 100000, DUPLEX_FULL, "DR", "100000baseDR/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseKR4_Full_BIT, 200000, DUPLEX_FULL, "KR4", "200000baseKR4/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseSR4_Full_BIT, 200000, DUPLEX_FULL, "SR4", "200000baseSR4/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseLR4_ER4_FR4_Full_BIT, 200000, DUPLEX_FULL, "LR4_ER4_FR4", "200000baseLR4_ER4_FR4/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseDR4_Full_BIT, 200000, DUPLEX_FULL, "DR4", "200000baseDR4/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseCR4_Full_BIT, 200000, DUPLEX_FULL, "CR4", "200000baseCR4/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseKR2_Full_BIT, 200000, DUPLEX_FULL, "KR2", "200000baseKR2/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseSR2_Full_BIT, 200000, DUPLEX_FULL, "SR2", "200000baseSR2/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseLR2_ER2_FR2_Full_BIT, 200000, DUPLEX_FULL, "LR2_ER2_FR2", "200000baseLR2_ER2_FR2/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseDR2_Full_BIT, 200000, DUPLEX_FULL, "DR2", "200000baseDR2/Full"},
+	{0, ETHTOOL_LINK_MODE_200000baseCR2_Full_BIT, 200000, DUPLEX_FULL, "CR2", "200000baseCR2/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseKR8_Full_BIT, 400000, DUPLEX_FULL, "KR8", "400000baseKR8/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseSR8_Full_BIT, 400000, DUPLEX_FULL, "SR8", "400000baseSR8/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseLR8_ER8_FR8_Full_BIT, 400000, DUPLEX_FULL, "LR8_ER8_FR8", "400000baseLR8_ER8_FR8/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseDR8_Full_BIT, 400000, DUPLEX_FULL, "DR8", "400000baseDR8/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseCR8_Full_BIT, 400000, DUPLEX_FULL, "CR8", "400000baseCR8/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseKR4_Full_BIT, 400000, DUPLEX_FULL, "KR4", "400000baseKR4/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseSR4_Full_BIT, 400000, DUPLEX_FULL, "SR4", "400000baseSR4/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseLR4_ER4_FR4_Full_BIT, 400000, DUPLEX_FULL, "LR4_ER4_FR4", "400000baseLR4_ER4_FR4/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseDR4_Full_BIT, 400000, DUPLEX_FULL, "DR4", "400000baseDR4/Full"},
+	{0, ETHTOOL_LINK_MODE_400000baseCR4_Full_BIT, 400000, DUPLEX_FULL, "CR4", "400000baseCR4/Full"},
+	{0, ETHTOOL_LINK_MODE_100baseFX_Half_BIT, 100, DUPLEX_HALF, "FX", "100baseFX/Half"},
+	{1, ETHTOOL_LINK_MODE_100baseFX_Full_BIT, 100, DUPLEX_FULL, "FX", "100baseFX/Full"},
+}
+
+/* link modes that are not a speed/duplex combination, named as the
+ * kernel names them in the ETH_SS_LINK_MODES string set
+ */
+var link_mode_flag_defs = []struct {
+	bit_index uint32
+	name      string
+}{
+	{ETHTOOL_LINK_MODE_Autoneg_BIT, "Autoneg"},
+	{ETHTOOL_LINK_MODE_TP_BIT, "TP"},
+	{ETHTOOL_LINK_MODE_AUI_BIT, "AUI"},
+	{ETHTOOL_LINK_MODE_MII_BIT, "MII"},
+	{ETHTOOL_LINK_MODE_FIBRE_BIT, "FIBRE"},
+	{ETHTOOL_LINK_MODE_BNC_BIT, "BNC"},
+	{ETHTOOL_LINK_MODE_Pause_BIT, "Pause"},
+	{ETHTOOL_LINK_MODE_Asym_Pause_BIT, "Asym_Pause"},
+	{ETHTOOL_LINK_MODE_Backplane_BIT, "Backplane"},
+	{ETHTOOL_LINK_MODE_FEC_NONE_BIT, "None"},
+	{ETHTOOL_LINK_MODE_FEC_RS_BIT, "RS"},
+	{ETHTOOL_LINK_MODE_FEC_BASER_BIT, "BASER"},
+	{ETHTOOL_LINK_MODE_FEC_LLRS_BIT, "LLRS"},
+}
+
+func link_mode_test_bit(nr uint32, mask []uint32) bool {
+	if nr >= __ETHTOOL_LINK_MODE_MASK_NBITS || int(nr/32) >= len(mask) {
+		return false
+	}
+	return mask[nr/32]&(1<<(nr%32)) != 0
+}
+
+func link_mode_is_empty(mask []uint32) bool {
+	for i := 0; i < len(mask); i++ {
+		if mask[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+/* all_advertised_modes has the bits of every named speed/duplex mode */
+func all_advertised_modes() []uint32 {
+	mask := make([]uint32, ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32)
+	for _, def := range link_mode_defs {
+		link_mode_set_bit(def.bit_index, mask)
+	}
+	return mask
+}
+
+/* link_mode_mask_str lists the names of the modes set in mask */
+func link_mode_mask_str(mask []uint32) string {
+	var names []string
+	for _, def := range link_mode_defs {
+		if link_mode_test_bit(def.bit_index, mask) {
+			names = append(names, def.name)
+		}
+	}
+	for _, def := range link_mode_flag_defs {
+		if link_mode_test_bit(def.bit_index, mask) {
+			names = append(names, def.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, " ")
+}
+
+func link_mode_set_bit(nr uint32, mask []uint32) {
+	mask[nr/32] |= 1 << (nr % 32)
+}
+
+/* link_mode_by_name looks up a link mode bit by its name, e.g.
+ * "10000baseT/Full" or "Pause". Case is ignored.
+ */
+func link_mode_by_name(name string) (uint32, bool) {
+	for _, def := range link_mode_defs {
+		if strings.EqualFold(def.name, name) {
+			return def.bit_index, true
+		}
+	}
+	for _, def := range link_mode_flag_defs {
+		if strings.EqualFold(def.name, name) {
+			return def.bit_index, true
+		}
+	}
+	return 0, false
+}
+
+/* link_modes_matching returns the modes of mask with the given speed and
+ * duplex; -1 matches any speed or duplex.
+ */
+func link_modes_matching(mask []uint32, speed int, duplex int) []uint32 {
+	match := make([]uint32, ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32)
+	for _, def := range link_mode_defs {
+		if def.speed == 0 || !link_mode_test_bit(def.bit_index, mask) {
+			continue
+		}
+		if speed >= 0 && def.speed != uint32(speed) {
+			continue
+		}
+		if duplex >= 0 && def.duplex != uint8(duplex) {
+			continue
+		}
+		link_mode_set_bit(def.bit_index, match)
+	}
+	return match
+}
+
+/* link_mode_common finds the best mode advertised by both ends of the
+ * link: the highest speed wins, full duplex beats half duplex at the
+ * same speed.
+ */
+func link_mode_common(local []uint32, partner []uint32) *link_mode_def {
+	var best *link_mode_def
+	for i := range link_mode_defs {
+		def := &link_mode_defs[i]
+		if def.speed == 0 || !link_mode_test_bit(def.bit_index, local) ||
+			!link_mode_test_bit(def.bit_index, partner) {
+			continue
+		}
+		if best == nil || def.speed > best.speed ||
+			(def.speed == best.speed && def.duplex == DUPLEX_FULL &&
+				best.duplex != DUPLEX_FULL) {
+			best = def
+		}
+	}
+	return best
+}
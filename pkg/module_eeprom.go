@@ -0,0 +1,405 @@
+package ethtool
+
+import (
+	"os"
+	"syscall"
+)
+
+/*
+ * Paged access to module memory. ETHTOOL_MSG_MODULE_EEPROM_GET reads at
+ * most one half page at a time: offsets 0-127 address the lower page,
+ * offsets 128-255 the upper page selected by page and bank.
+ */
+
+const (
+	ETH_I2C_ADDRESS_LOW  = 0x50
+	ETH_I2C_ADDRESS_HIGH = 0x51
+	ETH_I2C_MAX_ADDRESS  = 0x7f
+
+	ETH_MODULE_EEPROM_PAGE_LEN = 128
+)
+
+type module_eeprom struct {
+	offset      uint32
+	length      uint32
+	page        uint8
+	bank        uint8
+	i2c_address uint8
+	data        []byte
+}
+
+/* module_reader fills req.data with the module memory req describes */
+type module_reader func(req *module_eeprom) error
+
+func nl_get_eeprom_page(ctx *cmd_context, req *module_eeprom) error {
+	m := ethnl_msg(ctx, ETHTOOL_MSG_MODULE_EEPROM_GET,
+		ETHTOOL_A_MODULE_EEPROM_HEADER, 0)
+	m.put_u32(ETHTOOL_A_MODULE_EEPROM_OFFSET, req.offset)
+	m.put_u32(ETHTOOL_A_MODULE_EEPROM_LENGTH, req.length)
+	m.put_u8(ETHTOOL_A_MODULE_EEPROM_PAGE, req.page)
+	m.put_u8(ETHTOOL_A_MODULE_EEPROM_BANK, req.bank)
+	m.put_u8(ETHTOOL_A_MODULE_EEPROM_I2C_ADDRESS, req.i2c_address)
+
+	replies, err := nl_request(ctx.nlctx, m)
+	if err != nil {
+		return err
+	}
+	if len(replies) == 0 {
+		return syscall.ENODATA
+	}
+	tb := nl_attr_table(replies[0], ETHTOOL_A_MODULE_EEPROM_MAX)
+	if tb[ETHTOOL_A_MODULE_EEPROM_DATA] == nil {
+		return syscall.ENODATA
+	}
+	req.data = append([]byte(nil), tb[ETHTOOL_A_MODULE_EEPROM_DATA]...)
+	return nil
+}
+
+/* nl_module_reader caches the pages read from the kernel, decoders
+ * tend to look at the same page more than once.
+ */
+func nl_module_reader(ctx *cmd_context) module_reader {
+	cache := map[[5]uint32][]byte{}
+
+	return func(req *module_eeprom) error {
+		key := [5]uint32{req.offset, req.length, uint32(req.page),
+			uint32(req.bank), uint32(req.i2c_address)}
+		if data, ok := cache[key]; ok {
+			req.data = data
+			return nil
+		}
+		err := nl_get_eeprom_page(ctx, req)
+		if err != nil {
+			return err
+		}
+		cache[key] = req.data
+		return nil
+	}
+}
+
+/* module_lower_page returns the 128 bytes of the lower page */
+func module_lower_page(read module_reader, i2c uint8) ([]byte, error) {
+	req := module_eeprom{
+		offset:      0,
+		length:      ETH_MODULE_EEPROM_PAGE_LEN,
+		i2c_address: i2c,
+	}
+	err := read(&req)
+	if err != nil {
+		return nil, err
+	}
+	page := make([]byte, ETH_MODULE_EEPROM_PAGE_LEN)
+	copy(page, req.data)
+	return page, nil
+}
+
+/* module_upper_page returns a 256 byte buffer with the upper page at
+ * offsets 128-255, so that decoders can index it like the specs do.
+ */
+func module_upper_page(read module_reader, i2c uint8, page uint8,
+	bank uint8) ([]byte, error) {
+	req := module_eeprom{
+		offset:      ETH_MODULE_EEPROM_PAGE_LEN,
+		length:      ETH_MODULE_EEPROM_PAGE_LEN,
+		page:        page,
+		bank:        bank,
+		i2c_address: i2c,
+	}
+	err := read(&req)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 2*ETH_MODULE_EEPROM_PAGE_LEN)
+	copy(buf[ETH_MODULE_EEPROM_PAGE_LEN:], req.data)
+	return buf, nil
+}
+
+/* module_read_range reads the memory params selects. The kernel does not
+ * read across the lower/upper page boundary, so do it in two steps.
+ */
+func module_read_range(read module_reader, params *getmodule_params) ([]byte, error) {
+	var data []byte
+
+	offset := params.offset
+	end := params.offset + params.length
+	for offset < end {
+		chunk_end := end
+		if offset < ETH_MODULE_EEPROM_PAGE_LEN &&
+			end > ETH_MODULE_EEPROM_PAGE_LEN {
+			chunk_end = ETH_MODULE_EEPROM_PAGE_LEN
+		}
+		req := module_eeprom{
+			offset:      offset,
+			length:      chunk_end - offset,
+			page:        params.page,
+			bank:        params.bank,
+			i2c_address: params.i2c_address,
+		}
+		err := read(&req)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, req.data...)
+		offset = chunk_end
+	}
+	return data, nil
+}
+
+/* sff8079_read rebuilds the blob ETHTOOL_GMODULEEEPROM returns for SFP
+ * modules: the A0 page, followed by the A2 page if the module has one.
+ */
+func sff8079_read(read module_reader) ([]byte, error) {
+	id := make([]byte, ETH_MODULE_SFF_8472_LEN)
+
+	for i, i2c := range []uint8{ETH_I2C_ADDRESS_LOW, ETH_I2C_ADDRESS_HIGH} {
+		lower, err := module_lower_page(read, i2c)
+		if err != nil {
+			return nil, err
+		}
+		upper, err := module_upper_page(read, i2c, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		copy(id[i*256:], lower)
+		copy(id[i*256+ETH_MODULE_EEPROM_PAGE_LEN:],
+			upper[ETH_MODULE_EEPROM_PAGE_LEN:])
+
+		/* The A2 page only exists on modules with diagnostics */
+		if id[SFF_A0_DOM]&SFF_A0_DOM_IMPL == 0 {
+			break
+		}
+	}
+	return id, nil
+}
+
+func sff8636_read(read module_reader) (*sff8636_memory_map, error) {
+	var m sff8636_memory_map
+	var err error
+
+	m.lower_memory, err = module_lower_page(read, ETH_I2C_ADDRESS_LOW)
+	if err != nil {
+		return nil, err
+	}
+	m.page_00h, err = module_upper_page(read, ETH_I2C_ADDRESS_LOW, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	/* Flat memory modules only have page 00h, and thresholds are
+	 * optional: decode what we have if page 03h cannot be read.
+	 */
+	if m.lower_memory[SFF8636_STATUS_2_OFFSET]&SFF8636_STATUS_FLAT_MEM == 0 {
+		m.page_03h, _ = module_upper_page(read, ETH_I2C_ADDRESS_LOW, 3, 0)
+	}
+	return &m, nil
+}
+
+/* sff8079_decode only shows the A0 page, for images saved without A2 */
+func sff8079_decode(read module_reader) error {
+	id := make([]byte, ETH_MODULE_SFF_8472_LEN)
+
+	lower, err := module_lower_page(read, ETH_I2C_ADDRESS_LOW)
+	if err != nil {
+		return err
+	}
+	upper, err := module_upper_page(read, ETH_I2C_ADDRESS_LOW, 0, 0)
+	if err != nil {
+		return err
+	}
+	copy(id, lower)
+	copy(id[ETH_MODULE_EEPROM_PAGE_LEN:], upper[ETH_MODULE_EEPROM_PAGE_LEN:])
+	sff8079_show_all(id)
+	return nil
+}
+
+func sff8472_decode(read module_reader) error {
+	id, err := sff8079_read(read)
+	if err != nil {
+		return err
+	}
+	sff8079_show_all(id)
+	sff8472_show_all(id)
+	return nil
+}
+
+func sff8636_decode(read module_reader) error {
+	m, err := sff8636_read(read)
+	if err != nil {
+		return err
+	}
+	sff8636_show_all_common(m)
+	return nil
+}
+
+func cmis_decode(read module_reader) error {
+	m, err := cmis_read(read)
+	if err != nil {
+		return err
+	}
+	cmis_show_all(m)
+	return nil
+}
+
+/* module_decoders maps the names accepted by --decode-module-file type to
+ * the decoders, SFF-8436 is decoded as its SFF-8636 successor.
+ */
+var module_decoders = map[string]func(read module_reader) error{
+	"sff8079": sff8079_decode,
+	"sff8472": sff8472_decode,
+	"sff8436": sff8636_decode,
+	"sff8636": sff8636_decode,
+	"cmis":    cmis_decode,
+}
+
+/* module_decoder_name picks the decoder for an SFF-8024 identifier, or
+ * returns "" if there is none.
+ */
+func module_decoder_name(id uint8) string {
+	switch id {
+	case SFF8024_ID_GBIC, SFF8024_ID_SOLDERED_MODULE, SFF8024_ID_SFP:
+		return "sff8472"
+	case SFF8024_ID_QSFP, SFF8024_ID_QSFP_PLUS, SFF8024_ID_QSFP28:
+		return "sff8636"
+	case SFF8024_ID_QSFP_DD, SFF8024_ID_OSFP, SFF8024_ID_DSFP,
+		SFF8024_ID_QSFP_PLUS_CMIS, SFF8024_ID_SFP_DD_CMIS,
+		SFF8024_ID_SFP_PLUS_CMIS:
+		return "cmis"
+	}
+	return ""
+}
+
+/* module_show_all decodes module memory according to its SFF-8024
+ * identifier, reading whatever pages the decoder needs.
+ */
+func module_show_all(read module_reader) error {
+	lower, err := module_lower_page(read, ETH_I2C_ADDRESS_LOW)
+	if err != nil {
+		return err
+	}
+
+	name := module_decoder_name(lower[SFF8024_ID_OFFSET])
+	if name == "" {
+		dump_hex(os.Stdout, lower, uint32(len(lower)), 0)
+		return nil
+	}
+	return module_decoders[name](read)
+}
+
+/*
+ * Module EEPROM images, as -m raw on saves them: the lower page, followed
+ * by the upper pages of bank 0 in the order module_image_pages lists for
+ * the decoder. Memory at i2c address 0x51 (the SFF-8472 A2 page) comes
+ * right after those. SFP and QSFP images are the ETHTOOL_GMODULEEEPROM
+ * blob, CMIS images are read page by page over netlink, see
+ * module_read_image, and hold page 00h only for flat memory modules.
+ */
+var module_image_pages = map[string][]uint8{
+	"sff8079": {0x00},
+	"sff8472": {0x00},
+	"sff8436": {0x00, 0x01, 0x02, 0x03},
+	"sff8636": {0x00, 0x01, 0x02, 0x03},
+	"cmis":    {0x00, 0x01, 0x02, 0x10, 0x11},
+}
+
+/* Drivers that report a CMIS module as SFF-8636 return its memory from
+ * ETHTOOL_GMODULEEEPROM in the SFF-8636 layout, upper pages 00h-03h, not
+ * in the one -m raw on saves CMIS images with over netlink. Raw dumps
+ * taken through the ioctl are therefore 640 bytes in that layout.
+ */
+var module_cmis_ioctl_pages = []uint8{0x00, 0x01, 0x02, 0x03}
+
+/* module_read_image reads the lower page and the given upper pages of
+ * bank 0 into an image laid out as described above.
+ */
+func module_read_image(read module_reader, pages []uint8) ([]byte, error) {
+	image, err := module_lower_page(read, ETH_I2C_ADDRESS_LOW)
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		buf, err := module_upper_page(read, ETH_I2C_ADDRESS_LOW, page, 0)
+		if err != nil {
+			return nil, err
+		}
+		image = append(image, buf[ETH_MODULE_EEPROM_PAGE_LEN:]...)
+	}
+	return image, nil
+}
+
+/* cmis_read_image reads the image -m raw on saves for a CMIS module */
+func cmis_read_image(read module_reader, lower []byte) ([]byte, error) {
+	pages := module_image_pages["cmis"]
+	if lower[CMIS_MEMORY_MODEL_OFFSET]&CMIS_MEMORY_MODEL_MASK != 0 {
+		pages = pages[:1]
+	}
+	return module_read_image(read, pages)
+}
+
+/* image_module_reader serves reads from an image laid out as described
+ * above, pages the image is too short to hold read as ENODATA.
+ */
+func image_module_reader(image []byte, pages []uint8) module_reader {
+	return func(req *module_eeprom) error {
+		base := uint32(0)
+		switch req.i2c_address {
+		case ETH_I2C_ADDRESS_LOW:
+		case ETH_I2C_ADDRESS_HIGH:
+			base = uint32(len(pages)+1) * ETH_MODULE_EEPROM_PAGE_LEN
+		default:
+			return syscall.ENODATA
+		}
+		if req.offset >= ETH_MODULE_EEPROM_PAGE_LEN {
+			idx := -1
+			for i, page := range pages {
+				if page == req.page {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 || req.bank != 0 {
+				return syscall.ENODATA
+			}
+			base += uint32(idx) * ETH_MODULE_EEPROM_PAGE_LEN
+		}
+		start := uint64(base) + uint64(req.offset)
+		end := start + uint64(req.length)
+		if end > uint64(len(image)) {
+			return syscall.ENODATA
+		}
+		req.data = image[start:end]
+		return nil
+	}
+}
+
+/* module_decode_image runs the named decoder over a saved image, or the
+ * one its identifier byte asks for if name is empty.
+ */
+func module_decode_image(image []byte, name string) error {
+	if len(image) == 0 {
+		return syscall.ENODATA
+	}
+	if name == "" {
+		name = module_decoder_name(image[SFF8024_ID_OFFSET])
+		/* SFP images carry A2 only when saved as SFF-8472 */
+		if name == "sff8472" &&
+			len(image) < ETH_MODULE_SFF_8472_LEN {
+			name = "sff8079"
+		}
+	}
+	decode, ok := module_decoders[name]
+	if !ok {
+		dump_hex(os.Stdout, image, uint32(len(image)), 0)
+		return nil
+	}
+	return decode(module_image_reader(image, name))
+}
+
+/* module_image_reader serves reads from an image saved for the named
+ * decoder, telling CMIS images dumped through the ioctl by their size.
+ */
+func module_image_reader(image []byte, name string) module_reader {
+	if name == "cmis" && len(image) == ETH_MODULE_SFF_8636_MAX_LEN {
+		return image_module_reader(image, module_cmis_ioctl_pages)
+	}
+	return image_module_reader(image, module_image_pages[name])
+}
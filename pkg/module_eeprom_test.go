@@ -0,0 +1,327 @@
+package ethtool
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+)
+
+/* module_test_image returns an image of size bytes with every byte set to
+ * the number of the 128 byte block it is in.
+ */
+func module_test_image(size int) []byte {
+	image := make([]byte, size)
+	for i := range image {
+		image[i] = byte(i / ETH_MODULE_EEPROM_PAGE_LEN)
+	}
+	return image
+}
+
+func TestImageModuleReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		pages  []uint8
+		req    module_eeprom
+		offset int /* where the data starts in the image, -1 for ENODATA */
+	}{
+		{"sfp lower", 256, module_image_pages["sff8079"],
+			module_eeprom{offset: 0, length: 128, i2c_address: 0x50}, 0},
+		{"sfp upper", 256, module_image_pages["sff8079"],
+			module_eeprom{offset: 128, length: 128, i2c_address: 0x50}, 128},
+		{"sfp a2 missing", 256, module_image_pages["sff8079"],
+			module_eeprom{offset: 0, length: 128, i2c_address: 0x51}, -1},
+		{"sfp a2 lower", 512, module_image_pages["sff8472"],
+			module_eeprom{offset: 0, length: 128, i2c_address: 0x51}, 256},
+		{"sfp a2 upper", 512, module_image_pages["sff8472"],
+			module_eeprom{offset: 128, length: 128, i2c_address: 0x51}, 384},
+		{"sfp a2 part", 512, module_image_pages["sff8472"],
+			module_eeprom{offset: 96, length: 10, i2c_address: 0x51}, 352},
+		{"sfp page 1", 512, module_image_pages["sff8472"],
+			module_eeprom{offset: 128, length: 128, page: 1, i2c_address: 0x50}, -1},
+		{"sfp bad address", 512, module_image_pages["sff8472"],
+			module_eeprom{offset: 0, length: 128, i2c_address: 0x52}, -1},
+		{"qsfp page 0", 256, module_image_pages["sff8636"],
+			module_eeprom{offset: 128, length: 128, i2c_address: 0x50}, 128},
+		{"qsfp page 3 missing", 256, module_image_pages["sff8636"],
+			module_eeprom{offset: 128, length: 128, page: 3, i2c_address: 0x50}, -1},
+		{"qsfp page 1", 640, module_image_pages["sff8636"],
+			module_eeprom{offset: 128, length: 128, page: 1, i2c_address: 0x50}, 256},
+		{"qsfp page 3", 640, module_image_pages["sff8636"],
+			module_eeprom{offset: 128, length: 128, page: 3, i2c_address: 0x50}, 512},
+		{"qsfp page 3 part", 640, module_image_pages["sff8636"],
+			module_eeprom{offset: 200, length: 16, page: 3, i2c_address: 0x50}, 584},
+		{"qsfp lower ignores page", 640, module_image_pages["sff8636"],
+			module_eeprom{offset: 0, length: 128, page: 3, i2c_address: 0x50}, 0},
+		{"qsfp page 4", 640, module_image_pages["sff8636"],
+			module_eeprom{offset: 128, length: 128, page: 4, i2c_address: 0x50}, -1},
+		{"qsfp bank 1", 640, module_image_pages["sff8636"],
+			module_eeprom{offset: 128, length: 128, bank: 1, i2c_address: 0x50}, -1},
+		{"qsfp a2", 640, module_image_pages["sff8636"],
+			module_eeprom{offset: 0, length: 128, i2c_address: 0x51}, -1},
+		{"cmis page 2", 768, module_image_pages["cmis"],
+			module_eeprom{offset: 128, length: 128, page: 2, i2c_address: 0x50}, 384},
+		{"cmis page 10h", 768, module_image_pages["cmis"],
+			module_eeprom{offset: 128, length: 128, page: 0x10, i2c_address: 0x50}, 512},
+		{"cmis page 11h", 768, module_image_pages["cmis"],
+			module_eeprom{offset: 128, length: 128, page: 0x11, i2c_address: 0x50}, 640},
+		{"cmis page 11h missing", 640, module_image_pages["cmis"],
+			module_eeprom{offset: 128, length: 128, page: 0x11, i2c_address: 0x50}, -1},
+		{"cmis page 3", 768, module_image_pages["cmis"],
+			module_eeprom{offset: 128, length: 128, page: 3, i2c_address: 0x50}, -1},
+		{"cmis bank 1", 768, module_image_pages["cmis"],
+			module_eeprom{offset: 128, length: 128, page: 0x10, bank: 1, i2c_address: 0x50}, -1},
+		{"cmis ioctl page 3", 640, module_cmis_ioctl_pages,
+			module_eeprom{offset: 128, length: 128, page: 3, i2c_address: 0x50}, 512},
+		{"cmis ioctl page 10h", 640, module_cmis_ioctl_pages,
+			module_eeprom{offset: 128, length: 128, page: 0x10, i2c_address: 0x50}, -1},
+	}
+
+	for _, tt := range tests {
+		image := module_test_image(tt.size)
+		req := tt.req
+		err := image_module_reader(image, tt.pages)(&req)
+		if tt.offset < 0 {
+			if err != syscall.ENODATA {
+				t.Errorf("%s: got %v, want ENODATA", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(req.data) != int(tt.req.length) {
+			t.Errorf("%s: read %d bytes, want %d", tt.name,
+				len(req.data), tt.req.length)
+			continue
+		}
+		if &req.data[0] != &image[tt.offset] {
+			t.Errorf("%s: read from block %d, want offset %d (block %d)",
+				tt.name, req.data[0], tt.offset,
+				tt.offset/ETH_MODULE_EEPROM_PAGE_LEN)
+		}
+	}
+}
+
+/* module_test_silence sends what the decoders print to /dev/null until
+ * the returned function is called.
+ */
+func module_test_silence(t *testing.T) func() {
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = null
+	return func() {
+		os.Stdout = stdout
+		null.Close()
+	}
+}
+
+/* synthetic images of each module type, paged memory with every optional
+ * page advertised so that the decoders look for as much as they can.
+ */
+func module_test_images() map[string][]byte {
+	fill := func(size int, id byte) []byte {
+		image := make([]byte, size)
+		for i := range image {
+			image[i] = byte(i*7 + 3)
+		}
+		image[SFF8024_ID_OFFSET] = id
+		return image
+	}
+
+	sfp := fill(ETH_MODULE_SFF_8472_LEN, SFF8024_ID_SFP)
+	sfp[SFF_A0_DOM] |= SFF_A0_DOM_IMPL
+
+	qsfp := fill(ETH_MODULE_SFF_8636_MAX_LEN, SFF8024_ID_QSFP28)
+	qsfp[SFF8636_STATUS_2_OFFSET] &^= SFF8636_STATUS_FLAT_MEM
+
+	cmis := fill((len(module_image_pages["cmis"])+1)*ETH_MODULE_EEPROM_PAGE_LEN,
+		SFF8024_ID_QSFP_DD)
+	cmis[CMIS_MEMORY_MODEL_OFFSET] &^= CMIS_MEMORY_MODEL_MASK
+	/* page 01h starts at 256, its byte at offset N is at 128 + N */
+	p01 := ETH_MODULE_EEPROM_PAGE_LEN
+	cmis[p01+CMIS_PAGES_ADVER_OFFSET] |= CMIS_PAGES_ADVER_VDM | 0x02
+
+	return map[string][]byte{
+		"sff8079": sfp[:ETH_MODULE_SFF_8079_LEN],
+		"sff8472": sfp,
+		"sff8436": qsfp,
+		"sff8636": qsfp,
+		"cmis":    cmis,
+	}
+}
+
+func TestModuleDecodeImage(t *testing.T) {
+	defer module_test_silence(t)()
+
+	for name, image := range module_test_images() {
+		if err := module_decode_image(image, name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if err := module_decode_image(image, ""); err != nil {
+			t.Errorf("%s by identifier: %v", name, err)
+		}
+	}
+}
+
+func TestModuleDecodeImageShort(t *testing.T) {
+	defer module_test_silence(t)()
+
+	if err := module_decode_image(nil, ""); err != syscall.ENODATA {
+		t.Errorf("empty image: got %v, want ENODATA", err)
+	}
+
+	images := module_test_images()
+	tests := []struct {
+		name string
+		size int
+	}{
+		/* no complete lower page */
+		{"sff8079", 100},
+		{"sff8472", 127},
+		{"sff8636", 64},
+		{"cmis", 1},
+		/* no upper page 00h */
+		{"sff8079", 200},
+		{"sff8636", 255},
+		{"cmis", 128},
+		/* diagnostics advertised but no A2 page */
+		{"sff8472", ETH_MODULE_SFF_8079_LEN},
+		{"sff8472", ETH_MODULE_SFF_8472_LEN - 1},
+	}
+	for _, tt := range tests {
+		err := module_decode_image(images[tt.name][:tt.size], tt.name)
+		if err != syscall.ENODATA {
+			t.Errorf("%s of %d bytes: got %v, want ENODATA",
+				tt.name, tt.size, err)
+		}
+	}
+}
+
+func TestModuleDecodeImageTruncated(t *testing.T) {
+	defer module_test_silence(t)()
+
+	for name, image := range module_test_images() {
+		for size := 1; size <= len(image); size++ {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("%s of %d bytes: panic: %v",
+							name, size, r)
+					}
+				}()
+				module_decode_image(image[:size], name)
+				module_decode_image(image[:size], "")
+			}()
+		}
+	}
+
+	/* CMIS modules read by ioctl as SFF-8636 */
+	cmis := module_test_images()["cmis"]
+	for size := 1; size <= ETH_MODULE_SFF_8636_MAX_LEN; size++ {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("cmis ioctl of %d bytes: panic: %v",
+						size, r)
+				}
+			}()
+			cmis_decode(image_module_reader(cmis[:size],
+				module_cmis_ioctl_pages))
+		}()
+	}
+}
+
+/* module_test_module returns a reader over a CMIS module with more pages
+ * than any image holds, each page filled with its number.
+ */
+func module_test_module(flat bool) (module_reader, []byte) {
+	pages := []uint8{0x00, 0x01, 0x02, 0x03, 0x10, 0x11, 0x20}
+	module := make([]byte, ETH_MODULE_EEPROM_PAGE_LEN)
+	module[SFF8024_ID_OFFSET] = SFF8024_ID_QSFP_DD
+	if flat {
+		module[CMIS_MEMORY_MODEL_OFFSET] |= CMIS_MEMORY_MODEL_MASK
+	}
+	for _, page := range pages {
+		module = append(module,
+			bytes.Repeat([]byte{page}, ETH_MODULE_EEPROM_PAGE_LEN)...)
+	}
+	return image_module_reader(module, pages), module[:ETH_MODULE_EEPROM_PAGE_LEN]
+}
+
+func TestModuleImageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		flat    bool
+		ioctl   bool
+		size    int
+		present []uint8
+		missing []uint8
+	}{
+		{"cmis", false, false, 768,
+			[]uint8{0x00, 0x01, 0x02, 0x10, 0x11}, []uint8{0x03, 0x20}},
+		{"cmis flat", true, false, 256,
+			[]uint8{0x00}, []uint8{0x01, 0x10}},
+		{"cmis ioctl", false, true, ETH_MODULE_SFF_8636_MAX_LEN,
+			[]uint8{0x00, 0x01, 0x02, 0x03}, []uint8{0x10, 0x11}},
+	}
+
+	for _, tt := range tests {
+		module, lower := module_test_module(tt.flat)
+		var image []byte
+		var err error
+		if tt.ioctl {
+			image, err = module_read_image(module, module_cmis_ioctl_pages)
+		} else {
+			image, err = cmis_read_image(module, lower)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(image) != tt.size {
+			t.Errorf("%s: image of %d bytes, want %d", tt.name,
+				len(image), tt.size)
+			continue
+		}
+
+		read := module_image_reader(image, "cmis")
+		got, err := module_lower_page(read, ETH_I2C_ADDRESS_LOW)
+		if err != nil || !bytes.Equal(got, lower) {
+			t.Errorf("%s: lower page %v, %v", tt.name, got, err)
+		}
+		for _, page := range tt.present {
+			want, _ := module_upper_page(module, ETH_I2C_ADDRESS_LOW, page, 0)
+			got, err := module_upper_page(read, ETH_I2C_ADDRESS_LOW, page, 0)
+			if err != nil {
+				t.Errorf("%s: page %02xh: %v", tt.name, page, err)
+			} else if !bytes.Equal(got, want) {
+				t.Errorf("%s: page %02xh reads as page %02xh", tt.name,
+					page, got[ETH_MODULE_EEPROM_PAGE_LEN])
+			}
+		}
+		for _, page := range tt.missing {
+			_, err := module_upper_page(read, ETH_I2C_ADDRESS_LOW, page, 0)
+			if err != syscall.ENODATA {
+				t.Errorf("%s: page %02xh: got %v, want ENODATA",
+					tt.name, page, err)
+			}
+		}
+	}
+
+	/* a page the module cannot read fails the capture */
+	module, lower := module_test_module(false)
+	broken := func(req *module_eeprom) error {
+		if req.page == 0x11 {
+			return syscall.EIO
+		}
+		return module(req)
+	}
+	if _, err := cmis_read_image(broken, lower); err != syscall.EIO {
+		t.Errorf("unreadable page 11h: got %v, want EIO", err)
+	}
+}
@@ -1,9 +1,584 @@
 package ethtool
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"sync"
+	"syscall"
+)
 
-func nl_monitor_usage() {
-	fmt.Printf("        ethtool --monitor               Show kernel notifications\n")
-	fmt.Printf("                ( [ --all ]")
+const (
+	NETLINK_ADD_MEMBERSHIP = 1
 
+	/* how often the receive loop checks whether the monitor was closed */
+	MONITOR_POLL_USEC = 200000
+)
+
+// Notification types of Event.Type, one per kernel ETHTOOL_MSG_*_NTF.
+const (
+	EventLinkInfo     = "linkinfo"
+	EventLinkModes    = "linkmodes"
+	EventDebug        = "debug"
+	EventWoL          = "wol"
+	EventFeatures     = "features"
+	EventPrivFlags    = "privflags"
+	EventRings        = "rings"
+	EventChannels     = "channels"
+	EventCoalesce     = "coalesce"
+	EventPause        = "pause"
+	EventEEE          = "eee"
+	EventCableTest    = "cable-test"
+	EventCableTestTDR = "cable-test-tdr"
+	EventFEC          = "fec"
+)
+
+var monitor_events = map[uint8]string{
+	ETHTOOL_MSG_LINKINFO_NTF:       EventLinkInfo,
+	ETHTOOL_MSG_LINKMODES_NTF:      EventLinkModes,
+	ETHTOOL_MSG_DEBUG_NTF:          EventDebug,
+	ETHTOOL_MSG_WOL_NTF:            EventWoL,
+	ETHTOOL_MSG_FEATURES_NTF:       EventFeatures,
+	ETHTOOL_MSG_PRIVFLAGS_NTF:      EventPrivFlags,
+	ETHTOOL_MSG_RINGS_NTF:          EventRings,
+	ETHTOOL_MSG_CHANNELS_NTF:       EventChannels,
+	ETHTOOL_MSG_COALESCE_NTF:       EventCoalesce,
+	ETHTOOL_MSG_PAUSE_NTF:          EventPause,
+	ETHTOOL_MSG_EEE_NTF:            EventEEE,
+	ETHTOOL_MSG_CABLE_TEST_NTF:     EventCableTest,
+	ETHTOOL_MSG_CABLE_TEST_TDR_NTF: EventCableTestTDR,
+	ETHTOOL_MSG_FEC_NTF:            EventFEC,
+}
+
+/* command line options of --monitor and the notifications they select */
+var monitor_opts = []struct {
+	names  []string
+	events []string
+}{
+	{[]string{"change"}, []string{EventLinkInfo, EventLinkModes, EventDebug, EventWoL}},
+	{[]string{"show-features", "features"}, []string{EventFeatures}},
+	{[]string{"show-priv-flags", "set-priv-flag"}, []string{EventPrivFlags}},
+	{[]string{"show-ring", "set-ring"}, []string{EventRings}},
+	{[]string{"show-channels", "set-channels"}, []string{EventChannels}},
+	{[]string{"show-coalesce", "coalesce"}, []string{EventCoalesce}},
+	{[]string{"show-pause", "pause"}, []string{EventPause}},
+	{[]string{"show-eee", "set-eee"}, []string{EventEEE}},
+	{[]string{"cable-test"}, []string{EventCableTest}},
+	{[]string{"cable-test-tdr"}, []string{EventCableTestTDR}},
+	{[]string{"show-fec", "set-fec"}, []string{EventFEC}},
+}
+
+// LinkModes is the link mode state carried by a linkmodes notification.
+// The masks are ETHTOOL_LINK_MODE_* bitmaps, 32 bits per word.
+type LinkModes struct {
+	Autoneg       bool
+	Speed         uint32
+	Duplex        uint8
+	Supported     []uint32
+	Advertising   []uint32
+	LPAdvertising []uint32
+}
+
+// WakeOnLan holds the WAKE_* bits supported and enabled on a device.
+type WakeOnLan struct {
+	Supported uint32
+	Enabled   uint32
+	SecureOn  []byte
+}
+
+// CableTestResult is the outcome of a cable test on one pair.
+type CableTestResult struct {
+	Pair        uint8  /* ETHTOOL_A_CABLE_PAIR_* */
+	Code        uint8  /* ETHTOOL_A_CABLE_RESULT_CODE_* */
+	FaultLength uint32 /* distance to the fault in cm, 0 if not reported */
+}
+
+// CableTest is the state of a cable test notification.
+type CableTest struct {
+	Completed bool
+	Results   []CableTestResult
+}
+
+// CableTestTDRAmplitude is one raw TDR sample of a pair.
+type CableTestTDRAmplitude struct {
+	Pair      uint8 /* ETHTOOL_A_CABLE_PAIR_* */
+	Amplitude int16 /* reflection in mV */
+}
+
+// CableTestTDRStep is a range of distances the PHY sampled, followed by
+// the amplitudes it measured over that range.
+type CableTestTDRStep struct {
+	First      uint32 /* distances in cm */
+	Last       uint32
+	Step       uint32
+	Amplitudes []CableTestTDRAmplitude
+}
+
+// CableTestTDR is the state of a raw TDR cable test notification.
+type CableTestTDR struct {
+	Completed bool
+	Pulse     int16 /* test pulse in mV, 0 if not reported */
+	Steps     []CableTestTDRStep
+}
+
+// Event is one notification of the kernel ethtool monitor group.
+// Only the field matching Type is filled, others are nil.
+type Event struct {
+	Type      string
+	Device    string
+	LinkModes *LinkModes
+	WoL       *WakeOnLan
+	Features  []Feature
+	Rings     *RingParam
+	Channels  *Channels
+	Coalesce  *Coalesce
+	CableTest *CableTest
+	TDR       *CableTestTDR
+}
+
+// Monitor listens to ethtool notifications sent by the kernel.
+type Monitor struct {
+	nlctx  *nl_context
+	device string
+	types  map[string]bool
+	events chan Event
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewMonitor subscribes to the ethtool monitor group. Only notifications
+// for device are reported, or for all devices when it is empty; types
+// limits them to the given Event* types, all types when none is given.
+func NewMonitor(device string, types ...string) (*Monitor, error) {
+	nlctx, err := nl_open(debug_mask)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open netlink socket: %w", err)
+	}
+	err = nl_resolve_family(nlctx)
+	if err != nil {
+		nl_close(nlctx)
+		return nil, fmt.Errorf("cannot find ethtool netlink family: %w", err)
+	}
+	m, err := new_monitor(nlctx, device, types)
+	if err != nil {
+		nl_close(nlctx)
+		return nil, err
+	}
+	return m, nil
+}
+
+func new_monitor(nlctx *nl_context, device string, types []string) (*Monitor, error) {
+	if nlctx.monitor_group == 0 {
+		return nil, fmt.Errorf("cannot find ethtool monitor group: %w", syscall.ENOENT)
+	}
+	err := syscall.SetsockoptInt(nlctx.fd, SOL_NETLINK, NETLINK_ADD_MEMBERSHIP,
+		int(nlctx.monitor_group))
+	if err != nil {
+		return nil, fmt.Errorf("cannot join ethtool monitor group: %w", err)
+	}
+	/* wake up now and then so that Close does not wait forever */
+	tv := syscall.NsecToTimeval(MONITOR_POLL_USEC * 1000)
+	err = syscall.SetsockoptTimeval(nlctx.fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Monitor{
+		nlctx:  nlctx,
+		device: device,
+		events: make(chan Event, 16),
+		done:   make(chan struct{}),
+	}
+	if len(types) > 0 {
+		m.types = make(map[string]bool)
+		for _, t := range types {
+			m.types[t] = true
+		}
+	}
+	m.wg.Add(1)
+	go m.run()
+	return m, nil
+}
+
+// Events returns the channel notifications are delivered on. It is closed
+// by Close.
+func (m *Monitor) Events() <-chan Event {
+	return m.events
+}
+
+// Close stops listening and releases the netlink socket.
+func (m *Monitor) Close() error {
+	close(m.done)
+	m.wg.Wait()
+	nl_close(m.nlctx)
+	return nil
+}
+
+func (m *Monitor) run() {
+	defer m.wg.Done()
+	defer close(m.events)
+
+	for {
+		select {
+		case <-m.done:
+			return
+		default:
+		}
+		msgs, err := nl_recv(m.nlctx)
+		if err != nil {
+			/* timeouts and overruns are not fatal */
+			if err == syscall.EAGAIN || err == syscall.EINTR || err == syscall.ENOBUFS {
+				continue
+			}
+			return
+		}
+		for i := range msgs {
+			ev, ok := m.parse(&msgs[i])
+			if !ok {
+				continue
+			}
+			select {
+			case m.events <- ev:
+			case <-m.done:
+				return
+			}
+		}
+	}
+}
+
+/* parse turns one notification into an Event, ok is false if it is filtered out */
+func (m *Monitor) parse(msg *syscall.NetlinkMessage) (Event, bool) {
+	if msg.Header.Type != m.nlctx.family || len(msg.Data) < GENL_HDRLEN {
+		return Event{}, false
+	}
+	tp, ok := monitor_events[msg.Data[0]]
+	if !ok || (m.types != nil && !m.types[tp]) {
+		return Event{}, false
+	}
+	attrs := msg.Data[GENL_HDRLEN:]
+
+	/* every message type has its request header as attribute 1 */
+	tb := nl_attr_table(attrs, 1)
+	htb := nl_attr_table(tb[1], ETHTOOL_A_HEADER_MAX)
+	ev := Event{Type: tp, Device: nla_string(htb[ETHTOOL_A_HEADER_DEV_NAME])}
+	if m.device != "" && ev.Device != m.device {
+		return Event{}, false
+	}
+
+	switch tp {
+	case EventLinkModes:
+		ev.LinkModes = linkmodes_from_nl(nl_attr_table(attrs, ETHTOOL_A_LINKMODES_MAX))
+	case EventWoL:
+		ev.WoL = wol_from_nl(nl_attr_table(attrs, ETHTOOL_A_WOL_MAX))
+	case EventFeatures:
+		ev.Features = features_from_nl(ev.Device, nl_attr_table(attrs, ETHTOOL_A_FEATURES_MAX))
+	case EventRings:
+		rings := ring_from_nl(nl_attr_table(attrs, ETHTOOL_A_RINGS_MAX))
+		ev.Rings = &rings
+	case EventChannels:
+		channels := channels_from_nl(nl_attr_table(attrs, ETHTOOL_A_CHANNELS_MAX))
+		ev.Channels = &channels
+	case EventCoalesce:
+		coalesce := coalesce_from_nl(nl_attr_table(attrs, ETHTOOL_A_COALESCE_MAX))
+		ev.Coalesce = &coalesce
+	case EventCableTest:
+		ev.CableTest = cable_test_from_nl(nl_attr_table(attrs, ETHTOOL_A_CABLE_TEST_NTF_MAX))
+	case EventCableTestTDR:
+		ev.TDR = cable_test_tdr_from_nl(nl_attr_table(attrs, ETHTOOL_A_CABLE_TEST_TDR_NTF_MAX))
+	}
+	return ev, true
+}
+
+func linkmodes_from_nl(tb [][]byte) *LinkModes {
+	lm := &LinkModes{
+		Autoneg: nla_u8(tb[ETHTOOL_A_LINKMODES_AUTONEG]) == AUTONEG_ENABLE,
+		Speed:   nla_u32(tb[ETHTOOL_A_LINKMODES_SPEED]),
+		Duplex:  nla_u8(tb[ETHTOOL_A_LINKMODES_DUPLEX]),
+	}
+	if tb[ETHTOOL_A_LINKMODES_OURS] != nil {
+		ours := nl_parse_bitset(tb[ETHTOOL_A_LINKMODES_OURS])
+		lm.Advertising = ours.value
+		lm.Supported = ours.mask
+	}
+	if tb[ETHTOOL_A_LINKMODES_PEER] != nil {
+		lm.LPAdvertising = nl_parse_bitset(tb[ETHTOOL_A_LINKMODES_PEER]).value
+	}
+	return lm
+}
+
+func wol_from_nl(tb [][]byte) *WakeOnLan {
+	wol := &WakeOnLan{SecureOn: tb[ETHTOOL_A_WOL_SOPASS]}
+	if tb[ETHTOOL_A_WOL_MODES] != nil {
+		modes := nl_parse_bitset(tb[ETHTOOL_A_WOL_MODES])
+		wol.Enabled = modes.word(0)
+		wol.Supported = modes.mask_word(0)
+	}
+	return wol
+}
+
+/* features_from_nl takes the names from the bitsets when they are verbose,
+ * otherwise from the ETH_SS_FEATURES string set of the device.
+ */
+func features_from_nl(devname string, tb [][]byte) []Feature {
+	hw := nl_parse_bitset(tb[ETHTOOL_A_FEATURES_HW])
+	wanted := nl_parse_bitset(tb[ETHTOOL_A_FEATURES_WANTED])
+	active := nl_parse_bitset(tb[ETHTOOL_A_FEATURES_ACTIVE])
+	nochange := nl_parse_bitset(tb[ETHTOOL_A_FEATURES_NOCHANGE])
+
+	var names *ethtool_gstrings
+	if len(active.names) == 0 {
+		ctx := cmd_context{devname: devname}
+		if open_ioctl(&ctx) == nil {
+			names = get_stringset(&ctx, ETH_SS_FEATURES, 0, 1)
+			uninit_ioctl(&ctx)
+		}
+	}
+
+	features := make([]Feature, 0, active.size)
+	for i := uint32(0); i < active.size; i++ {
+		name := active.names[i]
+		if name == "" && names != nil && i < names.len {
+			name = gstring(names, i)
+		}
+		features = append(features, Feature{
+			Name:         name,
+			Available:    hw.test(i),
+			Requested:    wanted.test(i),
+			Active:       active.test(i),
+			NeverChanged: nochange.test(i),
+		})
+	}
+	return features
+}
+
+func cable_test_from_nl(tb [][]byte) *CableTest {
+	ct := &CableTest{
+		Completed: nla_u8(tb[ETHTOOL_A_CABLE_TEST_NTF_STATUS]) ==
+			ETHTOOL_A_CABLE_TEST_NTF_STATUS_COMPLETED,
+	}
+	for _, attr := range nl_attrs(tb[ETHTOOL_A_CABLE_TEST_NTF_NEST]) {
+		switch attr.tp {
+		case ETHTOOL_A_CABLE_NEST_RESULT:
+			rtb := nl_attr_table(attr.data, ETHTOOL_A_CABLE_RESULT_MAX)
+			r := cable_test_pair(ct, nla_u8(rtb[ETHTOOL_A_CABLE_RESULT_PAIR]))
+			r.Code = nla_u8(rtb[ETHTOOL_A_CABLE_RESULT_CODE])
+		case ETHTOOL_A_CABLE_NEST_FAULT_LENGTH:
+			ftb := nl_attr_table(attr.data, ETHTOOL_A_CABLE_FAULT_LENGTH_MAX)
+			r := cable_test_pair(ct, nla_u8(ftb[ETHTOOL_A_CABLE_FAULT_LENGTH_PAIR]))
+			r.FaultLength = nla_u32(ftb[ETHTOOL_A_CABLE_FAULT_LENGTH_CM])
+		}
+	}
+	return ct
+}
+
+/* cable_test_tdr_from_nl keeps the nests in the order the PHY reports
+ * them: amplitudes belong to the step configuration sent before them.
+ */
+func cable_test_tdr_from_nl(tb [][]byte) *CableTestTDR {
+	tdr := &CableTestTDR{
+		Completed: nla_u8(tb[ETHTOOL_A_CABLE_TEST_TDR_NTF_STATUS]) ==
+			ETHTOOL_A_CABLE_TEST_NTF_STATUS_COMPLETED,
+	}
+	for _, attr := range nl_attrs(tb[ETHTOOL_A_CABLE_TEST_TDR_NTF_NEST]) {
+		switch attr.tp {
+		case ETHTOOL_A_CABLE_TDR_NEST_PULSE:
+			ptb := nl_attr_table(attr.data, ETHTOOL_A_CABLE_PULSE_MAX)
+			tdr.Pulse = int16(nla_u16(ptb[ETHTOOL_A_CABLE_PULSE_mV]))
+		case ETHTOOL_A_CABLE_TDR_NEST_STEP:
+			stb := nl_attr_table(attr.data, ETHTOOL_A_CABLE_STEP_MAX)
+			tdr.Steps = append(tdr.Steps, CableTestTDRStep{
+				First: nla_u32(stb[ETHTOOL_A_CABLE_STEP_FIRST_DISTANCE]),
+				Last:  nla_u32(stb[ETHTOOL_A_CABLE_STEP_LAST_DISTANCE]),
+				Step:  nla_u32(stb[ETHTOOL_A_CABLE_STEP_STEP_DISTANCE]),
+			})
+		case ETHTOOL_A_CABLE_TDR_NEST_AMPLITUDE:
+			atb := nl_attr_table(attr.data, ETHTOOL_A_CABLE_AMPLITUDE_MAX)
+			if len(tdr.Steps) == 0 {
+				tdr.Steps = append(tdr.Steps, CableTestTDRStep{})
+			}
+			step := &tdr.Steps[len(tdr.Steps)-1]
+			step.Amplitudes = append(step.Amplitudes, CableTestTDRAmplitude{
+				Pair:      nla_u8(atb[ETHTOOL_A_CABLE_AMPLITUDE_PAIR]),
+				Amplitude: int16(nla_u16(atb[ETHTOOL_A_CABLE_AMPLITUDE_mV])),
+			})
+		}
+	}
+	return tdr
+}
+
+/* cable_test_pair finds the result entry of pair, adding it if needed */
+func cable_test_pair(ct *CableTest, pair uint8) *CableTestResult {
+	for i := range ct.Results {
+		if ct.Results[i].Pair == pair {
+			return &ct.Results[i]
+		}
+	}
+	ct.Results = append(ct.Results, CableTestResult{Pair: pair})
+	return &ct.Results[len(ct.Results)-1]
+}
+
+func cable_test_code_str(code uint8) string {
+	switch code {
+	case ETHTOOL_A_CABLE_RESULT_CODE_OK:
+		return "OK"
+	case ETHTOOL_A_CABLE_RESULT_CODE_OPEN:
+		return "Open Circuit"
+	case ETHTOOL_A_CABLE_RESULT_CODE_SAME_SHORT:
+		return "Short within Pair"
+	case ETHTOOL_A_CABLE_RESULT_CODE_CROSS_SHORT:
+		return "Short to another pair"
+	}
+	return "Unknown"
+}
+
+func dump_event(ev *Event) {
+	switch ev.Type {
+	case EventLinkModes:
+		lm := ev.LinkModes
+		fmt.Printf("Link modes for %s:\n", ev.Device)
+		if lm.Autoneg {
+			fmt.Printf("	Auto-negotiation: on\n")
+		} else {
+			fmt.Printf("	Auto-negotiation: off\n")
+		}
+		if lm.Speed == 0 || lm.Speed == math.MaxUint32 {
+			fmt.Printf("	Speed: Unknown!\n")
+		} else {
+			fmt.Printf("	Speed: %dMb/s\n", lm.Speed)
+		}
+		switch lm.Duplex {
+		case DUPLEX_HALF:
+			fmt.Printf("	Duplex: Half\n")
+		case DUPLEX_FULL:
+			fmt.Printf("	Duplex: Full\n")
+		default:
+			fmt.Printf("	Duplex: Unknown! (%d)\n", lm.Duplex)
+		}
+		if len(lm.Advertising) > 0 {
+			dump_link_caps("Advertised", "Advertised", lm.Advertising, true)
+		}
+		if !link_mode_is_empty(lm.LPAdvertising) {
+			dump_link_caps("Link partner advertised",
+				"Link partner advertised", lm.LPAdvertising, true)
+		}
+	case EventWoL:
+		wol := ethtool_wolinfo{
+			supported: ev.WoL.Supported,
+			wolopts:   ev.WoL.Enabled,
+		}
+		copy(wol.sopass[:], ev.WoL.SecureOn)
+		fmt.Printf("Wake-on-LAN settings for %s:\n", ev.Device)
+		dump_wol(&wol)
+	case EventFeatures:
+		fmt.Printf("Features for %s:\n", ev.Device)
+		for _, f := range ev.Features {
+			if f.Name == "" {
+				continue
+			}
+			state := "off"
+			if f.Active {
+				state = "on"
+			}
+			fixed := ""
+			if !f.Available || f.NeverChanged {
+				fixed = " [fixed]"
+			}
+			fmt.Printf("%s: %s%s\n", f.Name, state, fixed)
+		}
+	case EventRings:
+		fmt.Printf("Ring parameters for %s:\n", ev.Device)
+		dump_ring(ev.Rings)
+	case EventChannels:
+		fmt.Printf("Channel parameters for %s:\n", ev.Device)
+		dump_channels(ev.Channels)
+	case EventCoalesce:
+		fmt.Printf("Coalesce parameters for %s:\n", ev.Device)
+		dump_coalesce(ev.Coalesce)
+	case EventCableTest:
+		if !ev.CableTest.Completed {
+			fmt.Printf("Cable test started for device %s.\n", ev.Device)
+			break
+		}
+		fmt.Printf("Cable test completed for device %s.\n", ev.Device)
+		for _, r := range ev.CableTest.Results {
+			fmt.Printf("Pair %c code %s\n", 'A'+r.Pair, cable_test_code_str(r.Code))
+			if r.FaultLength != 0 {
+				fmt.Printf("Pair %c, fault length: %.2fm\n", 'A'+r.Pair,
+					float64(r.FaultLength)/100)
+			}
+		}
+	case EventCableTestTDR:
+		if !ev.TDR.Completed {
+			fmt.Printf("Cable test TDR started for device %s.\n", ev.Device)
+			break
+		}
+		fmt.Printf("Cable test TDR completed for device %s.\n", ev.Device)
+		if ev.TDR.Pulse != 0 {
+			fmt.Printf("Pulse %dmV\n", ev.TDR.Pulse)
+		}
+		for _, step := range ev.TDR.Steps {
+			if step.Step != 0 {
+				fmt.Printf("Step configuration: %.2f-%.2f meters in %.2fm steps\n",
+					float64(step.First)/100, float64(step.Last)/100,
+					float64(step.Step)/100)
+			}
+			for _, a := range step.Amplitudes {
+				fmt.Printf("Pair %c amplitude %dmV\n", 'A'+a.Pair, a.Amplitude)
+			}
+		}
+	default:
+		fmt.Printf("%s settings changed for %s\n", ev.Type, ev.Device)
+	}
+	fmt.Printf("\n")
+}
+
+/* nl_monitor parses [ --all | --OPTION ... ] [ DEVNAME ] and prints
+ * notifications until killed.
+ */
+func nl_monitor(ctx *cmd_context) int {
+	var types []string
+	devname := ""
+
+	for _, arg := range ctx.argp {
+		if arg == "--all" || arg == "*" {
+			continue
+		}
+		if len(arg) <= 2 || arg[:2] != "--" {
+			if devname != "" {
+				return -1
+			}
+			devname = arg
+			continue
+		}
+		found := false
+		for _, opt := range monitor_opts {
+			for _, name := range opt.names {
+				if arg[2:] == name {
+					types = append(types, opt.events...)
+					found = true
+				}
+			}
+		}
+		if !found {
+			return -1
+		}
+	}
+	if ctx.nlctx == nil {
+		fmt.Printf("Monitor requires the ethtool netlink interface\n")
+		return 1
+	}
+
+	/* the monitor owns the netlink socket from now on */
+	m, err := new_monitor(ctx.nlctx, devname, types)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return 1
+	}
+	ctx.nlctx = nil
+	defer m.Close()
+
+	fmt.Printf("listening...\n\n")
+	for ev := range m.Events() {
+		dump_event(&ev)
+	}
+	return 0
 }
@@ -0,0 +1,454 @@
+package ethtool
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	NETLINK_GENERIC = 16
+	NETLINK_EXT_ACK = 11
+	SOL_NETLINK     = 270
+
+	NLMSG_HDRLEN  = 16
+	GENL_HDRLEN   = 4
+	NLA_HDRLEN    = 4
+	NLA_F_NESTED  = 1 << 15
+	NLA_TYPE_MASK = ^uint16(NLA_F_NESTED | 1<<14)
+
+	NLMSG_ERROR = 0x2
+	NLMSG_DONE  = 0x3
+
+	NLM_F_REQUEST  = 0x1
+	NLM_F_MULTI    = 0x2
+	NLM_F_ACK      = 0x4
+	NLM_F_DUMP     = 0x300
+	NLM_F_CAPPED   = 0x100
+	NLM_F_ACK_TLVS = 0x200
+
+	NLMSGERR_ATTR_MSG = 1
+
+	GENL_ID_CTRL       = 0x10
+	CTRL_CMD_GETFAMILY = 3
+
+	CTRL_ATTR_FAMILY_ID    = 1
+	CTRL_ATTR_FAMILY_NAME  = 2
+	CTRL_ATTR_MCAST_GROUPS = 7
+	CTRL_ATTR_MAX          = CTRL_ATTR_MCAST_GROUPS
+
+	CTRL_ATTR_MCAST_GRP_NAME = 1
+	CTRL_ATTR_MCAST_GRP_ID   = 2
+	CTRL_ATTR_MCAST_GRP_MAX  = CTRL_ATTR_MCAST_GRP_ID
+
+	NL_RECV_BUF_SIZE = 65536
+)
+
+var native_endian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+type nl_context struct {
+	fd            int
+	seq           uint32
+	family        uint16 /* resolved id of the "ethtool" genl family */
+	monitor_group uint32 /* id of the "monitor" multicast group */
+	debug         uint64
+}
+
+/* nl_error carries the errno and the extended ack message of a failed request */
+type nl_error struct {
+	errno syscall.Errno
+	msg   string
+}
+
+func (e *nl_error) Error() string {
+	if e.msg != "" {
+		return fmt.Sprintf("%v (%s)", e.errno, e.msg)
+	}
+	return e.errno.Error()
+}
+
+func (e *nl_error) Unwrap() error {
+	return e.errno
+}
+
+type nl_attr struct {
+	tp   uint16
+	data []byte
+}
+
+type nl_msg struct {
+	buf   []byte
+	nests []int
+}
+
+func nla_align(l int) int {
+	return (l + 3) &^ 3
+}
+
+func nl_new_msg(tp uint16, flags uint16, cmd uint8, version uint8) *nl_msg {
+	m := &nl_msg{buf: make([]byte, NLMSG_HDRLEN+GENL_HDRLEN, 256)}
+	native_endian.PutUint16(m.buf[4:], tp)
+	native_endian.PutUint16(m.buf[6:], flags)
+	m.buf[NLMSG_HDRLEN] = cmd
+	m.buf[NLMSG_HDRLEN+1] = version
+	return m
+}
+
+func (m *nl_msg) put_attr(tp uint16, data []byte) {
+	var hdr [NLA_HDRLEN]byte
+	native_endian.PutUint16(hdr[0:], uint16(NLA_HDRLEN+len(data)))
+	native_endian.PutUint16(hdr[2:], tp)
+	m.buf = append(m.buf, hdr[:]...)
+	m.buf = append(m.buf, data...)
+	for len(m.buf)%4 != 0 {
+		m.buf = append(m.buf, 0)
+	}
+}
+
+func (m *nl_msg) put_flag(tp uint16) {
+	m.put_attr(tp, nil)
+}
+
+func (m *nl_msg) put_u8(tp uint16, v uint8) {
+	m.put_attr(tp, []byte{v})
+}
+
+func (m *nl_msg) put_u16(tp uint16, v uint16) {
+	var b [2]byte
+	native_endian.PutUint16(b[:], v)
+	m.put_attr(tp, b[:])
+}
+
+func (m *nl_msg) put_u32(tp uint16, v uint32) {
+	var b [4]byte
+	native_endian.PutUint32(b[:], v)
+	m.put_attr(tp, b[:])
+}
+
+func (m *nl_msg) put_string(tp uint16, s string) {
+	m.put_attr(tp, append([]byte(s), 0))
+}
+
+func (m *nl_msg) nest_start(tp uint16) {
+	m.nests = append(m.nests, len(m.buf))
+	m.put_attr(tp|NLA_F_NESTED, nil)
+}
+
+func (m *nl_msg) nest_end() {
+	start := m.nests[len(m.nests)-1]
+	m.nests = m.nests[:len(m.nests)-1]
+	native_endian.PutUint16(m.buf[start:], uint16(len(m.buf)-start))
+}
+
+/* nl_attrs splits a stream of attributes, the type has the flag bits masked off */
+func nl_attrs(b []byte) []nl_attr {
+	var attrs []nl_attr
+	for len(b) >= NLA_HDRLEN {
+		l := int(native_endian.Uint16(b[0:]))
+		if l < NLA_HDRLEN || l > len(b) {
+			break
+		}
+		attrs = append(attrs, nl_attr{
+			tp:   native_endian.Uint16(b[2:]) & NLA_TYPE_MASK,
+			data: b[NLA_HDRLEN:l],
+		})
+		if nla_align(l) >= len(b) {
+			break
+		}
+		b = b[nla_align(l):]
+	}
+	return attrs
+}
+
+/* nl_attr_table indexes attributes by type, missing ones are nil */
+func nl_attr_table(b []byte, max int) [][]byte {
+	tb := make([][]byte, max+1)
+	for _, attr := range nl_attrs(b) {
+		if int(attr.tp) <= max {
+			tb[attr.tp] = attr.data
+		}
+	}
+	return tb
+}
+
+func nla_u8(b []byte) uint8 {
+	if len(b) < 1 {
+		return 0
+	}
+	return b[0]
+}
+
+func nla_u16(b []byte) uint16 {
+	if len(b) < 2 {
+		return 0
+	}
+	return native_endian.Uint16(b)
+}
+
+func nla_u32(b []byte) uint32 {
+	if len(b) < 4 {
+		return 0
+	}
+	return native_endian.Uint32(b)
+}
+
+func nla_string(b []byte) string {
+	return cstring(b)
+}
+
+func nl_open(debug uint64) (*nl_context, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK,
+		syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, NETLINK_GENERIC)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	/* ask for error messages, not fatal when the kernel is too old */
+	syscall.SetsockoptInt(fd, SOL_NETLINK, NETLINK_EXT_ACK, 1)
+
+	return &nl_context{fd: fd, debug: debug}, nil
+}
+
+func nl_close(nlctx *nl_context) {
+	if nlctx != nil && nlctx.fd >= 0 {
+		syscall.Close(nlctx.fd)
+		nlctx.fd = -1
+	}
+}
+
+func nl_send(nlctx *nl_context, m *nl_msg) (uint32, error) {
+	nlctx.seq++
+	native_endian.PutUint32(m.buf[0:], uint32(len(m.buf)))
+	native_endian.PutUint32(m.buf[8:], nlctx.seq)
+	if debug_on(nlctx.debug, DEBUG_NL_DUMP_SND) {
+		fmt.Fprintf(os.Stderr, "sending genetlink packet (%d bytes):\n", len(m.buf))
+		dump_hex(os.Stderr, m.buf, uint32(len(m.buf)), 0)
+	}
+	err := syscall.Sendto(nlctx.fd, m.buf, 0,
+		&syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+	return nlctx.seq, err
+}
+
+/* nl_recv reads one datagram and returns the netlink messages it holds */
+func nl_recv(nlctx *nl_context) ([]syscall.NetlinkMessage, error) {
+	buf := make([]byte, NL_RECV_BUF_SIZE)
+	n, _, err := syscall.Recvfrom(nlctx.fd, buf, 0)
+	if err != nil {
+		return nil, err
+	}
+	if debug_on(nlctx.debug, DEBUG_NL_DUMP_RCV) {
+		fmt.Fprintf(os.Stderr, "received genetlink packet (%d bytes):\n", n)
+		dump_hex(os.Stderr, buf, uint32(n), 0)
+	}
+	return syscall.ParseNetlinkMessage(buf[:n])
+}
+
+func nl_parse_error(msg *syscall.NetlinkMessage) error {
+	if len(msg.Data) < 4 {
+		return syscall.EBADMSG
+	}
+	errno := -int32(native_endian.Uint32(msg.Data))
+	if errno == 0 {
+		return nil
+	}
+	nlerr := &nl_error{errno: syscall.Errno(errno)}
+	if msg.Header.Flags&NLM_F_ACK_TLVS != 0 {
+		/* the original request follows, capped to its header */
+		off := 4 + NLMSG_HDRLEN
+		if msg.Header.Flags&NLM_F_CAPPED == 0 && len(msg.Data) >= off {
+			off = 4 + int(native_endian.Uint32(msg.Data[4:]))
+		}
+		if off <= len(msg.Data) {
+			tb := nl_attr_table(msg.Data[off:], NLMSGERR_ATTR_MSG)
+			nlerr.msg = nla_string(tb[NLMSGERR_ATTR_MSG])
+		}
+	}
+	return nlerr
+}
+
+/* nl_request sends m and collects the genl payloads of all replies until
+ * the final ack, error or end of dump.
+ */
+func nl_request(nlctx *nl_context, m *nl_msg) ([][]byte, error) {
+	seq, err := nl_send(nlctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	var replies [][]byte
+	for {
+		msgs, err := nl_recv(nlctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range msgs {
+			msg := &msgs[i]
+			if msg.Header.Seq != seq {
+				continue
+			}
+			switch msg.Header.Type {
+			case NLMSG_ERROR:
+				return replies, nl_parse_error(msg)
+			case NLMSG_DONE:
+				return replies, nil
+			}
+			if len(msg.Data) < GENL_HDRLEN {
+				return nil, syscall.EBADMSG
+			}
+			replies = append(replies, msg.Data[GENL_HDRLEN:])
+		}
+	}
+}
+
+/* nl_resolve_family looks up the ethtool genl family and its multicast group */
+func nl_resolve_family(nlctx *nl_context) error {
+	m := nl_new_msg(GENL_ID_CTRL, NLM_F_REQUEST|NLM_F_ACK, CTRL_CMD_GETFAMILY, 1)
+	m.put_string(CTRL_ATTR_FAMILY_NAME, ETHTOOL_GENL_NAME)
+
+	replies, err := nl_request(nlctx, m)
+	if err != nil {
+		return err
+	}
+	if len(replies) == 0 {
+		return syscall.ENOENT
+	}
+	tb := nl_attr_table(replies[0], CTRL_ATTR_MAX)
+	nlctx.family = nla_u16(tb[CTRL_ATTR_FAMILY_ID])
+	if nlctx.family == 0 {
+		return syscall.ENOENT
+	}
+	for _, grp := range nl_attrs(tb[CTRL_ATTR_MCAST_GROUPS]) {
+		gtb := nl_attr_table(grp.data, CTRL_ATTR_MCAST_GRP_MAX)
+		if nla_string(gtb[CTRL_ATTR_MCAST_GRP_NAME]) == ETHTOOL_MCGRP_MONITOR_NAME {
+			nlctx.monitor_group = nla_u32(gtb[CTRL_ATTR_MCAST_GRP_ID])
+		}
+	}
+	return nil
+}
+
+/* netlink_init sets up ctx.nlctx, it fails if the kernel has no ethtool
+ * netlink interface so that the caller can fall back to ioctl.
+ */
+func netlink_init(ctx *cmd_context) error {
+	nlctx, err := nl_open(ctx.debug)
+	if err != nil {
+		return err
+	}
+	err = nl_resolve_family(nlctx)
+	if err != nil {
+		nl_close(nlctx)
+		return err
+	}
+	ctx.nlctx = nlctx
+	return nil
+}
+
+func netlink_done(ctx *cmd_context) {
+	nl_close(ctx.nlctx)
+	ctx.nlctx = nil
+}
+
+/* ethnl_msg starts an ethtool request for ctx.devname */
+func ethnl_msg(ctx *cmd_context, cmd uint8, hdr_attr uint16, flags uint32) *nl_msg {
+	m := nl_new_msg(ctx.nlctx.family, NLM_F_REQUEST|NLM_F_ACK, cmd, ETHTOOL_GENL_VERSION)
+	m.nest_start(hdr_attr)
+	if ctx.devname != "" {
+		m.put_string(ETHTOOL_A_HEADER_DEV_NAME, ctx.devname)
+	}
+	if flags != 0 {
+		m.put_u32(ETHTOOL_A_HEADER_FLAGS, flags)
+	}
+	m.nest_end()
+	return m
+}
+
+/* ethnl_get issues a GET request and returns the attribute table of the reply */
+func ethnl_get(ctx *cmd_context, cmd uint8, hdr_attr uint16, max int) ([][]byte, error) {
+	replies, err := nl_request(ctx.nlctx, ethnl_msg(ctx, cmd, hdr_attr, 0))
+	if err != nil {
+		return nil, err
+	}
+	if len(replies) == 0 {
+		return nil, syscall.ENODATA
+	}
+	return nl_attr_table(replies[0], max), nil
+}
+
+/* nl_bitset is an ethtool bitset attribute in either compact or verbose form */
+type nl_bitset struct {
+	size   uint32
+	nomask bool
+	value  []uint32
+	mask   []uint32
+	names  map[uint32]string /* only filled for verbose bitsets */
+}
+
+func nl_parse_bitset(b []byte) *nl_bitset {
+	tb := nl_attr_table(b, ETHTOOL_A_BITSET_MAX)
+	bs := &nl_bitset{
+		size:   nla_u32(tb[ETHTOOL_A_BITSET_SIZE]),
+		nomask: tb[ETHTOOL_A_BITSET_NOMASK] != nil,
+		names:  make(map[uint32]string),
+	}
+	words := (bs.size + 31) / 32
+	bs.value = make([]uint32, words)
+	bs.mask = make([]uint32, words)
+
+	if tb[ETHTOOL_A_BITSET_VALUE] != nil {
+		for i := uint32(0); i < words && int(i*4+4) <= len(tb[ETHTOOL_A_BITSET_VALUE]); i++ {
+			bs.value[i] = native_endian.Uint32(tb[ETHTOOL_A_BITSET_VALUE][i*4:])
+		}
+		for i := uint32(0); i < words && int(i*4+4) <= len(tb[ETHTOOL_A_BITSET_MASK]); i++ {
+			bs.mask[i] = native_endian.Uint32(tb[ETHTOOL_A_BITSET_MASK][i*4:])
+		}
+		return bs
+	}
+
+	for _, bit := range nl_attrs(tb[ETHTOOL_A_BITSET_BITS]) {
+		if bit.tp != ETHTOOL_A_BITSET_BITS_BIT {
+			continue
+		}
+		btb := nl_attr_table(bit.data, ETHTOOL_A_BITSET_BIT_MAX)
+		idx := nla_u32(btb[ETHTOOL_A_BITSET_BIT_INDEX])
+		if idx >= bs.size {
+			continue
+		}
+		bs.names[idx] = nla_string(btb[ETHTOOL_A_BITSET_BIT_NAME])
+		bs.mask[idx/32] |= 1 << (idx % 32)
+		if bs.nomask || btb[ETHTOOL_A_BITSET_BIT_VALUE] != nil {
+			bs.value[idx/32] |= 1 << (idx % 32)
+		}
+	}
+	return bs
+}
+
+func (bs *nl_bitset) test(idx uint32) bool {
+	return idx < bs.size && bs.value[idx/32]&(1<<(idx%32)) != 0
+}
+
+/* word returns the n-th 32 bit word of the value, 0 past the end */
+func (bs *nl_bitset) word(n int) uint32 {
+	if n >= len(bs.value) {
+		return 0
+	}
+	return bs.value[n]
+}
+
+func (bs *nl_bitset) mask_word(n int) uint32 {
+	if n >= len(bs.mask) {
+		return 0
+	}
+	return bs.mask[n]
+}
@@ -0,0 +1,592 @@
+package ethtool
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+/* nlfunc return value asking Run to retry with the ioctl handler */
+const nl_fallback = -int(syscall.EOPNOTSUPP)
+
+/* nl_failed reports err, or asks for the ioctl fallback if the kernel or the
+ * driver does not implement the request over netlink.
+ */
+func nl_failed(what string, err error, ret int) int {
+	if errors.Is(err, syscall.EOPNOTSUPP) {
+		return nl_fallback
+	}
+	fmt.Printf("Cannot get %s: %v\n", what, err)
+	return ret
+}
+
+func nl_get_linkinfo(ctx *cmd_context, base *ethtool_link_settings) error {
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_LINKINFO_GET,
+		ETHTOOL_A_LINKINFO_HEADER, ETHTOOL_A_LINKINFO_MAX)
+	if err != nil {
+		return err
+	}
+	base.port = nla_u8(tb[ETHTOOL_A_LINKINFO_PORT])
+	base.phy_address = nla_u8(tb[ETHTOOL_A_LINKINFO_PHYADDR])
+	base.eth_tp_mdix = nla_u8(tb[ETHTOOL_A_LINKINFO_TP_MDIX])
+	base.eth_tp_mdix_ctrl = nla_u8(tb[ETHTOOL_A_LINKINFO_TP_MDIX_CTRL])
+	base.transceiver = nla_u8(tb[ETHTOOL_A_LINKINFO_TRANSCEIVER])
+	return nil
+}
+
+/* nl_get_linkmodes fills speed, duplex, autoneg and the three link mode
+ * masks the same way ETHTOOL_GLINKSETTINGS does.
+ */
+func nl_get_linkmodes(ctx *cmd_context, lus *ethtool_link_usettings) error {
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_LINKMODES_GET,
+		ETHTOOL_A_LINKMODES_HEADER, ETHTOOL_A_LINKMODES_MAX)
+	if err != nil {
+		return err
+	}
+	lus.base.autoneg = nla_u8(tb[ETHTOOL_A_LINKMODES_AUTONEG])
+	lus.base.speed = nla_u32(tb[ETHTOOL_A_LINKMODES_SPEED])
+	lus.base.duplex = nla_u8(tb[ETHTOOL_A_LINKMODES_DUPLEX])
+	lus.base.master_slave_cfg = nla_u8(tb[ETHTOOL_A_LINKMODES_MASTER_SLAVE_CFG])
+	lus.base.master_slave_state = nla_u8(tb[ETHTOOL_A_LINKMODES_MASTER_SLAVE_STATE])
+
+	if tb[ETHTOOL_A_LINKMODES_OURS] != nil {
+		ours := nl_parse_bitset(tb[ETHTOOL_A_LINKMODES_OURS])
+		nwords := len(ours.value)
+		if nwords > ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32 {
+			nwords = ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32
+		}
+		lus.base.link_mode_masks_nwords = int8(nwords)
+		for i := 0; i < nwords; i++ {
+			lus.link_modes.advertising[i] = ours.word(i)
+			lus.link_modes.supported[i] = ours.mask_word(i)
+		}
+	}
+	if tb[ETHTOOL_A_LINKMODES_PEER] != nil {
+		peer := nl_parse_bitset(tb[ETHTOOL_A_LINKMODES_PEER])
+		for i := 0; i < len(peer.value) && i < ETHTOOL_LINK_MODE_MASK_MAX_KERNEL_NU32; i++ {
+			lus.link_modes.lp_advertising[i] = peer.word(i)
+		}
+	}
+	return nil
+}
+
+/* nl_get_linkstate returns 1 if link is detected, 0 if not */
+func nl_get_linkstate(ctx *cmd_context) (int, error) {
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_LINKSTATE_GET,
+		ETHTOOL_A_LINKSTATE_HEADER, ETHTOOL_A_LINKSTATE_MAX)
+	if err != nil {
+		return 0, err
+	}
+	if tb[ETHTOOL_A_LINKSTATE_LINK] == nil {
+		return 0, syscall.ENODATA
+	}
+	return int(nla_u8(tb[ETHTOOL_A_LINKSTATE_LINK])), nil
+}
+
+func ring_from_nl(tb [][]byte) RingParam {
+	return RingParam{
+		RxMaxPending:      nla_u32(tb[ETHTOOL_A_RINGS_RX_MAX]),
+		RxMiniMaxPending:  nla_u32(tb[ETHTOOL_A_RINGS_RX_MINI_MAX]),
+		RxJumboMaxPending: nla_u32(tb[ETHTOOL_A_RINGS_RX_JUMBO_MAX]),
+		TxMaxPending:      nla_u32(tb[ETHTOOL_A_RINGS_TX_MAX]),
+		RxPending:         nla_u32(tb[ETHTOOL_A_RINGS_RX]),
+		RxMiniPending:     nla_u32(tb[ETHTOOL_A_RINGS_RX_MINI]),
+		RxJumboPending:    nla_u32(tb[ETHTOOL_A_RINGS_RX_JUMBO]),
+		TxPending:         nla_u32(tb[ETHTOOL_A_RINGS_TX]),
+	}
+}
+
+func nl_gring(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_RINGS_GET,
+		ETHTOOL_A_RINGS_HEADER, ETHTOOL_A_RINGS_MAX)
+	if err != nil {
+		return nl_failed("device ring settings", err, 76)
+	}
+
+	ering := ring_from_nl(tb)
+	fmt.Printf("Ring parameters for %s:\n", ctx.devname)
+	dump_ring(&ering)
+	return 0
+}
+
+func channels_from_nl(tb [][]byte) Channels {
+	return Channels{
+		MaxRx:         nla_u32(tb[ETHTOOL_A_CHANNELS_RX_MAX]),
+		MaxTx:         nla_u32(tb[ETHTOOL_A_CHANNELS_TX_MAX]),
+		MaxOther:      nla_u32(tb[ETHTOOL_A_CHANNELS_OTHER_MAX]),
+		MaxCombined:   nla_u32(tb[ETHTOOL_A_CHANNELS_COMBINED_MAX]),
+		RxCount:       nla_u32(tb[ETHTOOL_A_CHANNELS_RX_COUNT]),
+		TxCount:       nla_u32(tb[ETHTOOL_A_CHANNELS_TX_COUNT]),
+		OtherCount:    nla_u32(tb[ETHTOOL_A_CHANNELS_OTHER_COUNT]),
+		CombinedCount: nla_u32(tb[ETHTOOL_A_CHANNELS_COMBINED_COUNT]),
+	}
+}
+
+func nl_gchannels(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_CHANNELS_GET,
+		ETHTOOL_A_CHANNELS_HEADER, ETHTOOL_A_CHANNELS_MAX)
+	if err != nil {
+		return nl_failed("device channel parameters", err, 1)
+	}
+
+	echannels := channels_from_nl(tb)
+	fmt.Printf("Channel parameters for %s:\n", ctx.devname)
+	dump_channels(&echannels)
+	return 0
+}
+
+func nl_get_coalesce(ctx *cmd_context) ([][]byte, error) {
+	return ethnl_get(ctx, ETHTOOL_MSG_COALESCE_GET,
+		ETHTOOL_A_COALESCE_HEADER, ETHTOOL_A_COALESCE_MAX)
+}
+
+func coalesce_from_nl(tb [][]byte) Coalesce {
+	return Coalesce{
+		RxCoalesceUsecs:          nla_u32(tb[ETHTOOL_A_COALESCE_RX_USECS]),
+		RxMaxCoalescedFrames:     nla_u32(tb[ETHTOOL_A_COALESCE_RX_MAX_FRAMES]),
+		RxCoalesceUsecsIrq:       nla_u32(tb[ETHTOOL_A_COALESCE_RX_USECS_IRQ]),
+		RxMaxCoalescedFramesIrq:  nla_u32(tb[ETHTOOL_A_COALESCE_RX_MAX_FRAMES_IRQ]),
+		TxCoalesceUsecs:          nla_u32(tb[ETHTOOL_A_COALESCE_TX_USECS]),
+		TxMaxCoalescedFrames:     nla_u32(tb[ETHTOOL_A_COALESCE_TX_MAX_FRAMES]),
+		TxCoalesceUsecsIrq:       nla_u32(tb[ETHTOOL_A_COALESCE_TX_USECS_IRQ]),
+		TxMaxCoalescedFramesIrq:  nla_u32(tb[ETHTOOL_A_COALESCE_TX_MAX_FRAMES_IRQ]),
+		StatsBlockCoalesceUsecs:  nla_u32(tb[ETHTOOL_A_COALESCE_STATS_BLOCK_USECS]),
+		UseAdaptiveRxCoalesce:    uint32(nla_u8(tb[ETHTOOL_A_COALESCE_USE_ADAPTIVE_RX])),
+		UseAdaptiveTxCoalesce:    uint32(nla_u8(tb[ETHTOOL_A_COALESCE_USE_ADAPTIVE_TX])),
+		PktRateLow:               nla_u32(tb[ETHTOOL_A_COALESCE_PKT_RATE_LOW]),
+		RxCoalesceUsecsLow:       nla_u32(tb[ETHTOOL_A_COALESCE_RX_USECS_LOW]),
+		RxMaxCoalescedFramesLow:  nla_u32(tb[ETHTOOL_A_COALESCE_RX_MAX_FRAMES_LOW]),
+		TxCoalesceUsecsLow:       nla_u32(tb[ETHTOOL_A_COALESCE_TX_USECS_LOW]),
+		TxMaxCoalescedFramesLow:  nla_u32(tb[ETHTOOL_A_COALESCE_TX_MAX_FRAMES_LOW]),
+		PktRateHigh:              nla_u32(tb[ETHTOOL_A_COALESCE_PKT_RATE_HIGH]),
+		RxCoalesceUsecsHigh:      nla_u32(tb[ETHTOOL_A_COALESCE_RX_USECS_HIGH]),
+		RxMaxCoalescedFramesHigh: nla_u32(tb[ETHTOOL_A_COALESCE_RX_MAX_FRAMES_HIGH]),
+		TxCoalesceUsecsHigh:      nla_u32(tb[ETHTOOL_A_COALESCE_TX_USECS_HIGH]),
+		TxMaxCoalescedFramesHigh: nla_u32(tb[ETHTOOL_A_COALESCE_TX_MAX_FRAMES_HIGH]),
+		RateSampleInterval:       nla_u32(tb[ETHTOOL_A_COALESCE_RATE_SAMPLE_INTERVAL]),
+	}
+}
+
+func nl_gcoalesce(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	tb, err := nl_get_coalesce(ctx)
+	if err != nil {
+		return nl_failed("device coalesce settings", err, 82)
+	}
+
+	ecoal := coalesce_from_nl(tb)
+	fmt.Printf("Coalesce parameters for %s:\n", ctx.devname)
+	dump_coalesce(&ecoal)
+	return 0
+}
+
+func nl_scoalesce(ctx *cmd_context) int {
+	var ecoal Coalesce
+
+	cl, ret := parse_coalesce_cmdline(ctx, &ecoal)
+	if ret != 0 {
+		return ret
+	}
+
+	tb, err := nl_get_coalesce(ctx)
+	if err != nil {
+		return nl_failed("device coalesce settings", err, 82)
+	}
+
+	ecoal = coalesce_from_nl(tb)
+	changed := 0
+	do_generic_set(&cl.info, &changed)
+
+	if changed == 0 {
+		fmt.Printf("no coalesce parameters changed, aborting\n")
+		return 80
+	}
+
+	m := ethnl_msg(ctx, ETHTOOL_MSG_COALESCE_SET, ETHTOOL_A_COALESCE_HEADER, 0)
+	for i, p := range coalesce_params {
+		if cl.seen[i] == 0 {
+			continue
+		}
+		if p.attr == ETHTOOL_A_COALESCE_USE_ADAPTIVE_RX ||
+			p.attr == ETHTOOL_A_COALESCE_USE_ADAPTIVE_TX {
+			m.put_u8(p.attr, uint8(*p.field(&ecoal)))
+		} else {
+			m.put_u32(p.attr, *p.field(&ecoal))
+		}
+	}
+	/* the kernel rejects parameters the driver does not support and
+	 * says why in the extended ack
+	 */
+	_, err = nl_request(ctx.nlctx, m)
+	if err != nil {
+		fmt.Printf("Cannot set device coalesce parameters: %v\n", err)
+		return 83
+	}
+	return 0
+}
+
+func nl_gpause(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_PAUSE_GET,
+		ETHTOOL_A_PAUSE_HEADER, ETHTOOL_A_PAUSE_MAX)
+	if err != nil {
+		return nl_failed("device pause settings", err, 76)
+	}
+
+	epause := ethtool_pauseparam{
+		autoneg:  uint32(nla_u8(tb[ETHTOOL_A_PAUSE_AUTONEG])),
+		rx_pause: uint32(nla_u8(tb[ETHTOOL_A_PAUSE_RX])),
+		tx_pause: uint32(nla_u8(tb[ETHTOOL_A_PAUSE_TX])),
+	}
+	fmt.Printf("Pause parameters for %s:\n", ctx.devname)
+	if epause.autoneg != 0 {
+		var lus ethtool_link_usettings
+		err = nl_get_linkmodes(ctx, &lus)
+		if err != nil {
+			fmt.Printf("Cannot get device settings: %v\n", err)
+			return 1
+		}
+		dump_pause(&epause, lus.link_modes.advertising[:],
+			lus.link_modes.lp_advertising[:])
+	} else {
+		dump_pause(&epause, nil, nil)
+	}
+	return 0
+}
+
+func nl_geee(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_EEE_GET,
+		ETHTOOL_A_EEE_HEADER, ETHTOOL_A_EEE_MAX)
+	if err != nil {
+		return nl_failed("EEE settings", err, 1)
+	}
+
+	eeecmd := ethtool_eee{
+		eee_active:     uint32(nla_u8(tb[ETHTOOL_A_EEE_ACTIVE])),
+		eee_enabled:    uint32(nla_u8(tb[ETHTOOL_A_EEE_ENABLED])),
+		tx_lpi_enabled: uint32(nla_u8(tb[ETHTOOL_A_EEE_TX_LPI_ENABLED])),
+		tx_lpi_timer:   nla_u32(tb[ETHTOOL_A_EEE_TX_LPI_TIMER]),
+	}
+	if tb[ETHTOOL_A_EEE_MODES_OURS] != nil {
+		ours := nl_parse_bitset(tb[ETHTOOL_A_EEE_MODES_OURS])
+		eeecmd.supported = ours.mask_word(0)
+		eeecmd.advertised = ours.word(0)
+	}
+	if tb[ETHTOOL_A_EEE_MODES_PEER] != nil {
+		eeecmd.lp_advertised = nl_parse_bitset(tb[ETHTOOL_A_EEE_MODES_PEER]).word(0)
+	}
+
+	fmt.Printf("EEE Settings for %s:\n", ctx.devname)
+	dump_eeecmd(&eeecmd)
+	return 0
+}
+
+func nl_gfeatures(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_FEATURES_GET,
+		ETHTOOL_A_FEATURES_HEADER, ETHTOOL_A_FEATURES_MAX)
+	if err != nil {
+		return nl_failed("device features", err, 1)
+	}
+
+	/* names come from the string set, bit indices match it */
+	defs := get_feature_defs(ctx)
+	if defs.n_features == 0 {
+		fmt.Printf("Cannot get device feature names\n")
+		return 1
+	}
+
+	hw := nl_parse_bitset(tb[ETHTOOL_A_FEATURES_HW])
+	wanted := nl_parse_bitset(tb[ETHTOOL_A_FEATURES_WANTED])
+	active := nl_parse_bitset(tb[ETHTOOL_A_FEATURES_ACTIVE])
+	nochange := nl_parse_bitset(tb[ETHTOOL_A_FEATURES_NOCHANGE])
+
+	var state feature_state
+	for i := 0; uint64(i*32) < defs.n_features; i++ {
+		state.features.features[i] = ethtool_get_features_block{
+			available:     hw.word(i),
+			requested:     wanted.word(i),
+			active:        active.word(i),
+			never_changed: nochange.word(i),
+		}
+	}
+	/* legacy flags are on if any of their features is active */
+	for i := uint32(0); uint64(i) < defs.n_features; i++ {
+		if defs.def[i].off_flag_index >= 0 && active.test(i) {
+			state.off_flags |= off_flag_def[defs.def[i].off_flag_index].value
+		}
+	}
+
+	fmt.Printf("Features for %s:\n", ctx.devname)
+	dump_features(&defs, &state, nil)
+	return 0
+}
+
+func nl_get_privflags(ctx *cmd_context) (*nl_bitset, int) {
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_PRIVFLAGS_GET,
+		ETHTOOL_A_PRIVFLAGS_HEADER, ETHTOOL_A_PRIVFLAGS_MAX)
+	if err != nil {
+		return nil, nl_failed("private flags", err, 1)
+	}
+	var flags *nl_bitset
+	if tb[ETHTOOL_A_PRIVFLAGS_FLAGS] != nil {
+		flags = nl_parse_bitset(tb[ETHTOOL_A_PRIVFLAGS_FLAGS])
+	}
+	if flags == nil || flags.size == 0 {
+		fmt.Printf("No private flags defined\n")
+		return nil, 1
+	}
+	return flags, 0
+}
+
+func nl_privflag_names(flags *nl_bitset) []string {
+	names := make([]string, flags.size)
+	for i := uint32(0); i < flags.size; i++ {
+		names[i] = flags.names[i]
+	}
+	return names
+}
+
+func nl_gprivflags(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	flags, ret := nl_get_privflags(ctx)
+	if flags == nil {
+		return ret
+	}
+	dump_privflags(ctx, nl_privflag_names(flags), func(i int) bool {
+		return flags.test(uint32(i))
+	})
+	return 0
+}
+
+func nl_sprivflags(ctx *cmd_context) int {
+
+	flags, ret := nl_get_privflags(ctx)
+	if flags == nil {
+		return ret
+	}
+	names := nl_privflag_names(flags)
+	wanted, seen, ret := parse_privflags(ctx, names)
+	if ret != 0 {
+		return ret
+	}
+
+	/* a verbose bitset without NOMASK only touches the listed flags */
+	m := ethnl_msg(ctx, ETHTOOL_MSG_PRIVFLAGS_SET, ETHTOOL_A_PRIVFLAGS_HEADER, 0)
+	m.nest_start(ETHTOOL_A_PRIVFLAGS_FLAGS)
+	m.nest_start(ETHTOOL_A_BITSET_BITS)
+	for i, name := range names {
+		if !seen[i] {
+			continue
+		}
+		m.nest_start(ETHTOOL_A_BITSET_BITS_BIT)
+		m.put_string(ETHTOOL_A_BITSET_BIT_NAME, name)
+		if wanted[i] {
+			m.put_flag(ETHTOOL_A_BITSET_BIT_VALUE)
+		}
+		m.nest_end()
+	}
+	m.nest_end()
+	m.nest_end()
+	_, err := nl_request(ctx.nlctx, m)
+	if err != nil {
+		fmt.Printf("Cannot set private flags: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func nl_getmodule(ctx *cmd_context) int {
+
+	params, ret := parse_getmodule_cmdline(ctx)
+	if params == nil {
+		return ret
+	}
+	read := nl_module_reader(ctx)
+
+	paged := params.page_seen != 0 || params.bank_seen != 0 ||
+		params.i2c_seen != 0
+	dump := params.dump_raw != 0 || params.dump_hex != 0 ||
+		params.offset_seen != 0 || params.length_seen != 0
+
+	if !paged && !dump {
+		err := module_show_all(read)
+		if err != nil {
+			return nl_failed("Module EEPROM data", err, 1)
+		}
+		return 0
+	}
+
+	/* A raw dump of a CMIS module saves the pages the decoder reads,
+	 * the ioctl would return them in the SFF-8636 layout.
+	 */
+	if !paged && params.dump_raw != 0 && params.offset_seen == 0 &&
+		params.length_seen == 0 {
+		lower, err := module_lower_page(read, ETH_I2C_ADDRESS_LOW)
+		if err != nil {
+			return nl_failed("Module EEPROM data", err, 1)
+		}
+		if module_decoder_name(lower[SFF8024_ID_OFFSET]) == "cmis" {
+			image, err := cmis_read_image(read, lower)
+			if err != nil {
+				fmt.Printf("Cannot get Module EEPROM data: %v\n", err)
+				return 1
+			}
+			dump_module_data(params, image, 0)
+			return 0
+		}
+	}
+
+	/* The ioctl returns the whole EEPROM blob the driver exposes, which
+	 * is what a dump without page selection is expected to show.
+	 */
+	if !paged {
+		return nl_fallback
+	}
+
+	/* Without an explicit offset, show the selected upper page */
+	if params.offset_seen == 0 && params.page_seen != 0 {
+		params.offset = ETH_MODULE_EEPROM_PAGE_LEN
+	}
+	if params.length_seen == 0 {
+		params.length = 2*ETH_MODULE_EEPROM_PAGE_LEN - params.offset
+	}
+	if params.offset+params.length > 2*ETH_MODULE_EEPROM_PAGE_LEN {
+		fmt.Printf("Offset and length exceed the %d bytes of a page\n",
+			2*ETH_MODULE_EEPROM_PAGE_LEN)
+		return 1
+	}
+
+	data, err := module_read_range(read, params)
+	if err != nil {
+		return nl_failed("Module EEPROM data", err, 1)
+	}
+	dump_module_data(params, data, params.offset)
+	return 0
+}
+
+func nl_tsinfo(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_TSINFO_GET,
+		ETHTOOL_A_TSINFO_HEADER, ETHTOOL_A_TSINFO_MAX)
+	if err != nil {
+		return nl_failed("device time stamping settings", err, 1)
+	}
+
+	info := ethtool_ts_info{phc_index: -1}
+	if tb[ETHTOOL_A_TSINFO_TIMESTAMPING] != nil {
+		info.so_timestamping = nl_parse_bitset(tb[ETHTOOL_A_TSINFO_TIMESTAMPING]).word(0)
+	}
+	if tb[ETHTOOL_A_TSINFO_TX_TYPES] != nil {
+		info.tx_types = nl_parse_bitset(tb[ETHTOOL_A_TSINFO_TX_TYPES]).word(0)
+	}
+	if tb[ETHTOOL_A_TSINFO_RX_FILTERS] != nil {
+		info.rx_filters = nl_parse_bitset(tb[ETHTOOL_A_TSINFO_RX_FILTERS]).word(0)
+	}
+	if tb[ETHTOOL_A_TSINFO_PHC_INDEX] != nil {
+		info.phc_index = int32(nla_u32(tb[ETHTOOL_A_TSINFO_PHC_INDEX]))
+	}
+
+	fmt.Printf("Time stamping parameters for %s:\n", ctx.devname)
+	dump_tsinfo(&info)
+	return 0
+}
+
+func nl_gset(ctx *cmd_context) int {
+
+	if ctx.argc != 0 {
+		return -1
+	}
+
+	var lus ethtool_link_usettings
+	err := nl_get_linkmodes(ctx, &lus)
+	if err == nil {
+		err = nl_get_linkinfo(ctx, &lus.base)
+	}
+	if errors.Is(err, syscall.EOPNOTSUPP) {
+		/* the driver may still provide the legacy settings */
+		return nl_fallback
+	}
+
+	fmt.Printf("Settings for %s:\n", ctx.devname)
+	allfail := true
+
+	if dump_gset_part(err, "device settings", func() { dump_link_usettings(&lus) }) {
+		allfail = false
+	}
+
+	var wolinfo ethtool_wolinfo
+	tb, err := ethnl_get(ctx, ETHTOOL_MSG_WOL_GET,
+		ETHTOOL_A_WOL_HEADER, ETHTOOL_A_WOL_MAX)
+	if err == nil {
+		wol := wol_from_nl(tb)
+		wolinfo.supported = wol.Supported
+		wolinfo.wolopts = wol.Enabled
+		copy(wolinfo.sopass[:], wol.SecureOn)
+	}
+	if dump_gset_part(err, "wake-on-lan settings", func() { dump_wol(&wolinfo) }) {
+		allfail = false
+	}
+
+	var msglvl uint32
+	tb, err = ethnl_get(ctx, ETHTOOL_MSG_DEBUG_GET,
+		ETHTOOL_A_DEBUG_HEADER, ETHTOOL_A_DEBUG_MAX)
+	if err == nil && tb[ETHTOOL_A_DEBUG_MSGMASK] == nil {
+		err = syscall.EOPNOTSUPP
+	}
+	if err == nil {
+		msglvl = nl_parse_bitset(tb[ETHTOOL_A_DEBUG_MSGMASK]).word(0)
+	}
+	if dump_gset_part(err, "message level", func() { dump_msglvl(msglvl) }) {
+		allfail = false
+	}
+
+	link, err := nl_get_linkstate(ctx)
+	if errors.Is(err, syscall.ENODATA) {
+		err = syscall.EOPNOTSUPP
+	}
+	if dump_gset_part(err, "link status", func() { dump_link(uint32(link)) }) {
+		allfail = false
+	}
+
+	if allfail {
+		fmt.Printf("No data available\n")
+		return 75
+	}
+	return 0
+}
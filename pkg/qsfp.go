@@ -0,0 +1,589 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * SFF-8436/SFF-8636 decoder: identification, capabilities and digital
+ * diagnostics of QSFP/QSFP+/QSFP28 modules.
+ *
+ * The kernel returns a blob, which contains:
+ *  - ETH_MODULE_SFF_8436/8636_LEN => The lower page and upper page 00h.
+ *  - ETH_MODULE_SFF_8436/8636_MAX_LEN => The lower page and upper pages
+ *    00h to 03h, each upper page being 128 bytes.
+ */
+
+/* Offsets in hex, as the upper pages are documented in SFF-8636 */
+const (
+	SFF8636_PAGE_SIZE       = 0x80
+	SFF8636_MAX_CHANNEL_NUM = 4
+
+	/* Lower page */
+	SFF8636_ID_OFFSET             = 0x00
+	SFF8636_REV_COMPLIANCE_OFFSET = 0x01
+	SFF8636_STATUS_2_OFFSET       = 0x02
+	SFF8636_STATUS_FLAT_MEM       = 1 << 2
+
+	/* Module monitor interrupt flags */
+	SFF8636_TEMP_AW_OFFSET = 0x06
+	SFF8636_VCC_AW_OFFSET  = 0x07
+
+	/* Channel monitor interrupt flags, two channels per byte */
+	SFF8636_RX_PWR_12_AW_OFFSET  = 0x09
+	SFF8636_RX_PWR_34_AW_OFFSET  = 0x0A
+	SFF8636_TX_BIAS_12_AW_OFFSET = 0x0B
+	SFF8636_TX_BIAS_34_AW_OFFSET = 0x0C
+	SFF8636_TX_PWR_12_AW_OFFSET  = 0x0D
+	SFF8636_TX_PWR_34_AW_OFFSET  = 0x0E
+
+	/* Module monitoring values */
+	SFF8636_TEMP_CURR = 0x16
+	SFF8636_VCC_CURR  = 0x1A
+
+	/* Channel monitoring values, 2 bytes per channel */
+	SFF8636_RX_PWR_1_OFFSET  = 0x22
+	SFF8636_TX_BIAS_1_OFFSET = 0x2A
+	SFF8636_TX_PWR_1_OFFSET  = 0x32
+
+	SFF8636_PWR_MODE_OFFSET = 0x5D
+	SFF8636_HIGH_PWR_ENABLE = 1 << 2
+	SFF8636_LOW_PWR_SET     = 1 << 1
+	SFF8636_PWR_OVERRIDE    = 1 << 0
+
+	/* Upper page 00h */
+	SFF8636_EXT_ID_OFFSET             = 0x81
+	SFF8636_EXT_ID_PWR_CLASS_MASK     = 0xC0
+	SFF8636_EXT_ID_PWR_CLASS_1        = 0 << 6
+	SFF8636_EXT_ID_PWR_CLASS_2        = 1 << 6
+	SFF8636_EXT_ID_PWR_CLASS_3        = 2 << 6
+	SFF8636_EXT_ID_PWR_CLASS_4        = 3 << 6
+	SFF8636_EXT_ID_CDR_TX_MASK        = 0x08
+	SFF8636_EXT_ID_CDR_RX_MASK        = 0x04
+	SFF8636_EXT_ID_EPWR_CLASS_MASK    = 0x03
+	SFF8636_EXT_ID_PWR_CLASS_LEGACY   = 0
+	SFF8636_EXT_ID_PWR_CLASS_5        = 1
+	SFF8636_EXT_ID_PWR_CLASS_6        = 2
+	SFF8636_EXT_ID_PWR_CLASS_7        = 3
+	SFF8636_CTOR_OFFSET               = 0x82
+	SFF8636_ETHERNET_COMP_OFFSET      = 0x83
+	SFF8636_ETHERNET_RSRVD            = 1 << 7
+	SFF8636_SONET_COMP_OFFSET         = 0x84
+	SFF8636_SAS_COMP_OFFSET           = 0x85
+	SFF8636_GIGE_COMP_OFFSET          = 0x86
+	SFF8636_FC_LEN_OFFSET             = 0x87
+	SFF8636_FC_TECH_OFFSET            = 0x88
+	SFF8636_FC_TRANS_MEDIA_OFFSET     = 0x89
+	SFF8636_FC_SPEED_OFFSET           = 0x8A
+	SFF8636_ENCODING_OFFSET           = 0x8B
+	SFF8636_BR_NOMINAL_OFFSET         = 0x8C
+	SFF8636_EXT_RS_OFFSET             = 0x8D
+	SFF8636_SM_LEN_OFFSET             = 0x8E
+	SFF8636_OM3_LEN_OFFSET            = 0x8F
+	SFF8636_OM2_LEN_OFFSET            = 0x90
+	SFF8636_OM1_LEN_OFFSET            = 0x91
+	SFF8636_CBL_LEN_OFFSET            = 0x92
+	SFF8636_DEVICE_TECH_OFFSET        = 0x93
+	SFF8636_TRANS_TECH_MASK           = 0xF0
+	SFF8636_TRANS_COPPER_PAS_UNEQUAL  = 10 << 4
+	SFF8636_VENDOR_NAME_START_OFFSET  = 0x94
+	SFF8636_VENDOR_NAME_END_OFFSET    = 0xA3
+	SFF8636_VENDOR_OUI_OFFSET         = 0xA5
+	SFF8636_VENDOR_PN_START_OFFSET    = 0xA8
+	SFF8636_VENDOR_PN_END_OFFSET      = 0xB7
+	SFF8636_VENDOR_REV_START_OFFSET   = 0xB8
+	SFF8636_VENDOR_REV_END_OFFSET     = 0xB9
+	SFF8636_WAVELEN_HIGH_BYTE_OFFSET  = 0xBA
+	SFF8636_WAVELEN_LOW_BYTE_OFFSET   = 0xBB
+	SFF8636_WAVE_TOL_HIGH_BYTE_OFFSET = 0xBC
+	SFF8636_WAVE_TOL_LOW_BYTE_OFFSET  = 0xBD
+	SFF8636_OPTION_1_OFFSET           = 0xC0
+	SFF8636_VENDOR_SN_START_OFFSET    = 0xC4
+	SFF8636_VENDOR_SN_END_OFFSET      = 0xD3
+	SFF8636_DATE_YEAR_OFFSET          = 0xD4
+	SFF8636_DATE_VENDOR_LOT_OFFSET    = 0xDA
+	SFF8636_DIAG_TYPE_OFFSET          = 0xDC
+	SFF8636_RX_PWR_TYPE_MASK          = 0x08
+	SFF8636_TX_PWR_TYPE_MASK          = 0x04
+
+	/* Upper page 03h */
+	SFF8636_TEMP_HALRM    = 0x80
+	SFF8636_TEMP_LALRM    = 0x82
+	SFF8636_TEMP_HWARN    = 0x84
+	SFF8636_TEMP_LWARN    = 0x86
+	SFF8636_VCC_HALRM     = 0x90
+	SFF8636_VCC_LALRM     = 0x92
+	SFF8636_VCC_HWARN     = 0x94
+	SFF8636_VCC_LWARN     = 0x96
+	SFF8636_RX_PWR_HALRM  = 0xB0
+	SFF8636_RX_PWR_LALRM  = 0xB2
+	SFF8636_RX_PWR_HWARN  = 0xB4
+	SFF8636_RX_PWR_LWARN  = 0xB6
+	SFF8636_TX_BIAS_HALRM = 0xB8
+	SFF8636_TX_BIAS_LALRM = 0xBA
+	SFF8636_TX_BIAS_HWARN = 0xBC
+	SFF8636_TX_BIAS_LWARN = 0xBE
+	SFF8636_TX_PWR_HALRM  = 0xC0
+	SFF8636_TX_PWR_LALRM  = 0xC2
+	SFF8636_TX_PWR_HWARN  = 0xC4
+	SFF8636_TX_PWR_LWARN  = 0xC6
+)
+
+/* sff8636_memory_map gives access to the pages of a module. Upper pages
+ * are indexed with their in-page offsets, 0x80 to 0xff, like the specs do.
+ * page_03h is nil when the module or the driver does not expose it.
+ */
+type sff8636_memory_map struct {
+	lower_memory []byte
+	page_00h     []byte
+	page_03h     []byte
+}
+
+/* sff8636_transceiver_codes maps the compliance bits of bytes 131-138 */
+var sff8636_transceiver_codes = []struct {
+	offset int
+	bit    uint8
+	desc   string
+}{
+	/* 10G/40G Ethernet Compliance Codes */
+	{SFF8636_ETHERNET_COMP_OFFSET, 1 << 6, "10G Ethernet: 10G Base-LRM"},
+	{SFF8636_ETHERNET_COMP_OFFSET, 1 << 5, "10G Ethernet: 10G Base-LR"},
+	{SFF8636_ETHERNET_COMP_OFFSET, 1 << 4, "10G Ethernet: 10G Base-SR"},
+	{SFF8636_ETHERNET_COMP_OFFSET, 1 << 3, "40G Ethernet: 40G Base-CR4"},
+	{SFF8636_ETHERNET_COMP_OFFSET, 1 << 2, "40G Ethernet: 40G Base-SR4"},
+	{SFF8636_ETHERNET_COMP_OFFSET, 1 << 1, "40G Ethernet: 40G Base-LR4"},
+	{SFF8636_ETHERNET_COMP_OFFSET, 1 << 0, "40G Ethernet: 40G Active Cable (XLPPI)"},
+	/* SONET Compliance Codes */
+	{SFF8636_SONET_COMP_OFFSET, 1 << 3, "40G OTN (OTU3B/OTU3C)"},
+	{SFF8636_SONET_COMP_OFFSET, 1 << 2, "SONET: OC-48, long reach"},
+	{SFF8636_SONET_COMP_OFFSET, 1 << 1, "SONET: OC-48, intermediate reach"},
+	{SFF8636_SONET_COMP_OFFSET, 1 << 0, "SONET: OC-48, short reach"},
+	/* SAS/SATA Compliance Codes */
+	{SFF8636_SAS_COMP_OFFSET, 1 << 6, "SAS 12.0G"},
+	{SFF8636_SAS_COMP_OFFSET, 1 << 5, "SAS 6.0G"},
+	{SFF8636_SAS_COMP_OFFSET, 1 << 4, "SAS 3.0G"},
+	/* Ethernet Compliance Codes */
+	{SFF8636_GIGE_COMP_OFFSET, 1 << 3, "Ethernet: 1000BASE-T"},
+	{SFF8636_GIGE_COMP_OFFSET, 1 << 2, "Ethernet: 1000BASE-CX"},
+	{SFF8636_GIGE_COMP_OFFSET, 1 << 1, "Ethernet: 1000BASE-LX"},
+	{SFF8636_GIGE_COMP_OFFSET, 1 << 0, "Ethernet: 1000BASE-SX"},
+	/* Fibre Channel link length */
+	{SFF8636_FC_LEN_OFFSET, 1 << 7, "FC: very long distance (V)"},
+	{SFF8636_FC_LEN_OFFSET, 1 << 6, "FC: short distance (S)"},
+	{SFF8636_FC_LEN_OFFSET, 1 << 5, "FC: intermediate distance (I)"},
+	{SFF8636_FC_LEN_OFFSET, 1 << 4, "FC: long distance (L)"},
+	{SFF8636_FC_LEN_OFFSET, 1 << 3, "FC: medium distance (M)"},
+	/* Fibre Channel transmitter technology */
+	{SFF8636_FC_LEN_OFFSET, 1 << 1, "FC: Longwave laser (LC)"},
+	{SFF8636_FC_LEN_OFFSET, 1 << 0, "FC: Electrical inter-enclosure (EL)"},
+	{SFF8636_FC_TECH_OFFSET, 1 << 7, "FC: Electrical intra-enclosure (EL)"},
+	{SFF8636_FC_TECH_OFFSET, 1 << 6, "FC: Shortwave laser w/o OFC (SN)"},
+	{SFF8636_FC_TECH_OFFSET, 1 << 5, "FC: Shortwave laser with OFC (SL)"},
+	{SFF8636_FC_TECH_OFFSET, 1 << 4, "FC: Longwave laser (LL)"},
+	/* Fibre Channel transmission media */
+	{SFF8636_FC_TRANS_MEDIA_OFFSET, 1 << 7, "FC: Twin Axial Pair (TW)"},
+	{SFF8636_FC_TRANS_MEDIA_OFFSET, 1 << 6, "FC: Twisted Pair (TP)"},
+	{SFF8636_FC_TRANS_MEDIA_OFFSET, 1 << 5, "FC: Miniature Coax (MI)"},
+	{SFF8636_FC_TRANS_MEDIA_OFFSET, 1 << 4, "FC: Video Coax (TV)"},
+	{SFF8636_FC_TRANS_MEDIA_OFFSET, 1 << 3, "FC: Multimode, 62.5m (M6)"},
+	{SFF8636_FC_TRANS_MEDIA_OFFSET, 1 << 2, "FC: Multimode, 50m (M5)"},
+	{SFF8636_FC_TRANS_MEDIA_OFFSET, 1 << 1, "FC: Multimode, 50um (OM3)"},
+	{SFF8636_FC_TRANS_MEDIA_OFFSET, 1 << 0, "FC: Single Mode (SM)"},
+	/* Fibre Channel speed */
+	{SFF8636_FC_SPEED_OFFSET, 1 << 7, "FC: 1200 MBytes/sec"},
+	{SFF8636_FC_SPEED_OFFSET, 1 << 6, "FC: 800 MBytes/sec"},
+	{SFF8636_FC_SPEED_OFFSET, 1 << 5, "FC: 1600 MBytes/sec"},
+	{SFF8636_FC_SPEED_OFFSET, 1 << 4, "FC: 400 MBytes/sec"},
+	{SFF8636_FC_SPEED_OFFSET, 1 << 2, "FC: 200 MBytes/sec"},
+	{SFF8636_FC_SPEED_OFFSET, 1 << 0, "FC: 100 MBytes/sec"},
+}
+
+var sff8636_trans_tech = []string{
+	"850 nm VCSEL",
+	"1310 nm VCSEL",
+	"1550 nm VCSEL",
+	"1310 nm FP",
+	"1310 nm DFB",
+	"1550 nm DFB",
+	"1310 nm EML",
+	"1550 nm EML",
+	"Others/Undefined",
+	"1490 nm DFB",
+	"Copper cable unequalized",
+	"Copper cable passive equalized",
+	"Copper cable, near and far end limiting active equalizers",
+	"Copper cable, far end limiting active equalizers",
+	"Copper cable, near end limiting active equalizers",
+	"Copper cable, linear active equalizers",
+}
+
+var sff8636_rev_compliance = []string{
+	"Revision not specified",
+	"SFF-8436 Rev 4.8 or earlier",
+	"SFF-8436 Rev 4.8 or earlier",
+	"SFF-8636 Rev 1.3 or earlier",
+	"SFF-8636 Rev 1.4",
+	"SFF-8636 Rev 1.5",
+	"SFF-8636 Rev 2.0",
+	"SFF-8636 Rev 2.5/2.6/2.7",
+	"SFF-8636 Rev 2.8/2.9/2.10",
+}
+
+type sff8636_aw_flag struct {
+	str    string
+	offset int
+	value  uint8
+}
+
+/* sff8636_aw_flags lists the module and per channel alarm/warning flags
+ * of the lower page, bytes 6-14
+ */
+var sff8636_aw_flags = sff8636_build_aw_flags()
+
+func sff8636_build_aw_flags() []sff8636_aw_flag {
+	levels := []string{"high alarm", "low alarm", "high warning", "low warning"}
+	flags := []sff8636_aw_flag{}
+
+	for i, l := range levels {
+		flags = append(flags, sff8636_aw_flag{"Module temperature " + l,
+			SFF8636_TEMP_AW_OFFSET, 1 << (7 - i)})
+	}
+	for i, l := range levels {
+		flags = append(flags, sff8636_aw_flag{"Module voltage " + l,
+			SFF8636_VCC_AW_OFFSET, 1 << (7 - i)})
+	}
+
+	/* Channels 1 and 3 use the high nibble, channels 2 and 4 the low one */
+	channels := []struct {
+		name   string
+		offset int
+	}{
+		{"Laser bias current", SFF8636_TX_BIAS_12_AW_OFFSET},
+		{"Laser tx power", SFF8636_TX_PWR_12_AW_OFFSET},
+		{"Laser rx power", SFF8636_RX_PWR_12_AW_OFFSET},
+	}
+	for _, c := range channels {
+		for ch := 0; ch < SFF8636_MAX_CHANNEL_NUM; ch++ {
+			offset := c.offset
+			if ch >= 2 {
+				offset++
+			}
+			shift := 7
+			if ch%2 != 0 {
+				shift = 3
+			}
+			for i, l := range levels {
+				flags = append(flags, sff8636_aw_flag{
+					fmt.Sprintf("%s %-12s (Chan %d)", c.name, l, ch+1),
+					offset, uint8(1 << (shift - i))})
+			}
+		}
+	}
+	return flags
+}
+
+func sff8636_show_identifier(m *sff8636_memory_map) {
+	sff8024_show_identifier(m.lower_memory, SFF8636_ID_OFFSET)
+}
+
+func sff8636_show_ext_identifier(m *sff8636_memory_map) {
+	pfx := "\tExtended identifier description           :"
+	ext_id := m.page_00h[SFF8636_EXT_ID_OFFSET]
+	pwr_mode := m.lower_memory[SFF8636_PWR_MODE_OFFSET]
+
+	fmt.Printf("\t%-41s : 0x%02x\n", "Extended identifier", ext_id)
+
+	switch ext_id & SFF8636_EXT_ID_PWR_CLASS_MASK {
+	case SFF8636_EXT_ID_PWR_CLASS_1:
+		fmt.Printf("%s 1.5W max. Power consumption\n", pfx)
+	case SFF8636_EXT_ID_PWR_CLASS_2:
+		fmt.Printf("%s 2.0W max. Power consumption\n", pfx)
+	case SFF8636_EXT_ID_PWR_CLASS_3:
+		fmt.Printf("%s 2.5W max. Power consumption\n", pfx)
+	case SFF8636_EXT_ID_PWR_CLASS_4:
+		fmt.Printf("%s 3.5W max. Power consumption\n", pfx)
+	}
+
+	if ext_id&SFF8636_EXT_ID_CDR_TX_MASK != 0 {
+		fmt.Printf("%s CDR present in TX,", pfx)
+	} else {
+		fmt.Printf("%s No CDR in TX,", pfx)
+	}
+	if ext_id&SFF8636_EXT_ID_CDR_RX_MASK != 0 {
+		fmt.Printf(" CDR present in RX\n")
+	} else {
+		fmt.Printf(" No CDR in RX\n")
+	}
+
+	switch ext_id & SFF8636_EXT_ID_EPWR_CLASS_MASK {
+	case SFF8636_EXT_ID_PWR_CLASS_LEGACY:
+		fmt.Printf("%s", pfx)
+	case SFF8636_EXT_ID_PWR_CLASS_5:
+		fmt.Printf("%s 4.0W max. Power consumption,", pfx)
+	case SFF8636_EXT_ID_PWR_CLASS_6:
+		fmt.Printf("%s 4.5W max. Power consumption,", pfx)
+	case SFF8636_EXT_ID_PWR_CLASS_7:
+		fmt.Printf("%s 5.0W max. Power consumption,", pfx)
+	}
+	if pwr_mode&SFF8636_HIGH_PWR_ENABLE != 0 {
+		fmt.Printf(" High Power Class (> 3.5 W) enabled\n")
+	} else {
+		fmt.Printf(" High Power Class (> 3.5 W) not enabled\n")
+	}
+
+	fmt.Printf("\t%-41s : %s\n", "Power set",
+		onoff(pwr_mode&SFF8636_LOW_PWR_SET != 0))
+	fmt.Printf("\t%-41s : %s\n", "Power override",
+		onoff(pwr_mode&SFF8636_PWR_OVERRIDE != 0))
+}
+
+func sff8636_show_connector(m *sff8636_memory_map) {
+	sff8024_show_connector(m.page_00h, SFF8636_CTOR_OFFSET)
+}
+
+func sff8636_show_transceiver(m *sff8636_memory_map) {
+	pfx := "\tTransceiver type                          :"
+	id := m.page_00h
+
+	fmt.Printf("\t%-41s : 0x%02x 0x%02x 0x%02x "+
+		"0x%02x 0x%02x 0x%02x 0x%02x 0x%02x\n",
+		"Transceiver codes",
+		id[SFF8636_ETHERNET_COMP_OFFSET], id[SFF8636_SONET_COMP_OFFSET],
+		id[SFF8636_SAS_COMP_OFFSET], id[SFF8636_GIGE_COMP_OFFSET],
+		id[SFF8636_FC_LEN_OFFSET], id[SFF8636_FC_TECH_OFFSET],
+		id[SFF8636_FC_TRANS_MEDIA_OFFSET], id[SFF8636_FC_SPEED_OFFSET])
+
+	/* Extended Specification Compliance Codes from SFF-8024 */
+	if id[SFF8636_ETHERNET_COMP_OFFSET]&SFF8636_ETHERNET_RSRVD != 0 {
+		if desc, ok := sff8024_ext_compliance_codes[id[SFF8636_OPTION_1_OFFSET]]; ok {
+			fmt.Printf("%s Extended: %s\n", pfx, desc)
+		} else {
+			fmt.Printf("%s (reserved or unknown)\n", pfx)
+		}
+	}
+
+	for _, code := range sff8636_transceiver_codes {
+		if id[code.offset]&code.bit != 0 {
+			fmt.Printf("%s %s\n", pfx, code.desc)
+		}
+	}
+}
+
+func sff8636_show_encoding(m *sff8636_memory_map) {
+	sff8024_show_encoding(m.page_00h, SFF8636_ENCODING_OFFSET,
+		ETH_MODULE_SFF_8636)
+}
+
+func sff8636_show_rate_identifier(m *sff8636_memory_map) {
+	/* TODO: Need to fix rate select logic */
+	fmt.Printf("\t%-41s : 0x%02x\n", "Rate identifier",
+		m.page_00h[SFF8636_EXT_RS_OFFSET])
+}
+
+func sff8636_show_wavelength_or_copper_compliance(m *sff8636_memory_map) {
+	id := m.page_00h
+	tech := id[SFF8636_DEVICE_TECH_OFFSET] & SFF8636_TRANS_TECH_MASK
+
+	fmt.Printf("\t%-41s : 0x%02x (%s)\n", "Transmitter technology",
+		tech, sff8636_trans_tech[tech>>4])
+
+	if tech >= SFF8636_TRANS_COPPER_PAS_UNEQUAL {
+		fmt.Printf("\t%-41s : %ddb\n", "Attenuation at 2.5GHz",
+			id[SFF8636_WAVELEN_HIGH_BYTE_OFFSET])
+		fmt.Printf("\t%-41s : %ddb\n", "Attenuation at 5.0GHz",
+			id[SFF8636_WAVELEN_LOW_BYTE_OFFSET])
+		fmt.Printf("\t%-41s : %ddb\n", "Attenuation at 7.0GHz",
+			id[SFF8636_WAVE_TOL_HIGH_BYTE_OFFSET])
+		fmt.Printf("\t%-41s : %ddb\n", "Attenuation at 12.9GHz",
+			id[SFF8636_WAVE_TOL_LOW_BYTE_OFFSET])
+	} else {
+		fmt.Printf("\t%-41s : %.3fnm\n", "Laser wavelength",
+			float64(sff_u16(id, SFF8636_WAVELEN_HIGH_BYTE_OFFSET))*0.05)
+		fmt.Printf("\t%-41s : %.3fnm\n", "Laser wavelength tolerance",
+			float64(sff_u16(id, SFF8636_WAVE_TOL_HIGH_BYTE_OFFSET))*0.005)
+	}
+}
+
+func sff8636_show_oui(m *sff8636_memory_map) {
+	id := m.page_00h
+	fmt.Printf("\t%-41s : %02x:%02x:%02x\n", "Vendor OUI",
+		id[SFF8636_VENDOR_OUI_OFFSET], id[SFF8636_VENDOR_OUI_OFFSET+1],
+		id[SFF8636_VENDOR_OUI_OFFSET+2])
+}
+
+func sff8636_show_revision_compliance(m *sff8636_memory_map) {
+	pfx := "\tRevision Compliance                       :"
+	rev := int(m.lower_memory[SFF8636_REV_COMPLIANCE_OFFSET])
+
+	if rev < len(sff8636_rev_compliance) {
+		fmt.Printf("%s %s\n", pfx, sff8636_rev_compliance[rev])
+	} else {
+		fmt.Printf("%s Unallocated\n", pfx)
+	}
+}
+
+func sff8636_dom_parse(m *sff8636_memory_map, sd *sff_diags) {
+	lower := m.lower_memory
+
+	sd.sfp_temp[MCURR] = int16(sff_u16(lower, SFF8636_TEMP_CURR))
+	sd.sfp_voltage[MCURR] = sff_u16(lower, SFF8636_VCC_CURR)
+
+	/* Channel Specific Data */
+	for i := 0; i < SFF8636_MAX_CHANNEL_NUM; i++ {
+		sd.scd[i].bias_cur = sff_u16(lower, SFF8636_TX_BIAS_1_OFFSET+2*i)
+		sd.scd[i].rx_power = sff_u16(lower, SFF8636_RX_PWR_1_OFFSET+2*i)
+		sd.scd[i].tx_power = sff_u16(lower, SFF8636_TX_PWR_1_OFFSET+2*i)
+	}
+
+	/* Fill alarm/warning thresholds only if page 03h is present */
+	p := m.page_03h
+	if p == nil {
+		return
+	}
+
+	sd.sfp_temp[HALRM] = int16(sff_u16(p, SFF8636_TEMP_HALRM))
+	sd.sfp_temp[LALRM] = int16(sff_u16(p, SFF8636_TEMP_LALRM))
+	sd.sfp_temp[HWARN] = int16(sff_u16(p, SFF8636_TEMP_HWARN))
+	sd.sfp_temp[LWARN] = int16(sff_u16(p, SFF8636_TEMP_LWARN))
+
+	sd.sfp_voltage[HALRM] = sff_u16(p, SFF8636_VCC_HALRM)
+	sd.sfp_voltage[LALRM] = sff_u16(p, SFF8636_VCC_LALRM)
+	sd.sfp_voltage[HWARN] = sff_u16(p, SFF8636_VCC_HWARN)
+	sd.sfp_voltage[LWARN] = sff_u16(p, SFF8636_VCC_LWARN)
+
+	sd.bias_cur[HALRM] = sff_u16(p, SFF8636_TX_BIAS_HALRM)
+	sd.bias_cur[LALRM] = sff_u16(p, SFF8636_TX_BIAS_LALRM)
+	sd.bias_cur[HWARN] = sff_u16(p, SFF8636_TX_BIAS_HWARN)
+	sd.bias_cur[LWARN] = sff_u16(p, SFF8636_TX_BIAS_LWARN)
+
+	sd.tx_power[HALRM] = sff_u16(p, SFF8636_TX_PWR_HALRM)
+	sd.tx_power[LALRM] = sff_u16(p, SFF8636_TX_PWR_LALRM)
+	sd.tx_power[HWARN] = sff_u16(p, SFF8636_TX_PWR_HWARN)
+	sd.tx_power[LWARN] = sff_u16(p, SFF8636_TX_PWR_LWARN)
+
+	sd.rx_power[HALRM] = sff_u16(p, SFF8636_RX_PWR_HALRM)
+	sd.rx_power[LALRM] = sff_u16(p, SFF8636_RX_PWR_LALRM)
+	sd.rx_power[HWARN] = sff_u16(p, SFF8636_RX_PWR_HWARN)
+	sd.rx_power[LWARN] = sff_u16(p, SFF8636_RX_PWR_LWARN)
+}
+
+func sff8636_show_dom(m *sff8636_memory_map) {
+	var sd sff_diags
+
+	/*
+	 * There is no clear identifier to signify the existence of
+	 * optical diagnostics similar to SFF-8472. So checking existence
+	 * of page 03h will provide the guarantee for existence of alarms
+	 * and thresholds.
+	 */
+	sd.supports_alarms = m.page_03h != nil
+	sd.rx_power_type = m.page_00h[SFF8636_DIAG_TYPE_OFFSET]&SFF8636_RX_PWR_TYPE_MASK != 0
+	sd.tx_power_type = m.page_00h[SFF8636_DIAG_TYPE_OFFSET]&SFF8636_TX_PWR_TYPE_MASK != 0
+
+	sff8636_dom_parse(m, &sd)
+
+	sff_print_temp("Module temperature", sd.sfp_temp[MCURR])
+	sff_print_vcc("Module voltage", sd.sfp_voltage[MCURR])
+
+	/*
+	 * SFF-8636/8436 spec is not clear whether RX power/ TX bias
+	 * current fields are supported or not. A valid temperature
+	 * reading is used as existence for TX/RX power.
+	 */
+	if sd.sfp_temp[MCURR] == 0 || sd.sfp_temp[MCURR] == -1 {
+		return
+	}
+
+	alarms := "No"
+	if sd.supports_alarms {
+		alarms = "Yes"
+	}
+	fmt.Printf("\t%-41s : %s\n", "Alarm/warning flags implemented", alarms)
+
+	for i := 0; i < SFF8636_MAX_CHANNEL_NUM; i++ {
+		sff_print_bias(fmt.Sprintf("%s (Channel %d)",
+			"Laser tx bias current", i+1), sd.scd[i].bias_cur)
+	}
+	for i := 0; i < SFF8636_MAX_CHANNEL_NUM; i++ {
+		sff_print_power(fmt.Sprintf("%s (Channel %d)",
+			"Transmit avg optical power", i+1), sd.scd[i].tx_power)
+	}
+
+	rx_power_string := "Receiver signal OMA"
+	if sd.rx_power_type {
+		rx_power_string = "Rcvr signal avg optical power"
+	}
+	for i := 0; i < SFF8636_MAX_CHANNEL_NUM; i++ {
+		sff_print_power(fmt.Sprintf("%s (Channel %d)",
+			rx_power_string, i+1), sd.scd[i].rx_power)
+	}
+
+	if sd.supports_alarms {
+		for _, f := range sff8636_aw_flags {
+			state := "Off"
+			if m.lower_memory[f.offset]&f.value != 0 {
+				state = "On"
+			}
+			fmt.Printf("\t%-41s : %s\n", f.str, state)
+		}
+		sff_show_thresholds(&sd)
+	}
+}
+
+func sff8636_show_all_common(m *sff8636_memory_map) {
+	sff8636_show_identifier(m)
+	switch m.lower_memory[SFF8636_ID_OFFSET] {
+	case SFF8024_ID_QSFP, SFF8024_ID_QSFP_PLUS, SFF8024_ID_QSFP28:
+	default:
+		/* Do not attempt to parse non-SFF-8636 modules */
+		return
+	}
+
+	id := m.page_00h
+	sff8636_show_ext_identifier(m)
+	sff8636_show_connector(m)
+	sff8636_show_transceiver(m)
+	sff8636_show_encoding(m)
+	sff_show_value_with_unit(id, SFF8636_BR_NOMINAL_OFFSET,
+		"BR, Nominal", 100, "Mbps")
+	sff8636_show_rate_identifier(m)
+	sff_show_value_with_unit(id, SFF8636_SM_LEN_OFFSET,
+		"Length (SMF,km)", 1, "km")
+	sff_show_value_with_unit(id, SFF8636_OM3_LEN_OFFSET,
+		"Length (OM3 50um)", 2, "m")
+	sff_show_value_with_unit(id, SFF8636_OM2_LEN_OFFSET,
+		"Length (OM2 50um)", 1, "m")
+	sff_show_value_with_unit(id, SFF8636_OM1_LEN_OFFSET,
+		"Length (OM1 62.5um)", 1, "m")
+	sff_show_value_with_unit(id, SFF8636_CBL_LEN_OFFSET,
+		"Length (Copper or Active cable)", 1, "m")
+	sff8636_show_wavelength_or_copper_compliance(m)
+	sff_show_ascii(id, SFF8636_VENDOR_NAME_START_OFFSET,
+		SFF8636_VENDOR_NAME_END_OFFSET, "Vendor name")
+	sff8636_show_oui(m)
+	sff_show_ascii(id, SFF8636_VENDOR_PN_START_OFFSET,
+		SFF8636_VENDOR_PN_END_OFFSET, "Vendor PN")
+	sff_show_ascii(id, SFF8636_VENDOR_REV_START_OFFSET,
+		SFF8636_VENDOR_REV_END_OFFSET, "Vendor rev")
+	sff_show_ascii(id, SFF8636_VENDOR_SN_START_OFFSET,
+		SFF8636_VENDOR_SN_END_OFFSET, "Vendor SN")
+	sff_show_ascii(id, SFF8636_DATE_YEAR_OFFSET,
+		SFF8636_DATE_VENDOR_LOT_OFFSET+1, "Date code")
+	sff8636_show_revision_compliance(m)
+	sff8636_show_dom(m)
+}
+
+/* sff8636_show_all decodes an SFF-8436/SFF-8636 blob of eeprom_len bytes
+ * as returned by ETHTOOL_GMODULEEEPROM
+ */
+func sff8636_show_all(id []byte, eeprom_len uint32) {
+	m := sff8636_memory_map{
+		lower_memory: id,
+		page_00h:     id[:2*SFF8636_PAGE_SIZE],
+	}
+
+	/* Page 03h is only present in the blob if the module is paged */
+	if eeprom_len == ETH_MODULE_SFF_8636_MAX_LEN {
+		m.page_03h = id[3*SFF8636_PAGE_SIZE : 5*SFF8636_PAGE_SIZE]
+	}
+
+	sff8636_show_all_common(&m)
+}
@@ -0,0 +1,178 @@
+package ethtool
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+ * Register dump of the r8169 driver: a copy of the first 256 bytes of the
+ * MMIO register space, so registers are addressed by byte offset and in
+ * the little endian order of the chip.
+ */
+
+const (
+	RTL8169_REGS_LEN = 0x100
+
+	/* ChipCmd */
+	RTL_CMD_TX_ENB = 0x04
+	RTL_CMD_RX_ENB = 0x08
+	RTL_CMD_RESET  = 0x10
+
+	/* TxConfig */
+	RTL_TXCFG_HWREV_MASK = 0x7cf00000
+
+	/* RxConfig */
+	RTL_RXCFG_AAP = 0x01 /* accept all physical */
+	RTL_RXCFG_APM = 0x02 /* accept physical match */
+	RTL_RXCFG_AM  = 0x04 /* accept multicast */
+	RTL_RXCFG_AB  = 0x08 /* accept broadcast */
+	RTL_RXCFG_AR  = 0x10 /* accept runt */
+	RTL_RXCFG_AER = 0x20 /* accept error */
+
+	/* PHYstatus */
+	RTL_PHY_FULL_DUP   = 0x01
+	RTL_PHY_LINK_STS   = 0x02
+	RTL_PHY_10BPS      = 0x04
+	RTL_PHY_100BPS     = 0x08
+	RTL_PHY_1000BPSF   = 0x10
+	RTL_PHY_RX_FLOWCTL = 0x20
+	RTL_PHY_TX_FLOWCTL = 0x40
+	RTL_PHY_TBI_ENABLE = 0x80
+
+	/* CPlusCmd */
+	RTL_CPCMD_PCI_MUL_RW = 0x0008
+	RTL_CPCMD_PCI_DAC    = 0x0010
+	RTL_CPCMD_RX_VLAN    = 0x0040
+	RTL_CPCMD_RX_CHKSUM  = 0x0020
+)
+
+var rtl_intr_bits = []string{
+	"RxOK", "RxErr", "TxOK", "TxErr", "RxOverflow", "LinkChg",
+	"RxFIFOOver", "TxDescUnavail", "SWInt", "", "", "", "", "",
+	"PCSTimeout", "SYSErr",
+}
+
+func rtl_intr_names(v uint16) string {
+	s := ""
+	for i, name := range rtl_intr_bits {
+		if v&(1<<uint(i)) != 0 && name != "" {
+			s += " " + name
+		}
+	}
+	if s == "" {
+		return " none"
+	}
+	return s
+}
+
+func realtek_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	if regs.len < RTL8169_REGS_LEN {
+		return -1
+	}
+	data := regs.data[:regs.len]
+	u8 := func(off int) uint8 { return data[off] }
+	u16 := func(off int) uint16 { return binary.LittleEndian.Uint16(data[off:]) }
+	u32 := func(off int) uint32 { return binary.LittleEndian.Uint32(data[off:]) }
+
+	tx_config := u32(0x40)
+	fmt.Printf("RealTek %s registers (hardware revision 0x%08x):\n",
+		cstring(info.driver[:]), tx_config&RTL_TXCFG_HWREV_MASK)
+	fmt.Printf("--------------------------------------------------------\n")
+
+	fmt.Printf("0x00: MAC Address                      %02x:%02x:%02x:%02x:%02x:%02x\n",
+		u8(0), u8(1), u8(2), u8(3), u8(4), u8(5))
+	fmt.Printf("0x08: Multicast Address Filter     0x%08x 0x%08x\n",
+		u32(0x0c), u32(0x08))
+	fmt.Printf("0x10: Dump Tally Counter Command   0x%08x 0x%08x\n",
+		u32(0x14), u32(0x10))
+	fmt.Printf("0x20: Tx Normal Priority Ring Addr 0x%08x 0x%08x\n",
+		u32(0x24), u32(0x20))
+	fmt.Printf("0x28: Tx High Priority Ring Addr   0x%08x 0x%08x\n",
+		u32(0x2c), u32(0x28))
+	fmt.Printf("0x30: Flash memory read/write                0x%08x\n", u32(0x30))
+
+	cmd := u8(0x37)
+	fmt.Printf("0x37: Command                                      0x%02x\n"+
+		"      Rx %s, Tx %s%s\n",
+		cmd, endis(cmd&RTL_CMD_RX_ENB != 0), endis(cmd&RTL_CMD_TX_ENB != 0),
+		regs_bit(cmd&RTL_CMD_RESET != 0, ", Reset", ""))
+
+	fmt.Printf("0x3C: Interrupt Mask                             0x%04x\n"+
+		"     %s\n", u16(0x3c), rtl_intr_names(u16(0x3c)))
+	fmt.Printf("0x3E: Interrupt Status                           0x%04x\n"+
+		"     %s\n", u16(0x3e), rtl_intr_names(u16(0x3e)))
+
+	fmt.Printf("0x40: Tx Configuration                       0x%08x\n", tx_config)
+	rx_config := u32(0x44)
+	fmt.Printf("0x44: Rx Configuration                       0x%08x\n"+
+		"      Accept all physical:   %s\n"+
+		"      Accept physical match: %s\n"+
+		"      Accept multicast:      %s\n"+
+		"      Accept broadcast:      %s\n"+
+		"      Accept runt:           %s\n"+
+		"      Accept error:          %s\n",
+		rx_config,
+		yesno(rx_config&RTL_RXCFG_AAP != 0),
+		yesno(rx_config&RTL_RXCFG_APM != 0),
+		yesno(rx_config&RTL_RXCFG_AM != 0),
+		yesno(rx_config&RTL_RXCFG_AB != 0),
+		yesno(rx_config&RTL_RXCFG_AR != 0),
+		yesno(rx_config&RTL_RXCFG_AER != 0))
+	fmt.Printf("0x48: Timer count                            0x%08x\n", u32(0x48))
+	fmt.Printf("0x4C: Missed packet counter                    0x%06x\n",
+		u32(0x4c)&0xffffff)
+	fmt.Printf("0x50: EEPROM Command                               0x%02x\n", u8(0x50))
+	for i := 0; i < 6; i++ {
+		fmt.Printf("0x%02X: Config %d                                     0x%02x\n",
+			0x51+i, i, u8(0x51+i))
+	}
+	fmt.Printf("0x58: Timer interrupt                        0x%08x\n", u32(0x58))
+	fmt.Printf("0x5C: Multiple Interrupt Select                  0x%04x\n", u16(0x5c))
+	fmt.Printf("0x60: PHY access                             0x%08x\n", u32(0x60))
+	fmt.Printf("0x64: TBI control and status                 0x%08x\n", u32(0x64))
+	fmt.Printf("0x68: TBI Autonegotiation advertisement (ANAR)   0x%04x\n", u16(0x68))
+	fmt.Printf("0x6A: TBI Link partner ability (LPAR)            0x%04x\n", u16(0x6a))
+
+	phy := u8(0x6c)
+	speed := "unknown"
+	switch {
+	case phy&RTL_PHY_1000BPSF != 0:
+		speed = "1000Mbps"
+	case phy&RTL_PHY_100BPS != 0:
+		speed = "100Mbps"
+	case phy&RTL_PHY_10BPS != 0:
+		speed = "10Mbps"
+	}
+	fmt.Printf("0x6C: PHY status                                   0x%02x\n"+
+		"      %s%s, %s, %s duplex%s%s\n",
+		phy,
+		regs_bit(phy&RTL_PHY_TBI_ENABLE != 0, "TBI enabled, ", ""),
+		regs_bit(phy&RTL_PHY_LINK_STS != 0, "Link up", "Link down"),
+		speed,
+		regs_bit(phy&RTL_PHY_FULL_DUP != 0, "full", "half"),
+		regs_bit(phy&RTL_PHY_RX_FLOWCTL != 0, ", Rx flow control", ""),
+		regs_bit(phy&RTL_PHY_TX_FLOWCTL != 0, ", Tx flow control", ""))
+
+	fmt.Printf("0xDA: Rx packet maximum size                     0x%04x\n", u16(0xda))
+	cp_cmd := u16(0xe0)
+	fmt.Printf("0xE0: C+ Command                                 0x%04x\n"+
+		"      VLAN de-tagging:    %s\n"+
+		"      RX checksumming:    %s\n"+
+		"      PCI 64-bit DAC:     %s\n"+
+		"      PCI Multiple RW:    %s\n",
+		cp_cmd,
+		endis(cp_cmd&RTL_CPCMD_RX_VLAN != 0),
+		endis(cp_cmd&RTL_CPCMD_RX_CHKSUM != 0),
+		endis(cp_cmd&RTL_CPCMD_PCI_DAC != 0),
+		endis(cp_cmd&RTL_CPCMD_PCI_MUL_RW != 0))
+	fmt.Printf("0xE2: Interrupt Mitigation                       0x%04x\n", u16(0xe2))
+	fmt.Printf("0xE4: Rx Ring Addr                 0x%08x 0x%08x\n",
+		u32(0xe8), u32(0xe4))
+	fmt.Printf("0xEC: Early Tx threshold                           0x%02x\n", u8(0xec))
+	fmt.Printf("0xF0: Func Event                             0x%08x\n", u32(0xf0))
+	fmt.Printf("0xF4: Func Event Mask                        0x%08x\n", u32(0xf4))
+	fmt.Printf("0xF8: Func Preset State                      0x%08x\n", u32(0xf8))
+	fmt.Printf("0xFC: Func Force Event                       0x%08x\n", u32(0xfc))
+	return 0
+}
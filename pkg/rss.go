@@ -0,0 +1,438 @@
+package ethtool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// RSSConfig is the receive side scaling state needed to predict the RX
+// queue of a flow.
+type RSSConfig struct {
+	Key      []byte   // hash key
+	Indir    []uint32 // indirection table, the RX queue of each entry
+	HashFunc string   // "toeplitz" or "xor", empty if the device does not say
+}
+
+// RSSFlow is a flow to look up. Type is the flow type as -N rx-flow-hash
+// takes it, e.g. "tcp4", and sets the address family. Fields names the
+// hashed header fields with the letters of -N rx-flow-hash, e.g. "sdfn"
+// for the 4-tuple.
+type RSSFlow struct {
+	Type    string
+	SrcIP   net.IP
+	DstIP   net.IP
+	SrcPort uint16
+	DstPort uint16
+	Fields  string
+}
+
+// RSSResult is where the hash of a flow lands.
+type RSSResult struct {
+	Hash  uint32 // hash value
+	Index uint32 // indirection table entry
+	Queue uint32 // RX queue
+}
+
+// ToeplitzHash returns the Toeplitz hash of input as defined by the
+// Microsoft RSS specification. The key must be at least 4 bytes longer
+// than the input.
+func ToeplitzHash(key []byte, input []byte) uint32 {
+	hash := uint32(0)
+	/* the 32 key bits lined up with the current input bit */
+	v := binary.BigEndian.Uint32(key)
+	for i, b := range input {
+		for bit := 7; bit >= 0; bit-- {
+			if b&(1<<uint(bit)) != 0 {
+				hash ^= v
+			}
+			v <<= 1
+			if i+4 < len(key) && key[i+4]&(1<<uint(bit)) != 0 {
+				v |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// XorHash returns the XOR of the input taken as big endian 32 bit words,
+// the last word zero padded.
+func XorHash(input []byte) uint32 {
+	hash := uint32(0)
+	for i := 0; i < len(input); i += 4 {
+		var word [4]byte
+		copy(word[:], input[i:])
+		hash ^= binary.BigEndian.Uint32(word[:])
+	}
+	return hash
+}
+
+/* rss_hash_input lines up the hashed fields of a flow in the order of the
+ * RSS specification: source address, destination address, source port
+ * and destination port.
+ */
+func rss_hash_input(flow *RSSFlow) ([]byte, error) {
+	flow_type := rxflow_str_to_type(flow.Type)
+	if flow_type == 0 || flow_type == ETHER_FLOW {
+		return nil, fmt.Errorf("unknown flow type %q", flow.Type)
+	}
+	fields, ok := parse_rxfhashopts(flow.Fields)
+	if !ok {
+		return nil, fmt.Errorf("invalid hash fields %q", flow.Fields)
+	}
+	if fields&RXH_DISCARD != 0 {
+		return nil, fmt.Errorf("flows of this type are discarded")
+	}
+	if fields&(RXH_L2DA|RXH_VLAN|RXH_L3_PROTO) != 0 {
+		return nil, fmt.Errorf("only IP addresses and ports can be hashed")
+	}
+
+	/* the IPv4 flow types come first */
+	v4 := flow_type <= AH_ESP_V4_FLOW
+	ip := func(addr net.IP, what string) ([]byte, error) {
+		if addr == nil {
+			return nil, fmt.Errorf("the %s IP address is hashed but not given", what)
+		}
+		if v4 != (addr.To4() != nil) {
+			return nil, fmt.Errorf("%s IP address %v does not match flow type %s",
+				what, addr, flow.Type)
+		}
+		if v4 {
+			return addr.To4(), nil
+		}
+		return addr.To16(), nil
+	}
+
+	var input []byte
+	if fields&RXH_IP_SRC != 0 {
+		b, err := ip(flow.SrcIP, "source")
+		if err != nil {
+			return nil, err
+		}
+		input = append(input, b...)
+	}
+	if fields&RXH_IP_DST != 0 {
+		b, err := ip(flow.DstIP, "destination")
+		if err != nil {
+			return nil, err
+		}
+		input = append(input, b...)
+	}
+	var port [2]byte
+	if fields&RXH_L4_B_0_1 != 0 {
+		binary.BigEndian.PutUint16(port[:], flow.SrcPort)
+		input = append(input, port[:]...)
+	}
+	if fields&RXH_L4_B_2_3 != 0 {
+		binary.BigEndian.PutUint16(port[:], flow.DstPort)
+		input = append(input, port[:]...)
+	}
+	return input, nil
+}
+
+// RSSLookup computes the hash of flow with the key and hash function of
+// cfg and the queue it is steered to. The indirection table entry is the
+// hash modulo the table size, the low order bits of the hash for the
+// power of two sizes devices use.
+func RSSLookup(cfg RSSConfig, flow RSSFlow) (RSSResult, error) {
+	if len(cfg.Indir) == 0 {
+		return RSSResult{}, fmt.Errorf("no indirection table")
+	}
+	input, err := rss_hash_input(&flow)
+	if err != nil {
+		return RSSResult{}, err
+	}
+
+	var res RSSResult
+	switch cfg.HashFunc {
+	case "", "toeplitz":
+		if len(cfg.Key) < len(input)+4 {
+			return RSSResult{}, fmt.Errorf("hash key of %d bytes is too short for %d bytes of input",
+				len(cfg.Key), len(input))
+		}
+		res.Hash = ToeplitzHash(cfg.Key, input)
+	case "xor":
+		res.Hash = XorHash(input)
+	default:
+		return RSSResult{}, fmt.Errorf("hash function %s cannot be simulated", cfg.HashFunc)
+	}
+	res.Index = res.Hash % uint32(len(cfg.Indir))
+	res.Queue = cfg.Indir[res.Index]
+	return res, nil
+}
+
+// RSSConfig returns the hash key, indirection table and hash function of
+// an RSS context, 0 being the default one.
+func (d *Device) RSSConfig(context uint32) (RSSConfig, error) {
+	rss_head := ethtool_rxfh{cmd: ETHTOOL_GRSSH, rss_context: context}
+	err := send_ioctl(d.ctx, uintptr(unsafe.Pointer(&rss_head)))
+	if err != nil {
+		return RSSConfig{}, fmt.Errorf("cannot get RX flow hash indir size and key size: %w", err)
+	}
+
+	rss := ethtool_rxfh{
+		cmd:         ETHTOOL_GRSSH,
+		rss_context: context,
+		indir_size:  rss_head.indir_size,
+		key_size:    rss_head.key_size,
+	}
+	err = send_ioctl(d.ctx, uintptr(unsafe.Pointer(&rss)))
+	if err != nil {
+		return RSSConfig{}, fmt.Errorf("cannot get RX flow hash configuration: %w", err)
+	}
+
+	var cfg RSSConfig
+	cfg.Indir = append(cfg.Indir, rss.rss_config[:rss.indir_size]...)
+	config := (*[MAX_DATA_BUF * 4]byte)(unsafe.Pointer(&rss.rss_config[0]))
+	cfg.Key = append(cfg.Key, config[rss.indir_size*4:(rss.indir_size*4+rss.key_size)]...)
+	if rss.hfunc != 0 {
+		hfuncs := get_stringset(d.ctx, ETH_SS_RSS_HASH_FUNCS, 0, 1)
+		for i := uint32(0); hfuncs != nil && i < hfuncs.len; i++ {
+			if rss.hfunc&(1<<i) != 0 {
+				cfg.HashFunc = cstring(hfuncs.data[i*ETH_GSTRING_LEN : (i+1)*ETH_GSTRING_LEN])
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// RxFlowHashFields returns the header fields hashed for a flow type such
+// as "tcp4", with the letters of -N rx-flow-hash.
+func (d *Device) RxFlowHashFields(flowType string, context uint32) (string, error) {
+	flow_type := rxflow_str_to_type(flowType)
+	if flow_type == 0 {
+		return "", fmt.Errorf("unknown flow type %s", flowType)
+	}
+	nfccmd := ethtool_rxnfc{
+		cmd:       ETHTOOL_GRXFH,
+		flow_type: uint32(flow_type),
+	}
+	if context != 0 {
+		nfccmd.flow_type |= FLOW_RSS
+		nfccmd.rule_cnt = context
+	}
+	err := send_ioctl(d.ctx, uintptr(unsafe.Pointer(&nfccmd)))
+	if err != nil {
+		return "", fmt.Errorf("cannot get RX network flow hashing options: %w", err)
+	}
+	s := ""
+	for _, o := range rxfhash_opts {
+		if nfccmd.data&o.flag != 0 {
+			s += string(o.letter)
+		}
+	}
+	return s, nil
+}
+
+/* rss_config_from_dump reads back the output of ethtool -x saved to a
+ * file: the indirection table rows, the hash key line and the hash
+ * functions marked on.
+ */
+func rss_config_from_dump(r io.Reader) (RSSConfig, error) {
+	var cfg RSSConfig
+	section := ""
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "RX flow hash indirection table"):
+			section = "indir"
+			continue
+		case line == "RSS hash key:":
+			section = "hkey"
+			continue
+		case line == "RSS hash function:":
+			section = "hfunc"
+			continue
+		case line == "":
+			continue
+		}
+
+		switch section {
+		case "indir":
+			words := strings.Fields(line)
+			if len(words) < 2 || !strings.HasSuffix(words[0], ":") {
+				continue
+			}
+			for _, w := range words[1:] {
+				v, err := strconv.ParseUint(w, 10, 32)
+				if err != nil {
+					return cfg, fmt.Errorf("invalid indirection table entry %q", w)
+				}
+				cfg.Indir = append(cfg.Indir, uint32(v))
+			}
+		case "hkey":
+			if !strings.Contains(line, ":") {
+				continue
+			}
+			for _, w := range strings.Split(line, ":") {
+				v, err := strconv.ParseUint(w, 16, 8)
+				if err != nil {
+					return cfg, fmt.Errorf("invalid hash key byte %q", w)
+				}
+				cfg.Key = append(cfg.Key, uint8(v))
+			}
+			section = ""
+		case "hfunc":
+			words := strings.Fields(line)
+			if len(words) == 2 && words[1] == "on" {
+				cfg.HashFunc = strings.TrimSuffix(words[0], ":")
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, err
+	}
+	if len(cfg.Indir) == 0 {
+		return cfg, fmt.Errorf("no indirection table found")
+	}
+	return cfg, nil
+}
+
+/* rss_default_fields are the fields assumed hashed offline, the 4-tuple
+ * for flows with ports and the addresses for the others.
+ */
+func rss_default_fields(flow_type int) string {
+	switch flow_type {
+	case TCP_V4_FLOW, UDP_V4_FLOW, SCTP_V4_FLOW,
+		TCP_V6_FLOW, UDP_V6_FLOW, SCTP_V6_FLOW:
+		return "sdfn"
+	}
+	return "sd"
+}
+
+func do_rss_lookup(ctx *cmd_context) int {
+	if ctx.argc < 2 {
+		return -1
+	}
+
+	devname := ctx.argp[0]
+	dump_file := ""
+	argp := ctx.argp[1:]
+	if devname == "file" {
+		dump_file = argp[0]
+		argp = argp[1:]
+	}
+	if len(argp) < 1 {
+		return -1
+	}
+	flow_name := argp[0]
+	flow_type := rxflow_str_to_type(flow_name)
+	if flow_type == 0 || flow_type == ETHER_FLOW {
+		fmt.Printf("Unknown flow type %s\n", flow_name)
+		return 1
+	}
+
+	flow := RSSFlow{Type: flow_name}
+	context := uint32(0)
+	hfunc := ""
+	fields_seen := false
+	for i := 1; i < len(argp); i += 2 {
+		if i+1 >= len(argp) {
+			return -1
+		}
+		val := argp[i+1]
+		switch argp[i] {
+		case "src-ip", "dst-ip":
+			ip := net.ParseIP(val)
+			if ip == nil {
+				fmt.Printf("Invalid %s value: %s\n", argp[i], val)
+				return 1
+			}
+			if argp[i] == "src-ip" {
+				flow.SrcIP = ip
+			} else {
+				flow.DstIP = ip
+			}
+		case "src-port", "dst-port":
+			port, err := strconv.ParseUint(val, 0, 16)
+			if err != nil {
+				fmt.Printf("Invalid %s value: %s\n", argp[i], val)
+				return 1
+			}
+			if argp[i] == "src-port" {
+				flow.SrcPort = uint16(port)
+			} else {
+				flow.DstPort = uint16(port)
+			}
+		case "context":
+			v, err := strconv.ParseUint(val, 0, 32)
+			if err != nil {
+				return -1
+			}
+			context = uint32(v)
+		case "fields":
+			flow.Fields = val
+			fields_seen = true
+		case "hfunc":
+			hfunc = val
+		default:
+			return -1
+		}
+	}
+
+	var cfg RSSConfig
+	if dump_file != "" {
+		f, err := os.Open(dump_file)
+		if err != nil {
+			fmt.Printf("Cannot open RSS configuration: %v\n", err)
+			return 1
+		}
+		cfg, err = rss_config_from_dump(f)
+		f.Close()
+		if err != nil {
+			fmt.Printf("Cannot read RSS configuration from %s: %v\n", dump_file, err)
+			return 1
+		}
+		if !fields_seen {
+			flow.Fields = rss_default_fields(flow_type)
+		}
+	} else {
+		d, err := Open(devname)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return 70
+		}
+		defer d.Close()
+		cfg, err = d.RSSConfig(context)
+		if err != nil {
+			fmt.Printf("%v\n", err)
+			return 1
+		}
+		if !fields_seen {
+			flow.Fields, err = d.RxFlowHashFields(flow_name, context)
+			if err != nil {
+				fmt.Printf("%v\n", err)
+				return 1
+			}
+		}
+	}
+	if hfunc != "" {
+		cfg.HashFunc = hfunc
+	}
+
+	res, err := RSSLookup(cfg, flow)
+	if err != nil {
+		fmt.Printf("Cannot compute RSS hash: %v\n", err)
+		return 1
+	}
+
+	hfunc = cfg.HashFunc
+	if hfunc == "" {
+		hfunc = "toeplitz (assumed)"
+	}
+	fmt.Printf("RSS lookup for %s flow:\n", flow_name)
+	fmt.Printf("\tHash function:      %s\n", hfunc)
+	fmt.Printf("\tHashed fields:      %s\n", flow.Fields)
+	fmt.Printf("\tHash value:         0x%08x\n", res.Hash)
+	fmt.Printf("\tIndirection index:  %d of %d\n", res.Index, len(cfg.Indir))
+	fmt.Printf("\tRX queue:           %d\n", res.Queue)
+	return 0
+}
@@ -0,0 +1,196 @@
+package ethtool
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+/* the verification key of the Microsoft RSS specification */
+var rss_test_key = []byte{
+	0x6d, 0x5a, 0x56, 0xda, 0x25, 0x5b, 0x0e, 0xc2,
+	0x41, 0x67, 0x25, 0x3d, 0x43, 0xa3, 0x8f, 0xb0,
+	0xd0, 0xca, 0x2b, 0xcb, 0xae, 0x7b, 0x30, 0xb4,
+	0x77, 0xcb, 0x2d, 0xa3, 0x80, 0x30, 0xf2, 0x0c,
+	0x6a, 0x42, 0xb7, 0x3b, 0xbe, 0xac, 0x01, 0xfa,
+}
+
+/* the verification flows of the Microsoft RSS specification with their
+ * hash over the addresses only and over the 4-tuple */
+var rss_test_flows = []struct {
+	tp               string
+	src, dst         string
+	sport, dport     uint16
+	ip_hash, l4_hash uint32
+}{
+	{"tcp4", "66.9.149.187", "161.142.100.80", 2794, 1766, 0x323e8fc2, 0x51ccc178},
+	{"tcp4", "199.92.111.2", "65.69.140.83", 14230, 4739, 0xd718262a, 0xc626b0ea},
+	{"tcp4", "24.19.198.95", "12.22.207.184", 12898, 38024, 0xd2d0a5de, 0x5c2b394a},
+	{"tcp4", "38.27.205.30", "209.142.163.6", 48228, 2217, 0x82989176, 0xafc7327f},
+	{"tcp4", "153.39.163.191", "202.188.127.2", 44251, 1303, 0x5d1809c5, 0x10e828a2},
+	{"tcp6", "3ffe:2501:200:1fff::7", "3ffe:2501:200:3::1", 2794, 1766, 0x2cc18cd5, 0x40207d3d},
+	{"tcp6", "3ffe:501:8::260:97ff:fe40:efab", "ff02::1", 14230, 4739, 0x0f0c461c, 0xdde51bbf},
+	{"tcp6", "3ffe:1900:4545:3:200:f8ff:fe21:67cf", "fe80::200:f8ff:fe21:67cf", 44251, 38024, 0x4b61e985, 0x02d1feef},
+}
+
+func TestToeplitzHash(t *testing.T) {
+	for _, f := range rss_test_flows {
+		flow := RSSFlow{
+			Type:    f.tp,
+			SrcIP:   net.ParseIP(f.src),
+			DstIP:   net.ParseIP(f.dst),
+			SrcPort: f.sport,
+			DstPort: f.dport,
+		}
+		for _, c := range []struct {
+			fields string
+			hash   uint32
+		}{
+			{"sd", f.ip_hash},
+			{"sdfn", f.l4_hash},
+		} {
+			flow.Fields = c.fields
+			input, err := rss_hash_input(&flow)
+			if err != nil {
+				t.Fatalf("%s -> %s %s: %v", f.src, f.dst, c.fields, err)
+			}
+			if hash := ToeplitzHash(rss_test_key, input); hash != c.hash {
+				t.Errorf("%s -> %s %s: hash 0x%08x, want 0x%08x",
+					f.src, f.dst, c.fields, hash, c.hash)
+			}
+		}
+	}
+}
+
+func TestXorHash(t *testing.T) {
+	tests := []struct {
+		input []byte
+		hash  uint32
+	}{
+		{nil, 0},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, 0x01020304},
+		{[]byte{0x01, 0x02, 0x03, 0x04, 0x0a, 0x0b, 0x0c, 0x0d}, 0x0b090f09},
+		{[]byte{0x01, 0x02, 0x03, 0x04, 0x05}, 0x04020304},
+		{[]byte{0xff, 0xff}, 0xffff0000},
+	}
+	for _, tt := range tests {
+		if hash := XorHash(tt.input); hash != tt.hash {
+			t.Errorf("XorHash(% x) = 0x%08x, want 0x%08x", tt.input, hash, tt.hash)
+		}
+	}
+}
+
+/* output of ethtool -x on a 4 queue NIC keyed with the verification key */
+const rss_test_dump = `RX flow hash indirection table for eth0 with 4 RX ring(s):
+    0:      0     1     2     3     0     1     2     3
+    8:      0     1     2     3     0     1     2     3
+   16:      3     2     1     0     3     2     1     0
+   24:      3     2     1     0     3     2     1     0
+RSS hash key:
+6d:5a:56:da:25:5b:0e:c2:41:67:25:3d:43:a3:8f:b0:d0:ca:2b:cb:ae:7b:30:b4:77:cb:2d:a3:80:30:f2:0c:6a:42:b7:3b:be:ac:01:fa
+RSS hash function:
+    toeplitz: on
+    xor: off
+    crc32: off
+`
+
+func TestRSSConfigFromDump(t *testing.T) {
+	cfg, err := rss_config_from_dump(strings.NewReader(rss_test_dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Indir) != 32 {
+		t.Fatalf("indirection table of %d entries, want 32", len(cfg.Indir))
+	}
+	for i, q := range cfg.Indir {
+		want := uint32(i % 4)
+		if i >= 16 {
+			want = 3 - want
+		}
+		if q != want {
+			t.Errorf("entry %d is queue %d, want %d", i, q, want)
+		}
+	}
+	if string(cfg.Key) != string(rss_test_key) {
+		t.Errorf("key % x, want % x", cfg.Key, rss_test_key)
+	}
+	if cfg.HashFunc != "toeplitz" {
+		t.Errorf("hash function %q, want toeplitz", cfg.HashFunc)
+	}
+
+	/* 0x51ccc178 lands on entry 24 */
+	res, err := RSSLookup(cfg, RSSFlow{
+		Type:    "tcp4",
+		SrcIP:   net.ParseIP("66.9.149.187"),
+		DstIP:   net.ParseIP("161.142.100.80"),
+		SrcPort: 2794,
+		DstPort: 1766,
+		Fields:  "sdfn",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Hash != 0x51ccc178 || res.Index != 24 || res.Queue != 3 {
+		t.Errorf("got hash 0x%08x entry %d queue %d, want 0x51ccc178 entry 24 queue 3",
+			res.Hash, res.Index, res.Queue)
+	}
+}
+
+func TestRSSConfigFromDumpErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		dump string
+	}{
+		{"empty", ""},
+		{"no table", "RSS hash key:\n6d:5a:56:da\n"},
+		{"bad entry", "RX flow hash indirection table for eth0 with 2 RX ring(s):\n    0:      0     x\n"},
+		{"bad key", "RX flow hash indirection table for eth0 with 2 RX ring(s):\n    0:      0     1\nRSS hash key:\n6d:zz\n"},
+	}
+	for _, tt := range tests {
+		if _, err := rss_config_from_dump(strings.NewReader(tt.dump)); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+func TestRSSLookupErrors(t *testing.T) {
+	cfg := RSSConfig{Key: rss_test_key, Indir: []uint32{0, 1, 2, 3}}
+	v4 := net.ParseIP("66.9.149.187")
+	v6 := net.ParseIP("3ffe:2501:200:3::1")
+
+	tests := []struct {
+		name string
+		cfg  RSSConfig
+		flow RSSFlow
+	}{
+		{"short key", RSSConfig{Key: rss_test_key[:12], Indir: cfg.Indir},
+			RSSFlow{Type: "tcp4", SrcIP: v4, DstIP: v4, Fields: "sdfn"}},
+		{"short key ipv6", RSSConfig{Key: rss_test_key[:36], Indir: cfg.Indir},
+			RSSFlow{Type: "tcp6", SrcIP: v6, DstIP: v6, Fields: "sdfn"}},
+		{"mixed families", cfg, RSSFlow{Type: "udp4", SrcIP: v4, DstIP: v6, Fields: "sd"}},
+		{"mixed families reversed", cfg, RSSFlow{Type: "udp6", SrcIP: v6, DstIP: v4, Fields: "sd"}},
+		{"ipv4 on ipv6 flow", cfg, RSSFlow{Type: "tcp6", SrcIP: v4, DstIP: v4, Fields: "sdfn"}},
+		{"ipv6 on ipv4 flow", cfg, RSSFlow{Type: "esp4", SrcIP: v6, DstIP: v6, Fields: "sd"}},
+		{"no flow type", cfg, RSSFlow{SrcIP: v4, DstIP: v4, Fields: "sd"}},
+		{"ether flow", cfg, RSSFlow{Type: "ether", SrcIP: v4, DstIP: v4, Fields: "sd"}},
+		{"discard", cfg, RSSFlow{Type: "tcp4", SrcIP: v4, DstIP: v4, Fields: "r"}},
+		{"missing address", cfg, RSSFlow{Type: "tcp4", SrcIP: v4, Fields: "sd"}},
+		{"unhashable field", cfg, RSSFlow{Type: "tcp4", SrcIP: v4, DstIP: v4, Fields: "sdv"}},
+		{"bad field", cfg, RSSFlow{Type: "tcp4", SrcIP: v4, DstIP: v4, Fields: "sdq"}},
+		{"no table", RSSConfig{Key: rss_test_key},
+			RSSFlow{Type: "tcp4", SrcIP: v4, DstIP: v4, Fields: "sd"}},
+		{"unknown function", RSSConfig{Key: rss_test_key, Indir: cfg.Indir, HashFunc: "crc32"},
+			RSSFlow{Type: "tcp4", SrcIP: v4, DstIP: v4, Fields: "sd"}},
+	}
+	for _, tt := range tests {
+		if _, err := RSSLookup(tt.cfg, tt.flow); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+
+	/* the xor hash needs no key */
+	if _, err := RSSLookup(RSSConfig{Indir: cfg.Indir, HashFunc: "xor"},
+		RSSFlow{Type: "tcp4", SrcIP: v4, DstIP: v4, Fields: "sdfn"}); err != nil {
+		t.Errorf("xor: %v", err)
+	}
+}
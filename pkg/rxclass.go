@@ -4,6 +4,9 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
+	"syscall"
 	"unsafe"
 )
 
@@ -419,7 +422,7 @@ func rxclass_get_dev_info(ctx *cmd_context, count *uint32,
 		*driver_select = int(nfccmd.data & RX_CLS_LOC_SPECIAL)
 	}
 	if err != nil {
-		fmt.Printf("rxclass: Cannot get RX class rule count")
+		fmt.Printf("rxclass: Cannot get RX class rule count\n")
 	}
 	return err
 }
@@ -473,3 +476,522 @@ func rxclass_rule_getall(ctx *cmd_context) error {
 
 	return err
 }
+
+/*
+ * Rule parsing: each option of a flow type names a field of the flow spec
+ * by its offset, the mask of the field is at moffset or -1 if it has none.
+ */
+
+const (
+	OPT_U8 = iota
+	OPT_U32
+	OPT_LOC
+	OPT_U64
+	OPT_RING_VF
+	OPT_RING_QUEUE
+	OPT_BE16
+	OPT_BE32
+	OPT_BE64
+	OPT_IP4
+	OPT_IP6
+	OPT_MAC
+)
+
+const (
+	NFC_FLAG_RING       = 0x0001
+	NFC_FLAG_LOC        = 0x0002
+	NFC_FLAG_SADDR      = 0x0004
+	NFC_FLAG_DADDR      = 0x0008
+	NFC_FLAG_SPORT      = 0x0010
+	NFC_FLAG_SPI        = 0x0010 /* shares the bytes of the ports */
+	NFC_FLAG_DPORT      = 0x0020
+	NFC_FLAG_TOS        = 0x0040
+	NFC_FLAG_PROTO      = 0x0080
+	NTUPLE_FLAG_VLAN    = 0x0100
+	NTUPLE_FLAG_UDEF    = 0x0200
+	NTUPLE_FLAG_VETH    = 0x0400
+	NFC_FLAG_MAC_ADDR   = 0x0800
+	NFC_FLAG_RING_VF    = 0x1000
+	NFC_FLAG_RING_QUEUE = 0x2000
+)
+
+type rule_opts struct {
+	name    string
+	tp      int
+	flag    uint32
+	offset  int
+	moffset int
+}
+
+var (
+	nfc_h_u   = int(unsafe.Offsetof(ethtool_rx_flow_spec{}.h_u))
+	nfc_m_u   = int(unsafe.Offsetof(ethtool_rx_flow_spec{}.m_u))
+	nfc_h_ext = int(unsafe.Offsetof(ethtool_rx_flow_spec{}.h_ext))
+	nfc_m_ext = int(unsafe.Offsetof(ethtool_rx_flow_spec{}.m_ext))
+)
+
+/* nfc_field is an option on the flow union at byte off of the spec */
+func nfc_field(name string, tp int, flag uint32, off int) rule_opts {
+	return rule_opts{name, tp, flag, nfc_h_u + off, nfc_m_u + off}
+}
+
+/* nfc_ext_field is an option on the flow extension at byte off */
+func nfc_ext_field(name string, tp int, flag uint32, off int) rule_opts {
+	return rule_opts{name, tp, flag, nfc_h_ext + off, nfc_m_ext + off}
+}
+
+/* the action, location and extension options shared by all flow types */
+func rule_nfc_common(mac bool) []rule_opts {
+	ring := int(unsafe.Offsetof(ethtool_rx_flow_spec{}.ring_cookie))
+	loc := int(unsafe.Offsetof(ethtool_rx_flow_spec{}.location))
+	opts := []rule_opts{
+		{"action", OPT_U64, NFC_FLAG_RING, ring, -1},
+		{"vf", OPT_RING_VF, NFC_FLAG_RING_VF, ring, -1},
+		{"queue", OPT_RING_QUEUE, NFC_FLAG_RING_QUEUE, ring, -1},
+		{"loc", OPT_LOC, NFC_FLAG_LOC, loc, -1},
+		nfc_ext_field("vlan-etype", OPT_BE16, NTUPLE_FLAG_VETH, 8),
+		nfc_ext_field("vlan", OPT_BE16, NTUPLE_FLAG_VLAN, 10),
+		nfc_ext_field("user-def", OPT_BE64, NTUPLE_FLAG_UDEF, 12),
+	}
+	if mac {
+		opts = append(opts,
+			nfc_ext_field("dst-mac", OPT_MAC, NFC_FLAG_MAC_ADDR, 2))
+	}
+	return opts
+}
+
+var rule_nfc_tcp_ip4 = append([]rule_opts{
+	nfc_field("src-ip", OPT_IP4, NFC_FLAG_SADDR, 0),
+	nfc_field("dst-ip", OPT_IP4, NFC_FLAG_DADDR, 4),
+	nfc_field("tos", OPT_U8, NFC_FLAG_TOS, 12),
+	nfc_field("src-port", OPT_BE16, NFC_FLAG_SPORT, 8),
+	nfc_field("dst-port", OPT_BE16, NFC_FLAG_DPORT, 10),
+}, rule_nfc_common(true)...)
+
+var rule_nfc_esp_ip4 = append([]rule_opts{
+	nfc_field("src-ip", OPT_IP4, NFC_FLAG_SADDR, 0),
+	nfc_field("dst-ip", OPT_IP4, NFC_FLAG_DADDR, 4),
+	nfc_field("tos", OPT_U8, NFC_FLAG_TOS, 12),
+	nfc_field("spi", OPT_BE32, NFC_FLAG_SPI, 8),
+}, rule_nfc_common(true)...)
+
+var rule_nfc_usr_ip4 = append([]rule_opts{
+	nfc_field("src-ip", OPT_IP4, NFC_FLAG_SADDR, 0),
+	nfc_field("dst-ip", OPT_IP4, NFC_FLAG_DADDR, 4),
+	nfc_field("tos", OPT_U8, NFC_FLAG_TOS, 12),
+	nfc_field("l4proto", OPT_U8, NFC_FLAG_PROTO, 14),
+	nfc_field("l4data", OPT_BE32, NFC_FLAG_SPI, 8),
+	nfc_field("spi", OPT_BE32, NFC_FLAG_SPI, 8),
+	nfc_field("src-port", OPT_BE16, NFC_FLAG_SPORT, 8),
+	nfc_field("dst-port", OPT_BE16, NFC_FLAG_DPORT, 10),
+}, rule_nfc_common(true)...)
+
+var rule_nfc_tcp_ip6 = append([]rule_opts{
+	nfc_field("src-ip", OPT_IP6, NFC_FLAG_SADDR, 0),
+	nfc_field("dst-ip", OPT_IP6, NFC_FLAG_DADDR, 16),
+	nfc_field("tclass", OPT_U8, NFC_FLAG_TOS, 36),
+	nfc_field("src-port", OPT_BE16, NFC_FLAG_SPORT, 32),
+	nfc_field("dst-port", OPT_BE16, NFC_FLAG_DPORT, 34),
+}, rule_nfc_common(true)...)
+
+var rule_nfc_esp_ip6 = append([]rule_opts{
+	nfc_field("src-ip", OPT_IP6, NFC_FLAG_SADDR, 0),
+	nfc_field("dst-ip", OPT_IP6, NFC_FLAG_DADDR, 16),
+	nfc_field("tclass", OPT_U8, NFC_FLAG_TOS, 36),
+	nfc_field("spi", OPT_BE32, NFC_FLAG_SPI, 32),
+}, rule_nfc_common(true)...)
+
+var rule_nfc_usr_ip6 = append([]rule_opts{
+	nfc_field("src-ip", OPT_IP6, NFC_FLAG_SADDR, 0),
+	nfc_field("dst-ip", OPT_IP6, NFC_FLAG_DADDR, 16),
+	nfc_field("tclass", OPT_U8, NFC_FLAG_TOS, 36),
+	nfc_field("l4proto", OPT_U8, NFC_FLAG_PROTO, 37),
+	nfc_field("l4data", OPT_BE32, NFC_FLAG_SPI, 32),
+	nfc_field("spi", OPT_BE32, NFC_FLAG_SPI, 32),
+	nfc_field("src-port", OPT_BE16, NFC_FLAG_SPORT, 32),
+	nfc_field("dst-port", OPT_BE16, NFC_FLAG_DPORT, 34),
+}, rule_nfc_common(true)...)
+
+var rule_nfc_ether = append([]rule_opts{
+	nfc_field("src", OPT_MAC, NFC_FLAG_SADDR, 6),
+	nfc_field("dst", OPT_MAC, NFC_FLAG_DADDR, 0),
+	nfc_field("proto", OPT_BE16, NFC_FLAG_PROTO, 12),
+}, rule_nfc_common(false)...)
+
+var rule_flow_types = []struct {
+	name      string
+	flow_type uint32
+	opts      []rule_opts
+}{
+	{"ether", ETHER_FLOW, rule_nfc_ether},
+	{"ip4", IPV4_USER_FLOW, rule_nfc_usr_ip4},
+	{"tcp4", TCP_V4_FLOW, rule_nfc_tcp_ip4},
+	{"udp4", UDP_V4_FLOW, rule_nfc_tcp_ip4},
+	{"sctp4", SCTP_V4_FLOW, rule_nfc_tcp_ip4},
+	{"ah4", AH_V4_FLOW, rule_nfc_esp_ip4},
+	{"esp4", ESP_V4_FLOW, rule_nfc_esp_ip4},
+	{"ip6", IPV6_USER_FLOW, rule_nfc_usr_ip6},
+	{"tcp6", TCP_V6_FLOW, rule_nfc_tcp_ip6},
+	{"udp6", UDP_V6_FLOW, rule_nfc_tcp_ip6},
+	{"sctp6", SCTP_V6_FLOW, rule_nfc_tcp_ip6},
+	{"ah6", AH_V6_FLOW, rule_nfc_esp_ip6},
+	{"esp6", ESP_V6_FLOW, rule_nfc_esp_ip6},
+}
+
+/* rxclass_parse_field parses str as an option value into the big endian
+ * bytes of the field */
+func rxclass_parse_field(str string, tp int) ([]byte, error) {
+	var b []byte
+	switch tp {
+	case OPT_U8, OPT_BE16, OPT_BE32, OPT_BE64:
+		size := map[int]int{OPT_U8: 1, OPT_BE16: 2, OPT_BE32: 4, OPT_BE64: 8}[tp]
+		val, err := strconv.ParseUint(str, 0, size*8)
+		if err != nil {
+			return nil, err
+		}
+		b = make([]byte, 8)
+		binary.BigEndian.PutUint64(b, val)
+		b = b[8-size:]
+	case OPT_IP4:
+		ip := net.ParseIP(str).To4()
+		if ip == nil {
+			return nil, syscall.EINVAL
+		}
+		b = ip
+	case OPT_IP6:
+		ip := net.ParseIP(str)
+		if ip == nil || ip.To4() != nil && !strings.Contains(str, ":") {
+			return nil, syscall.EINVAL
+		}
+		b = ip.To16()
+	case OPT_MAC:
+		mac, err := net.ParseMAC(str)
+		if err != nil || len(mac) != 6 {
+			return nil, syscall.EINVAL
+		}
+		b = mac
+	default:
+		return nil, syscall.EINVAL
+	}
+	return b, nil
+}
+
+/* rxclass_get_val stores the value of opt in the spec image p, the
+ * field is matched exactly unless a mask follows */
+func rxclass_get_val(str string, p []byte, flags *uint32, opt *rule_opts) error {
+	switch opt.tp {
+	case OPT_U32:
+		val, err := strconv.ParseUint(str, 0, 32)
+		if err != nil {
+			return err
+		}
+		*(*uint32)(unsafe.Pointer(&p[opt.offset])) = uint32(val)
+	case OPT_LOC:
+		val := uint64(RX_CLS_LOC_ANY)
+		switch str {
+		case "any":
+		case "first":
+			val = RX_CLS_LOC_FIRST
+		case "last":
+			val = RX_CLS_LOC_LAST
+		default:
+			var err error
+			val, err = strconv.ParseUint(str, 0, 31)
+			if err != nil {
+				return err
+			}
+		}
+		*(*uint32)(unsafe.Pointer(&p[opt.offset])) = uint32(val)
+	case OPT_U64:
+		/* negative actions are the special drop and wake cookies */
+		val, err := strconv.ParseInt(str, 0, 64)
+		if err != nil {
+			return err
+		}
+		*(*uint64)(unsafe.Pointer(&p[opt.offset])) = uint64(val)
+	case OPT_RING_VF:
+		val, err := strconv.ParseUint(str, 0, 8)
+		if err != nil || val+1 > ETHTOOL_RX_FLOW_SPEC_RING_VF>>
+			ETHTOOL_RX_FLOW_SPEC_RING_VF_OFF {
+			return syscall.EINVAL
+		}
+		/* vf 0 in the cookie is the main function */
+		cookie := (*uint64)(unsafe.Pointer(&p[opt.offset]))
+		*cookie &^= ETHTOOL_RX_FLOW_SPEC_RING_VF
+		*cookie |= (val + 1) << ETHTOOL_RX_FLOW_SPEC_RING_VF_OFF
+	case OPT_RING_QUEUE:
+		val, err := strconv.ParseUint(str, 0, 32)
+		if err != nil {
+			return err
+		}
+		cookie := (*uint64)(unsafe.Pointer(&p[opt.offset]))
+		*cookie &^= ETHTOOL_RX_FLOW_SPEC_RING
+		*cookie |= val
+	default:
+		b, err := rxclass_parse_field(str, opt.tp)
+		if err != nil {
+			return err
+		}
+		copy(p[opt.offset:], b)
+		for i := range b {
+			p[opt.moffset+i] = 0xff
+		}
+	}
+	*flags |= opt.flag
+	return nil
+}
+
+/* rxclass_get_mask stores the mask of opt in the spec image p, bits set
+ * on the command line are the ones to ignore */
+func rxclass_get_mask(str string, p []byte, opt *rule_opts) error {
+	if opt.moffset < 0 {
+		return syscall.EINVAL
+	}
+	b, err := rxclass_parse_field(str, opt.tp)
+	if err != nil {
+		return err
+	}
+	for i := range b {
+		p[opt.moffset+i] = ^b[i]
+	}
+	return nil
+}
+
+/* rxclass_parse_ruleopts turns "flow-type" arguments, starting with the
+ * flow type name, into a flow spec and an optional RSS context */
+func rxclass_parse_ruleopts(argp []string, fsp *ethtool_rx_flow_spec,
+	rss_context *uint32) int {
+	if len(argp) < 1 {
+		fmt.Printf("Add rule, invalid syntax\n")
+		return -1
+	}
+
+	var options []rule_opts
+	flow_type := uint32(0)
+	for _, ft := range rule_flow_types {
+		if ft.name == argp[0] {
+			flow_type = ft.flow_type
+			options = ft.opts
+		}
+	}
+	if options == nil {
+		fmt.Printf("Unknown flow type\n")
+		fmt.Printf("Add rule, invalid syntax\n")
+		return -1
+	}
+
+	*fsp = ethtool_rx_flow_spec{}
+	p := (*[unsafe.Sizeof(ethtool_rx_flow_spec{})]byte)(unsafe.Pointer(fsp))[:]
+	fsp.flow_type = flow_type
+	fsp.location = RX_CLS_LOC_ANY
+
+	flags := uint32(0)
+	for i := 1; i < len(argp); {
+		/* the RSS context is not part of the flow spec */
+		if argp[i] == "context" {
+			if i+1 >= len(argp) {
+				fmt.Printf("'context' missing value\n")
+				return -1
+			}
+			val, err := strconv.ParseUint(argp[i+1], 0, 32)
+			if err != nil {
+				fmt.Printf("Invalid context value: %s\n", argp[i+1])
+				return -1
+			}
+			*rss_context = uint32(val)
+			fsp.flow_type |= FLOW_RSS
+			i += 2
+			continue
+		}
+
+		var opt *rule_opts
+		for idx := range options {
+			if options[idx].name == argp[i] {
+				opt = &options[idx]
+				break
+			}
+		}
+		if opt == nil {
+			fmt.Printf("Add rule, invalid rule option: %s\n", argp[i])
+			return -1
+		}
+		if i+1 >= len(argp) {
+			fmt.Printf("Add rule, missing value for %s\n", opt.name)
+			return -1
+		}
+		if flags&opt.flag != 0 {
+			fmt.Printf("Add rule, %s is already set\n", opt.name)
+			return -1
+		}
+		/* a rule steers either to an action or to a vf and queue */
+		if opt.flag&NFC_FLAG_RING != 0 &&
+			flags&(NFC_FLAG_RING_VF|NFC_FLAG_RING_QUEUE) != 0 ||
+			opt.flag&(NFC_FLAG_RING_VF|NFC_FLAG_RING_QUEUE) != 0 &&
+				flags&NFC_FLAG_RING != 0 {
+			fmt.Printf("Add rule, action and vf/queue are exclusive\n")
+			return -1
+		}
+		if err := rxclass_get_val(argp[i+1], p, &flags, opt); err != nil {
+			fmt.Printf("Invalid %s value: %s\n", opt.name, argp[i+1])
+			return -1
+		}
+		i += 2
+
+		if i < len(argp) && (argp[i] == "m" || argp[i] == opt.name+"-mask") {
+			if i+1 >= len(argp) {
+				fmt.Printf("Add rule, missing mask for %s\n", opt.name)
+				return -1
+			}
+			if err := rxclass_get_mask(argp[i+1], p, opt); err != nil {
+				fmt.Printf("Invalid %s mask: %s\n", opt.name, argp[i+1])
+				return -1
+			}
+			i += 2
+		}
+	}
+
+	if flow_type == IPV4_USER_FLOW {
+		p[nfc_h_u+13] = ETH_RX_NFC_IP4 /* usr_ip4_spec.ip_ver */
+	}
+	if flags&(NTUPLE_FLAG_VLAN|NTUPLE_FLAG_UDEF|NTUPLE_FLAG_VETH) != 0 {
+		fsp.flow_type |= FLOW_EXT
+	}
+	if flags&NFC_FLAG_MAC_ADDR != 0 {
+		fsp.flow_type |= FLOW_MAC_EXT
+	}
+	return 0
+}
+
+/*
+ * Rule manager: drivers that do not pick rule locations themselves get
+ * one chosen from the free slots of their rule table.
+ */
+type rmgr_ctrl struct {
+	driver_select bool     /* driver picks the location of new rules */
+	slot          []uint64 /* bitmap of the locations in use */
+	n_rules       uint32
+	size          uint32
+}
+
+func (rmgr *rmgr_ctrl) slot_in_use(loc uint32) bool {
+	return rmgr.slot[loc/64]&(1<<(loc%64)) != 0
+}
+
+func rmgr_init(ctx *cmd_context, rmgr *rmgr_ctrl) error {
+	var driver_select int
+
+	err := rxclass_get_dev_info(ctx, &rmgr.n_rules, &driver_select)
+	if err != nil {
+		return err
+	}
+	rmgr.driver_select = driver_select != 0
+	if rmgr.driver_select {
+		return nil
+	}
+
+	nfccmd := ethtool_rxnfc{
+		cmd:      ETHTOOL_GRXCLSRLALL,
+		rule_cnt: rmgr.n_rules,
+	}
+	err = send_ioctl(ctx, uintptr(unsafe.Pointer(&nfccmd)))
+	if err != nil {
+		fmt.Printf("rmgr: Cannot get RX class rules: %v\n", err)
+		return err
+	}
+
+	/* GRXCLSRLALL reports the size of the rule table in data */
+	rmgr.size = uint32(nfccmd.data)
+	if rmgr.size == 0 || rmgr.size < rmgr.n_rules {
+		fmt.Printf("rmgr: Invalid RX class rules table size\n")
+		return syscall.EINVAL
+	}
+
+	rmgr.slot = make([]uint64, (rmgr.size+63)/64)
+	for _, loc := range nfccmd.rule_locs[:rmgr.n_rules] {
+		if loc >= rmgr.size {
+			fmt.Printf("rmgr: Invalid RX class rule location\n")
+			return syscall.EINVAL
+		}
+		rmgr.slot[loc/64] |= 1 << (loc % 64)
+	}
+	return nil
+}
+
+/* rmgr_add picks a free location for a rule asking for a special one */
+func rmgr_add(rmgr *rmgr_ctrl, fsp *ethtool_rx_flow_spec) error {
+	loc := rmgr.size
+	switch fsp.location {
+	case RX_CLS_LOC_ANY, RX_CLS_LOC_LAST:
+		/* search from the end, the lowest priority slot, so a new
+		 * rule does not take precedence over the existing ones */
+		for i := rmgr.size; i > 0; i-- {
+			if !rmgr.slot_in_use(i - 1) {
+				loc = i - 1
+				break
+			}
+		}
+	case RX_CLS_LOC_FIRST:
+		for i := uint32(0); i < rmgr.size; i++ {
+			if !rmgr.slot_in_use(i) {
+				loc = i
+				break
+			}
+		}
+	}
+	if loc >= rmgr.size {
+		fmt.Printf("rmgr: Cannot find appropriate slot to insert rule, "+
+			"all %d locations are in use\n", rmgr.size)
+		return syscall.ENOSPC
+	}
+	fsp.location = loc
+	return nil
+}
+
+/* rmgr_set_location resolves a special rule location on drivers that
+ * leave the choice to user space */
+func rmgr_set_location(ctx *cmd_context, fsp *ethtool_rx_flow_spec) error {
+	var rmgr rmgr_ctrl
+
+	err := rmgr_init(ctx, &rmgr)
+	if err != nil || rmgr.driver_select {
+		return err
+	}
+	return rmgr_add(&rmgr, fsp)
+}
+
+func rxclass_rule_ins(ctx *cmd_context, fsp *ethtool_rx_flow_spec,
+	rss_context uint32) error {
+	loc := fsp.location
+
+	if loc&RX_CLS_LOC_SPECIAL != 0 {
+		err := rmgr_set_location(ctx, fsp)
+		if err != nil {
+			return err
+		}
+	}
+
+	nfccmd := ethtool_rxnfc{
+		cmd:      ETHTOOL_SRXCLSRLINS,
+		fs:       *fsp,
+		rule_cnt: rss_context,
+	}
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&nfccmd)))
+	if err != nil {
+		fmt.Printf("rmgr: Cannot insert RX class rule: %v\n", err)
+		return err
+	}
+	if loc&RX_CLS_LOC_SPECIAL != 0 {
+		fmt.Printf("Added rule with ID %d\n", nfccmd.fs.location)
+	}
+	return nil
+}
+
+func rxclass_rule_del(ctx *cmd_context, loc uint32) error {
+	nfccmd := ethtool_rxnfc{cmd: ETHTOOL_SRXCLSRLDEL}
+	nfccmd.fs.location = loc
+	err := send_ioctl(ctx, uintptr(unsafe.Pointer(&nfccmd)))
+	if err != nil {
+		fmt.Printf("rmgr: Cannot delete RX class rule: %v\n", err)
+	}
+	return err
+}
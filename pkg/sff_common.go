@@ -0,0 +1,370 @@
+package ethtool
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+/* SFF-8024 identifier, connector and encoding values shared by the
+ * SFP, QSFP and CMIS module decoders
+ */
+const (
+	SFF8024_ID_OFFSET            = 0x00
+	SFF8024_ID_UNKNOWN           = 0x00
+	SFF8024_ID_GBIC              = 0x01
+	SFF8024_ID_SOLDERED_MODULE   = 0x02
+	SFF8024_ID_SFP               = 0x03
+	SFF8024_ID_300_PIN_XBI       = 0x04
+	SFF8024_ID_XENPAK            = 0x05
+	SFF8024_ID_XFP               = 0x06
+	SFF8024_ID_XFF               = 0x07
+	SFF8024_ID_XFP_E             = 0x08
+	SFF8024_ID_XPAK              = 0x09
+	SFF8024_ID_X2                = 0x0A
+	SFF8024_ID_DWDM_SFP          = 0x0B
+	SFF8024_ID_QSFP              = 0x0C
+	SFF8024_ID_QSFP_PLUS         = 0x0D
+	SFF8024_ID_CXP               = 0x0E
+	SFF8024_ID_HD4X              = 0x0F
+	SFF8024_ID_HD8X              = 0x10
+	SFF8024_ID_QSFP28            = 0x11
+	SFF8024_ID_CXP2              = 0x12
+	SFF8024_ID_CDFP              = 0x13
+	SFF8024_ID_HD4X_FANOUT       = 0x14
+	SFF8024_ID_HD8X_FANOUT       = 0x15
+	SFF8024_ID_CDFP_S3           = 0x16
+	SFF8024_ID_MICRO_QSFP        = 0x17
+	SFF8024_ID_QSFP_DD           = 0x18
+	SFF8024_ID_OSFP              = 0x19
+	SFF8024_ID_DSFP              = 0x1B
+	SFF8024_ID_QSFP_PLUS_CMIS    = 0x1E
+	SFF8024_ID_SFP_DD_CMIS       = 0x1F
+	SFF8024_ID_SFP_PLUS_CMIS     = 0x20
+	SFF8024_ID_LAST              = SFF8024_ID_SFP_PLUS_CMIS
+	SFF8024_ID_UNALLOCATED_LAST  = 0x7F
+	SFF8024_ID_VENDOR_START      = 0x80
+	SFF8024_ID_VENDOR_LAST       = 0xFF
+	SFF8024_CTOR_UNKNOWN         = 0x00
+	SFF8024_CTOR_SC              = 0x01
+	SFF8024_CTOR_FC_STYLE_1      = 0x02
+	SFF8024_CTOR_FC_STYLE_2      = 0x03
+	SFF8024_CTOR_BNC_TNC         = 0x04
+	SFF8024_CTOR_FC_COAX         = 0x05
+	SFF8024_CTOR_FIBER_JACK      = 0x06
+	SFF8024_CTOR_LC              = 0x07
+	SFF8024_CTOR_MT_RJ           = 0x08
+	SFF8024_CTOR_MU              = 0x09
+	SFF8024_CTOR_SG              = 0x0A
+	SFF8024_CTOR_OPT_PT          = 0x0B
+	SFF8024_CTOR_MPO             = 0x0C
+	SFF8024_CTOR_MPO_2           = 0x0D
+	SFF8024_CTOR_HSDC_II         = 0x20
+	SFF8024_CTOR_COPPER_PT       = 0x21
+	SFF8024_CTOR_RJ45            = 0x22
+	SFF8024_CTOR_NO_SEPARABLE    = 0x23
+	SFF8024_CTOR_MXC_2X16        = 0x24
+	SFF8024_CTOR_CS_OPTICAL      = 0x25
+	SFF8024_CTOR_CS_OPTICAL_MINI = 0x26
+	SFF8024_CTOR_MPO_2X12        = 0x27
+	SFF8024_CTOR_MPO_1X16        = 0x28
+	SFF8024_CTOR_NO_SEP_QSFP_DD  = 0x6F
+	SFF8024_ENCODING_UNSPEC      = 0x00
+	SFF8024_ENCODING_8B10B       = 0x01
+	SFF8024_ENCODING_4B5B        = 0x02
+	SFF8024_ENCODING_NRZ         = 0x03
+	SFF8024_ENCODING_4h          = 0x04
+	SFF8024_ENCODING_5h          = 0x05
+	SFF8024_ENCODING_6h          = 0x06
+	SFF8024_ENCODING_256B        = 0x07
+	SFF8024_ENCODING_PAM4        = 0x08
+)
+
+/* Index into the sff_diags value arrays */
+const (
+	MCURR = iota
+	LWARN
+	HWARN
+	LALRM
+	HALRM
+)
+
+/* Per channel diagnostics of multi lane modules */
+type sff_channel_diags struct {
+	bias_cur uint16 /* Measured bias current in 2uA units */
+	rx_power uint16 /* Measured RX Power */
+	tx_power uint16 /* Measured TX Power */
+}
+
+/* Module diagnostics, already calibrated. Units are those of the
+ * SFF-8472 A2 page: bias 2uA, power 0.1uW, temperature 1/256 C,
+ * voltage 100uV.
+ */
+type sff_diags struct {
+	supports_dom    bool
+	supports_alarms bool
+	calibrated_ext  bool
+
+	bias_cur    [5]uint16
+	sfp_voltage [5]uint16
+	tx_power    [5]uint16
+	rx_power    [5]uint16
+	sfp_temp    [5]int16
+
+	/* true if rx power is average power, false if OMA */
+	rx_power_type bool
+	tx_power_type bool
+
+	/* Channel specific data, up to 8 lanes in each of 4 CMIS banks */
+	scd [CMIS_MAX_CHANNEL_NUM]sff_channel_diags
+}
+
+/* sff_u16 reads a big endian 16-bit register */
+func sff_u16(id []byte, offset int) uint16 {
+	return binary.BigEndian.Uint16(id[offset:])
+}
+
+func yesno(yes bool) string {
+	if yes {
+		return "Yes"
+	}
+	return "No"
+}
+
+func convert_mw_to_dbm(mw float64) float64 {
+	if mw <= 0 {
+		return -40.0
+	}
+	return 10.0 * math.Log10(mw)
+}
+
+func sff_print_temp(str string, val int16) {
+	fmt.Printf("\t%-41s : %.2f degrees C / %.2f degrees F\n", str,
+		float64(val)/256.0, float64(val)/256.0*1.8+32)
+}
+
+func sff_print_bias(str string, val uint16) {
+	fmt.Printf("\t%-41s : %.3f mA\n", str, float64(val)/500.0)
+}
+
+func sff_print_power(str string, val uint16) {
+	mw := float64(val) / 10000.0
+	fmt.Printf("\t%-41s : %.4f mW / %.2f dBm\n", str, mw,
+		convert_mw_to_dbm(mw))
+}
+
+func sff_print_vcc(str string, val uint16) {
+	fmt.Printf("\t%-41s : %.4f V\n", str, float64(val)/10000.0)
+}
+
+func sff_show_value_with_unit(id []byte, reg int, name string, mult uint32,
+	unit string) {
+	fmt.Printf("\t%-41s : %d%s\n", name, uint32(id[reg])*mult, unit)
+}
+
+/* sff_show_ascii prints the printable part of id[first..last], without
+ * the trailing space padding
+ */
+func sff_show_ascii(id []byte, first int, last int, name string) {
+	fmt.Printf("\t%-41s : ", name)
+	for first <= last && id[last] == ' ' {
+		last--
+	}
+	for reg := first; reg <= last; reg++ {
+		if id[reg] >= 0x20 && id[reg] < 0x7f {
+			fmt.Printf("%c", id[reg])
+		}
+	}
+	fmt.Printf("\n")
+}
+
+func sff8024_show_identifier(id []byte, id_offset int) {
+	fmt.Printf("\t%-41s : 0x%02x", "Identifier", id[id_offset])
+	switch id[id_offset] {
+	case SFF8024_ID_UNKNOWN:
+		fmt.Printf(" (no module present, unknown, or unspecified)\n")
+	case SFF8024_ID_GBIC:
+		fmt.Printf(" (GBIC)\n")
+	case SFF8024_ID_SOLDERED_MODULE:
+		fmt.Printf(" (module soldered to motherboard)\n")
+	case SFF8024_ID_SFP:
+		fmt.Printf(" (SFP)\n")
+	case SFF8024_ID_300_PIN_XBI:
+		fmt.Printf(" (300 pin XBI)\n")
+	case SFF8024_ID_XENPAK:
+		fmt.Printf(" (XENPAK)\n")
+	case SFF8024_ID_XFP:
+		fmt.Printf(" (XFP)\n")
+	case SFF8024_ID_XFF:
+		fmt.Printf(" (XFF)\n")
+	case SFF8024_ID_XFP_E:
+		fmt.Printf(" (XFP-E)\n")
+	case SFF8024_ID_XPAK:
+		fmt.Printf(" (XPAK)\n")
+	case SFF8024_ID_X2:
+		fmt.Printf(" (X2)\n")
+	case SFF8024_ID_DWDM_SFP:
+		fmt.Printf(" (DWDM-SFP)\n")
+	case SFF8024_ID_QSFP:
+		fmt.Printf(" (QSFP)\n")
+	case SFF8024_ID_QSFP_PLUS:
+		fmt.Printf(" (QSFP+)\n")
+	case SFF8024_ID_CXP:
+		fmt.Printf(" (CXP)\n")
+	case SFF8024_ID_HD4X:
+		fmt.Printf(" (Shielded Mini Multilane HD 4X)\n")
+	case SFF8024_ID_HD8X:
+		fmt.Printf(" (Shielded Mini Multilane HD 8X)\n")
+	case SFF8024_ID_QSFP28:
+		fmt.Printf(" (QSFP28)\n")
+	case SFF8024_ID_CXP2:
+		fmt.Printf(" (CXP2/CXP28)\n")
+	case SFF8024_ID_CDFP:
+		fmt.Printf(" (CDFP Style 1/Style 2)\n")
+	case SFF8024_ID_HD4X_FANOUT:
+		fmt.Printf(" (Shielded Mini Multilane HD 4X Fanout Cable)\n")
+	case SFF8024_ID_HD8X_FANOUT:
+		fmt.Printf(" (Shielded Mini Multilane HD 8X Fanout Cable)\n")
+	case SFF8024_ID_CDFP_S3:
+		fmt.Printf(" (CDFP Style 3)\n")
+	case SFF8024_ID_MICRO_QSFP:
+		fmt.Printf(" (microQSFP)\n")
+	case SFF8024_ID_QSFP_DD:
+		fmt.Printf(" (QSFP-DD Double Density 8X Pluggable Transceiver (INF-8628))\n")
+	case SFF8024_ID_OSFP:
+		fmt.Printf(" (OSFP 8X Pluggable Transceiver)\n")
+	case SFF8024_ID_DSFP:
+		fmt.Printf(" (DSFP Dual Small Form Factor Pluggable Transceiver)\n")
+	case SFF8024_ID_QSFP_PLUS_CMIS:
+		fmt.Printf(" (QSFP+ or later with Common Management Interface Specification (CMIS))\n")
+	case SFF8024_ID_SFP_DD_CMIS:
+		fmt.Printf(" (SFP-DD Double Density 2X Pluggable Transceiver with Common Management Interface Specification (CMIS))\n")
+	case SFF8024_ID_SFP_PLUS_CMIS:
+		fmt.Printf(" (SFP+ and later with Common Management Interface Specification (CMIS))\n")
+	default:
+		fmt.Printf(" (reserved or unknown)\n")
+	}
+}
+
+func sff8024_show_connector(id []byte, ctor_offset int) {
+	fmt.Printf("\t%-41s : 0x%02x", "Connector", id[ctor_offset])
+	switch id[ctor_offset] {
+	case SFF8024_CTOR_UNKNOWN:
+		fmt.Printf(" (unknown or unspecified)\n")
+	case SFF8024_CTOR_SC:
+		fmt.Printf(" (SC)\n")
+	case SFF8024_CTOR_FC_STYLE_1:
+		fmt.Printf(" (Fibre Channel Style 1 copper)\n")
+	case SFF8024_CTOR_FC_STYLE_2:
+		fmt.Printf(" (Fibre Channel Style 2 copper)\n")
+	case SFF8024_CTOR_BNC_TNC:
+		fmt.Printf(" (BNC/TNC)\n")
+	case SFF8024_CTOR_FC_COAX:
+		fmt.Printf(" (Fibre Channel coaxial headers)\n")
+	case SFF8024_CTOR_FIBER_JACK:
+		fmt.Printf(" (FibreJack)\n")
+	case SFF8024_CTOR_LC:
+		fmt.Printf(" (LC)\n")
+	case SFF8024_CTOR_MT_RJ:
+		fmt.Printf(" (MT-RJ)\n")
+	case SFF8024_CTOR_MU:
+		fmt.Printf(" (MU)\n")
+	case SFF8024_CTOR_SG:
+		fmt.Printf(" (SG)\n")
+	case SFF8024_CTOR_OPT_PT:
+		fmt.Printf(" (Optical pigtail)\n")
+	case SFF8024_CTOR_MPO:
+		fmt.Printf(" (MPO Parallel Optic)\n")
+	case SFF8024_CTOR_MPO_2:
+		fmt.Printf(" (MPO Parallel Optic - 2x16)\n")
+	case SFF8024_CTOR_HSDC_II:
+		fmt.Printf(" (HSSDC II)\n")
+	case SFF8024_CTOR_COPPER_PT:
+		fmt.Printf(" (Copper pigtail)\n")
+	case SFF8024_CTOR_RJ45:
+		fmt.Printf(" (RJ45)\n")
+	case SFF8024_CTOR_NO_SEPARABLE:
+		fmt.Printf(" (No separable connector)\n")
+	case SFF8024_CTOR_MXC_2X16:
+		fmt.Printf(" (MXC 2x16)\n")
+	case SFF8024_CTOR_CS_OPTICAL:
+		fmt.Printf(" (CS optical connector)\n")
+	case SFF8024_CTOR_CS_OPTICAL_MINI:
+		fmt.Printf(" (Mini CS optical connector)\n")
+	case SFF8024_CTOR_MPO_2X12:
+		fmt.Printf(" (MPO 2x12)\n")
+	case SFF8024_CTOR_MPO_1X16:
+		fmt.Printf(" (MPO 1x16)\n")
+	case SFF8024_CTOR_NO_SEP_QSFP_DD:
+		fmt.Printf(" (No separable connector)\n")
+	default:
+		fmt.Printf(" (reserved or unknown)\n")
+	}
+}
+
+/* The meaning of encodings 4h-6h differs between SFF-8472 and SFF-8636 */
+func sff8024_show_encoding(id []byte, encoding_offset int, sff_type int) {
+	fmt.Printf("\t%-41s : 0x%02x", "Encoding", id[encoding_offset])
+	switch id[encoding_offset] {
+	case SFF8024_ENCODING_UNSPEC:
+		fmt.Printf(" (unspecified)")
+	case SFF8024_ENCODING_8B10B:
+		fmt.Printf(" (8B/10B)")
+	case SFF8024_ENCODING_4B5B:
+		fmt.Printf(" (4B/5B)")
+	case SFF8024_ENCODING_NRZ:
+		fmt.Printf(" (NRZ)")
+	case SFF8024_ENCODING_4h:
+		if sff_type == ETH_MODULE_SFF_8472 {
+			fmt.Printf(" (Manchester)")
+		} else if sff_type == ETH_MODULE_SFF_8636 {
+			fmt.Printf(" (SONET Scrambled)")
+		}
+	case SFF8024_ENCODING_5h:
+		if sff_type == ETH_MODULE_SFF_8472 {
+			fmt.Printf(" (SONET Scrambled)")
+		} else if sff_type == ETH_MODULE_SFF_8636 {
+			fmt.Printf(" (64B/66B)")
+		}
+	case SFF8024_ENCODING_6h:
+		if sff_type == ETH_MODULE_SFF_8472 {
+			fmt.Printf(" (64B/66B)")
+		} else if sff_type == ETH_MODULE_SFF_8636 {
+			fmt.Printf(" (Manchester)")
+		}
+	case SFF8024_ENCODING_256B:
+		fmt.Printf(" ((256B/257B (transcoded FEC-enabled data))")
+	case SFF8024_ENCODING_PAM4:
+		fmt.Printf(" (PAM4)")
+	default:
+		fmt.Printf(" (reserved or unknown)")
+	}
+	fmt.Printf("\n")
+}
+
+func sff_show_thresholds(sd *sff_diags) {
+	sff_print_bias("Laser bias current high alarm threshold", sd.bias_cur[HALRM])
+	sff_print_bias("Laser bias current low alarm threshold", sd.bias_cur[LALRM])
+	sff_print_bias("Laser bias current high warning threshold", sd.bias_cur[HWARN])
+	sff_print_bias("Laser bias current low warning threshold", sd.bias_cur[LWARN])
+
+	sff_print_power("Laser output power high alarm threshold", sd.tx_power[HALRM])
+	sff_print_power("Laser output power low alarm threshold", sd.tx_power[LALRM])
+	sff_print_power("Laser output power high warning threshold", sd.tx_power[HWARN])
+	sff_print_power("Laser output power low warning threshold", sd.tx_power[LWARN])
+
+	sff_print_temp("Module temperature high alarm threshold", sd.sfp_temp[HALRM])
+	sff_print_temp("Module temperature low alarm threshold", sd.sfp_temp[LALRM])
+	sff_print_temp("Module temperature high warning threshold", sd.sfp_temp[HWARN])
+	sff_print_temp("Module temperature low warning threshold", sd.sfp_temp[LWARN])
+
+	sff_print_vcc("Module voltage high alarm threshold", sd.sfp_voltage[HALRM])
+	sff_print_vcc("Module voltage low alarm threshold", sd.sfp_voltage[LALRM])
+	sff_print_vcc("Module voltage high warning threshold", sd.sfp_voltage[HWARN])
+	sff_print_vcc("Module voltage low warning threshold", sd.sfp_voltage[LWARN])
+
+	sff_print_power("Laser rx power high alarm threshold", sd.rx_power[HALRM])
+	sff_print_power("Laser rx power low alarm threshold", sd.rx_power[LALRM])
+	sff_print_power("Laser rx power high warning threshold", sd.rx_power[HWARN])
+	sff_print_power("Laser rx power low warning threshold", sd.rx_power[LWARN])
+}
@@ -0,0 +1,248 @@
+package ethtool
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+/*
+ * SFF-8472 A2 page decoder: digital optical monitoring of SFP/SFP+
+ * modules. The kernel returns the A2 page right after the A0 page.
+ */
+
+/* Offsets in decimal, for direct comparison with the SFF specs */
+
+/* A0-based EEPROM offsets for DOM support checks */
+const (
+	SFF_A0_DOM     = 92
+	SFF_A0_OPTIONS = 93
+	SFF_A0_COMP    = 94
+
+	/* EEPROM bit values for various registers */
+	SFF_A0_DOM_EXTCAL = 1 << 4
+	SFF_A0_DOM_INTCAL = 1 << 5
+	SFF_A0_DOM_IMPL   = 1 << 6
+	SFF_A0_DOM_PWRT   = 1 << 3
+
+	SFF_A0_OPTIONS_AW = 1 << 7
+)
+
+/* A2-based offsets, see SFF-8472 Table 9-5 */
+const (
+	SFF_A2_BASE = 0x100
+
+	SFF_A2_TEMP_HALRM = 0
+	SFF_A2_TEMP_LALRM = 2
+	SFF_A2_TEMP_HWARN = 4
+	SFF_A2_TEMP_LWARN = 6
+
+	SFF_A2_VCC_HALRM = 8
+	SFF_A2_VCC_LALRM = 10
+	SFF_A2_VCC_HWARN = 12
+	SFF_A2_VCC_LWARN = 14
+
+	SFF_A2_BIAS_HALRM = 16
+	SFF_A2_BIAS_LALRM = 18
+	SFF_A2_BIAS_HWARN = 20
+	SFF_A2_BIAS_LWARN = 22
+
+	SFF_A2_TX_PWR_HALRM = 24
+	SFF_A2_TX_PWR_LALRM = 26
+	SFF_A2_TX_PWR_HWARN = 28
+	SFF_A2_TX_PWR_LWARN = 30
+
+	SFF_A2_RX_PWR_HALRM = 32
+	SFF_A2_RX_PWR_LALRM = 34
+	SFF_A2_RX_PWR_HWARN = 36
+	SFF_A2_RX_PWR_LWARN = 38
+
+	SFF_A2_CAL_RXPWR4    = 56
+	SFF_A2_CAL_RXPWR3    = 60
+	SFF_A2_CAL_RXPWR2    = 64
+	SFF_A2_CAL_RXPWR1    = 68
+	SFF_A2_CAL_RXPWR0    = 72
+	SFF_A2_CAL_TXI_SLP   = 76
+	SFF_A2_CAL_TXI_OFF   = 78
+	SFF_A2_CAL_TXPWR_SLP = 80
+	SFF_A2_CAL_TXPWR_OFF = 82
+	SFF_A2_CAL_T_SLP     = 84
+	SFF_A2_CAL_T_OFF     = 86
+	SFF_A2_CAL_V_SLP     = 88
+	SFF_A2_CAL_V_OFF     = 90
+
+	SFF_A2_TEMP   = 96
+	SFF_A2_VCC    = 98
+	SFF_A2_BIAS   = 100
+	SFF_A2_TX_PWR = 102
+	SFF_A2_RX_PWR = 104
+
+	SFF_A2_ALRM_FLG = 112
+	SFF_A2_WARN_FLG = 116
+)
+
+var sff8472_aw_flags = []struct {
+	str    string
+	offset int
+	value  uint8
+}{
+	{"Laser bias current high alarm", SFF_A2_ALRM_FLG, 1 << 3},
+	{"Laser bias current low alarm", SFF_A2_ALRM_FLG, 1 << 2},
+	{"Laser bias current high warning", SFF_A2_WARN_FLG, 1 << 3},
+	{"Laser bias current low warning", SFF_A2_WARN_FLG, 1 << 2},
+
+	{"Laser output power high alarm", SFF_A2_ALRM_FLG, 1 << 1},
+	{"Laser output power low alarm", SFF_A2_ALRM_FLG, 1 << 0},
+	{"Laser output power high warning", SFF_A2_WARN_FLG, 1 << 1},
+	{"Laser output power low warning", SFF_A2_WARN_FLG, 1 << 0},
+
+	{"Module temperature high alarm", SFF_A2_ALRM_FLG, 1 << 7},
+	{"Module temperature low alarm", SFF_A2_ALRM_FLG, 1 << 6},
+	{"Module temperature high warning", SFF_A2_WARN_FLG, 1 << 7},
+	{"Module temperature low warning", SFF_A2_WARN_FLG, 1 << 6},
+
+	{"Module voltage high alarm", SFF_A2_ALRM_FLG, 1 << 5},
+	{"Module voltage low alarm", SFF_A2_ALRM_FLG, 1 << 4},
+	{"Module voltage high warning", SFF_A2_WARN_FLG, 1 << 5},
+	{"Module voltage low warning", SFF_A2_WARN_FLG, 1 << 4},
+
+	{"Laser rx power high alarm", SFF_A2_ALRM_FLG + 1, 1 << 7},
+	{"Laser rx power low alarm", SFF_A2_ALRM_FLG + 1, 1 << 6},
+	{"Laser rx power high warning", SFF_A2_WARN_FLG + 1, 1 << 7},
+	{"Laser rx power low warning", SFF_A2_WARN_FLG + 1, 1 << 6},
+}
+
+func a2_u16(id []byte, offset int) uint16 {
+	return binary.BigEndian.Uint16(id[SFF_A2_BASE+offset:])
+}
+
+/* slopes are unsigned 8.8 fixed point */
+func a2_slope(id []byte, offset int) float64 {
+	return float64(id[SFF_A2_BASE+offset]) +
+		float64(id[SFF_A2_BASE+offset+1])/256.0
+}
+
+func a2_offset(id []byte, offset int) float64 {
+	return float64(int16(a2_u16(id, offset)))
+}
+
+/* rx power calibration constants are big endian IEEE 754 floats */
+func a2_float(id []byte, offset int) float64 {
+	return float64(math.Float32frombits(
+		binary.BigEndian.Uint32(id[SFF_A2_BASE+offset:])))
+}
+
+func sff8472_dom_parse(id []byte, sd *sff_diags) {
+	sd.bias_cur[MCURR] = a2_u16(id, SFF_A2_BIAS)
+	sd.bias_cur[HALRM] = a2_u16(id, SFF_A2_BIAS_HALRM)
+	sd.bias_cur[LALRM] = a2_u16(id, SFF_A2_BIAS_LALRM)
+	sd.bias_cur[HWARN] = a2_u16(id, SFF_A2_BIAS_HWARN)
+	sd.bias_cur[LWARN] = a2_u16(id, SFF_A2_BIAS_LWARN)
+
+	sd.sfp_voltage[MCURR] = a2_u16(id, SFF_A2_VCC)
+	sd.sfp_voltage[HALRM] = a2_u16(id, SFF_A2_VCC_HALRM)
+	sd.sfp_voltage[LALRM] = a2_u16(id, SFF_A2_VCC_LALRM)
+	sd.sfp_voltage[HWARN] = a2_u16(id, SFF_A2_VCC_HWARN)
+	sd.sfp_voltage[LWARN] = a2_u16(id, SFF_A2_VCC_LWARN)
+
+	sd.tx_power[MCURR] = a2_u16(id, SFF_A2_TX_PWR)
+	sd.tx_power[HALRM] = a2_u16(id, SFF_A2_TX_PWR_HALRM)
+	sd.tx_power[LALRM] = a2_u16(id, SFF_A2_TX_PWR_LALRM)
+	sd.tx_power[HWARN] = a2_u16(id, SFF_A2_TX_PWR_HWARN)
+	sd.tx_power[LWARN] = a2_u16(id, SFF_A2_TX_PWR_LWARN)
+
+	sd.rx_power[MCURR] = a2_u16(id, SFF_A2_RX_PWR)
+	sd.rx_power[HALRM] = a2_u16(id, SFF_A2_RX_PWR_HALRM)
+	sd.rx_power[LALRM] = a2_u16(id, SFF_A2_RX_PWR_LALRM)
+	sd.rx_power[HWARN] = a2_u16(id, SFF_A2_RX_PWR_HWARN)
+	sd.rx_power[LWARN] = a2_u16(id, SFF_A2_RX_PWR_LWARN)
+
+	sd.sfp_temp[MCURR] = int16(a2_u16(id, SFF_A2_TEMP))
+	sd.sfp_temp[HALRM] = int16(a2_u16(id, SFF_A2_TEMP_HALRM))
+	sd.sfp_temp[LALRM] = int16(a2_u16(id, SFF_A2_TEMP_LALRM))
+	sd.sfp_temp[HWARN] = int16(a2_u16(id, SFF_A2_TEMP_HWARN))
+	sd.sfp_temp[LWARN] = int16(a2_u16(id, SFF_A2_TEMP_LWARN))
+}
+
+/* sff8472_calibration converts externally calibrated readings and
+ * thresholds into the units of internally calibrated modules.
+ */
+func sff8472_calibration(id []byte, sd *sff_diags) {
+	/* Calibration should occur for all values (threshold and current) */
+	for i := range sd.bias_cur {
+		/* Apply calibration formula 1 (Temp., Voltage, Bias, Tx Power) */
+		sd.bias_cur[i] = uint16(float64(sd.bias_cur[i])*
+			a2_slope(id, SFF_A2_CAL_TXI_SLP) + a2_offset(id, SFF_A2_CAL_TXI_OFF))
+		sd.tx_power[i] = uint16(float64(sd.tx_power[i])*
+			a2_slope(id, SFF_A2_CAL_TXPWR_SLP) + a2_offset(id, SFF_A2_CAL_TXPWR_OFF))
+		sd.sfp_voltage[i] = uint16(float64(sd.sfp_voltage[i])*
+			a2_slope(id, SFF_A2_CAL_V_SLP) + a2_offset(id, SFF_A2_CAL_V_OFF))
+		sd.sfp_temp[i] = int16(float64(sd.sfp_temp[i])*
+			a2_slope(id, SFF_A2_CAL_T_SLP) + a2_offset(id, SFF_A2_CAL_T_OFF))
+
+		/* Apply calibration formula 2 (Rx Power only) */
+		rx := float64(sd.rx_power[i])
+		sd.rx_power[i] = uint16(a2_float(id, SFF_A2_CAL_RXPWR0) +
+			a2_float(id, SFF_A2_CAL_RXPWR1)*rx +
+			a2_float(id, SFF_A2_CAL_RXPWR2)*rx*rx +
+			a2_float(id, SFF_A2_CAL_RXPWR3)*rx*rx*rx +
+			a2_float(id, SFF_A2_CAL_RXPWR4)*rx*rx*rx*rx)
+	}
+}
+
+func sff8472_parse_eeprom(id []byte, sd *sff_diags) {
+	sd.supports_dom = id[SFF_A0_DOM]&SFF_A0_DOM_IMPL != 0
+	sd.supports_alarms = id[SFF_A0_OPTIONS]&SFF_A0_OPTIONS_AW != 0
+	sd.calibrated_ext = id[SFF_A0_DOM]&SFF_A0_DOM_EXTCAL != 0
+	sd.rx_power_type = id[SFF_A0_DOM]&SFF_A0_DOM_PWRT != 0
+
+	sff8472_dom_parse(id, sd)
+
+	/* If the SFP is externally calibrated, apply the calibration now */
+	if sd.calibrated_ext {
+		sff8472_calibration(id, sd)
+	}
+}
+
+/* sff8472_show_all decodes the A2 page of id, which holds the A0 page
+ * followed by the A2 page
+ */
+func sff8472_show_all(id []byte) {
+	var sd sff_diags
+
+	sff8472_parse_eeprom(id, &sd)
+
+	if !sd.supports_dom {
+		fmt.Printf("\t%-41s : No\n", "Optical diagnostics support")
+		return
+	}
+	fmt.Printf("\t%-41s : Yes\n", "Optical diagnostics support")
+
+	sff_print_bias("Laser bias current", sd.bias_cur[MCURR])
+	sff_print_power("Laser output power", sd.tx_power[MCURR])
+
+	rx_power_string := "Receiver signal OMA"
+	if sd.rx_power_type {
+		rx_power_string = "Receiver signal average optical power"
+	}
+	sff_print_power(rx_power_string, sd.rx_power[MCURR])
+
+	sff_print_temp("Module temperature", sd.sfp_temp[MCURR])
+	sff_print_vcc("Module voltage", sd.sfp_voltage[MCURR])
+
+	alarms := "No"
+	if sd.supports_alarms {
+		alarms = "Yes"
+	}
+	fmt.Printf("\t%-41s : %s\n", "Alarm/warning flags implemented", alarms)
+	if sd.supports_alarms {
+		for _, f := range sff8472_aw_flags {
+			state := "Off"
+			if id[SFF_A2_BASE+f.offset]&f.value != 0 {
+				state = "On"
+			}
+			fmt.Printf("\t%-41s : %s\n", f.str, state)
+		}
+		sff_show_thresholds(&sd)
+	}
+}
@@ -0,0 +1,295 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * SFF-8079 A0 page decoder: identification and capabilities of SFP/SFP+
+ * modules.
+ */
+
+func sff8079_show_identifier(id []byte) {
+	sff8024_show_identifier(id, 0)
+}
+
+func sff8079_show_ext_identifier(id []byte) {
+	fmt.Printf("\t%-41s : 0x%02x", "Extended identifier", id[1])
+	if id[1] == 0x00 {
+		fmt.Printf(" (GBIC not specified / not MOD_DEF compliant)\n")
+	} else if id[1] == 0x04 {
+		fmt.Printf(" (GBIC/SFP defined by 2-wire interface ID)\n")
+	} else if id[1] <= 0x07 {
+		fmt.Printf(" (GBIC compliant with MOD_DEF %d)\n", id[1])
+	} else {
+		fmt.Printf(" (unknown)\n")
+	}
+}
+
+func sff8079_show_connector(id []byte) {
+	sff8024_show_connector(id, 2)
+}
+
+/* sff8079_transceiver_codes maps the compliance bits of bytes 3-10 */
+var sff8079_transceiver_codes = []struct {
+	offset int
+	bit    uint8
+	desc   string
+}{
+	/* 10G Ethernet Compliance Codes */
+	{3, 1 << 7, "10G Ethernet: 10G Base-ER [SFF-8472 rev10.4 onwards]"},
+	{3, 1 << 6, "10G Ethernet: 10G Base-LRM"},
+	{3, 1 << 5, "10G Ethernet: 10G Base-LR"},
+	{3, 1 << 4, "10G Ethernet: 10G Base-SR"},
+	/* Infiniband Compliance Codes */
+	{3, 1 << 3, "Infiniband: 1X SX"},
+	{3, 1 << 2, "Infiniband: 1X LX"},
+	{3, 1 << 1, "Infiniband: 1X Copper Active"},
+	{3, 1 << 0, "Infiniband: 1X Copper Passive"},
+	/* ESCON Compliance Codes */
+	{4, 1 << 7, "ESCON: ESCON MMF, 1310nm LED"},
+	{4, 1 << 6, "ESCON: ESCON SMF, 1310nm Laser"},
+	/* SONET Compliance Codes */
+	{4, 1 << 5, "SONET: OC-192, short reach"},
+	{4, 1 << 4, "SONET: SONET reach specifier bit 1"},
+	{4, 1 << 3, "SONET: SONET reach specifier bit 2"},
+	{4, 1 << 2, "SONET: OC-48, long reach"},
+	{4, 1 << 1, "SONET: OC-48, intermediate reach"},
+	{4, 1 << 0, "SONET: OC-48, short reach"},
+	{5, 1 << 6, "SONET: OC-12, single mode, long reach"},
+	{5, 1 << 5, "SONET: OC-12, single mode, inter. reach"},
+	{5, 1 << 4, "SONET: OC-12, short reach"},
+	{5, 1 << 2, "SONET: OC-3, single mode, long reach"},
+	{5, 1 << 1, "SONET: OC-3, single mode, inter. reach"},
+	{5, 1 << 0, "SONET: OC-3, short reach"},
+	/* Ethernet Compliance Codes */
+	{6, 1 << 7, "Ethernet: BASE-PX"},
+	{6, 1 << 6, "Ethernet: BASE-BX10"},
+	{6, 1 << 5, "Ethernet: 100BASE-FX"},
+	{6, 1 << 4, "Ethernet: 100BASE-LX/LX10"},
+	{6, 1 << 3, "Ethernet: 1000BASE-T"},
+	{6, 1 << 2, "Ethernet: 1000BASE-CX"},
+	{6, 1 << 1, "Ethernet: 1000BASE-LX"},
+	{6, 1 << 0, "Ethernet: 1000BASE-SX"},
+	/* Fibre Channel link length */
+	{7, 1 << 7, "FC: very long distance (V)"},
+	{7, 1 << 6, "FC: short distance (S)"},
+	{7, 1 << 5, "FC: intermediate distance (I)"},
+	{7, 1 << 4, "FC: long distance (L)"},
+	{7, 1 << 3, "FC: medium distance (M)"},
+	/* Fibre Channel transmitter technology */
+	{7, 1 << 2, "FC: Shortwave laser, linear Rx (SA)"},
+	{7, 1 << 1, "FC: Longwave laser (LC)"},
+	{7, 1 << 0, "FC: Electrical inter-enclosure (EL)"},
+	{8, 1 << 7, "FC: Electrical intra-enclosure (EL)"},
+	{8, 1 << 6, "FC: Shortwave laser w/o OFC (SN)"},
+	{8, 1 << 5, "FC: Shortwave laser with OFC (SL)"},
+	{8, 1 << 4, "FC: Longwave laser (LL)"},
+	{8, 1 << 3, "Active Cable"},
+	{8, 1 << 2, "Passive Cable"},
+	{8, 1 << 1, "FC: Copper FC-BaseT"},
+	/* Fibre Channel transmission media */
+	{9, 1 << 7, "FC: Twin Axial Pair (TW)"},
+	{9, 1 << 6, "FC: Twisted Pair (TP)"},
+	{9, 1 << 5, "FC: Miniature Coax (MI)"},
+	{9, 1 << 4, "FC: Video Coax (TV)"},
+	{9, 1 << 3, "FC: Multimode, 62.5um (M6)"},
+	{9, 1 << 2, "FC: Multimode, 50um (M5)"},
+	{9, 1 << 0, "FC: Single Mode (SM)"},
+	/* Fibre Channel speed */
+	{10, 1 << 7, "FC: 1200 MBytes/sec"},
+	{10, 1 << 6, "FC: 800 MBytes/sec"},
+	{10, 1 << 4, "FC: 400 MBytes/sec"},
+	{10, 1 << 2, "FC: 200 MBytes/sec"},
+	{10, 1 << 0, "FC: 100 MBytes/sec"},
+}
+
+/* Extended Specification Compliance Codes from SFF-8024, SFP byte 36
+ * and QSFP byte 192
+ */
+var sff8024_ext_compliance_codes = map[uint8]string{
+	0x01: "100G AOC or 25GAUI C2M AOC with worst BER of 5x10^(-5)",
+	0x02: "100G Base-SR4 or 25GBase-SR",
+	0x03: "100G Base-LR4 or 25GBase-LR",
+	0x04: "100G Base-ER4 or 25GBase-ER",
+	0x05: "100G Base-SR10",
+	0x06: "100G CWDM4 MSA with FEC",
+	0x07: "100G PSM4 Parallel SMF",
+	0x08: "100G ACC or 25GAUI C2M ACC with worst BER of 5x10^(-5)",
+	0x09: "100G CWDM4 MSA without FEC",
+	0x0b: "100G Base-CR4 or 25G Base-CR CA-L",
+	0x0c: "25G Base-CR CA-S",
+	0x0d: "25G Base-CR CA-N",
+	0x10: "40G Base-ER4",
+	0x11: "4x10G Base-SR",
+	0x12: "40G PSM4 Parallel SMF",
+	0x13: "G959.1 profile P1I1-2D1 (10709 MBd, 2km, 1310nm SM)",
+	0x14: "G959.1 profile P1S1-2D2 (10709 MBd, 40km, 1550nm SM)",
+	0x15: "G959.1 profile P1L1-2D2 (10709 MBd, 80km, 1550nm SM)",
+	0x16: "10Gbase-T with SFI electrical interface",
+	0x17: "100G CLR4",
+	0x18: "100G AOC or 25GAUI C2M AOC with worst BER of 10^(-12)",
+	0x19: "100G ACC or 25GAUI C2M ACC with worst BER of 10^(-12)",
+	0x1c: "10Gbase-T Short Reach",
+}
+
+func sff8079_show_transceiver(id []byte) {
+	pfx := "\tTransceiver type                          :"
+
+	fmt.Printf("\t%-41s : 0x%02x 0x%02x 0x%02x "+
+		"0x%02x 0x%02x 0x%02x 0x%02x 0x%02x 0x%02x\n",
+		"Transceiver codes",
+		id[3], id[4], id[5], id[6],
+		id[7], id[8], id[9], id[10], id[36])
+	for _, code := range sff8079_transceiver_codes {
+		if id[code.offset]&code.bit != 0 {
+			fmt.Printf("%s %s\n", pfx, code.desc)
+		}
+	}
+	if desc, ok := sff8024_ext_compliance_codes[id[36]]; ok {
+		fmt.Printf("%s Extended: %s\n", pfx, desc)
+	}
+}
+
+func sff8079_show_encoding(id []byte) {
+	sff8024_show_encoding(id, 11, ETH_MODULE_SFF_8472)
+}
+
+func sff8079_show_rate_identifier(id []byte) {
+	fmt.Printf("\t%-41s : 0x%02x", "Rate identifier", id[13])
+	switch id[13] {
+	case 0x00:
+		fmt.Printf(" (unspecified)\n")
+	case 0x01:
+		fmt.Printf(" (4/2/1G Rate_Select & AS0/AS1)\n")
+	case 0x02:
+		fmt.Printf(" (8/4/2G Rx Rate_Select only)\n")
+	case 0x03:
+		fmt.Printf(" (8/4/2G Independent Rx & Tx Rate_Select)\n")
+	case 0x04:
+		fmt.Printf(" (8/4/2G Tx Rate_Select only)\n")
+	default:
+		fmt.Printf(" (reserved or unknown)\n")
+	}
+}
+
+func sff8079_show_oui(id []byte) {
+	fmt.Printf("\t%-41s : %02x:%02x:%02x\n", "Vendor OUI",
+		id[37], id[38], id[39])
+}
+
+func sff8079_show_wavelength_or_copper_compliance(id []byte) {
+	if id[8]&(1<<2) != 0 {
+		fmt.Printf("\t%-41s : 0x%02x", "Passive Cu cmplnce.", id[60])
+		switch id[60] {
+		case 0x00:
+			fmt.Printf(" (unspecified)")
+		case 0x01:
+			fmt.Printf(" (SFF-8431 appendix E)")
+		default:
+			fmt.Printf(" (unknown)")
+		}
+		fmt.Printf(" [SFF-8472 rev10.4 only]\n")
+	} else if id[8]&(1<<3) != 0 {
+		fmt.Printf("\t%-41s : 0x%02x", "Active Cu cmplnce.", id[60])
+		switch id[60] {
+		case 0x00:
+			fmt.Printf(" (unspecified)")
+		case 0x01:
+			fmt.Printf(" (SFF-8431 appendix E)")
+		case 0x04:
+			fmt.Printf(" (SFF-8431 limiting)")
+		default:
+			fmt.Printf(" (unknown)")
+		}
+		fmt.Printf(" [SFF-8472 rev10.4 only]\n")
+	} else {
+		fmt.Printf("\t%-41s : %dnm\n", "Laser wavelength",
+			uint16(id[60])<<8|uint16(id[61]))
+	}
+}
+
+func sff8079_show_options(id []byte) {
+	pfx := "\tOption                                    :"
+
+	fmt.Printf("\t%-41s : 0x%02x 0x%02x\n", "Option values", id[64], id[65])
+	if id[65]&(1<<1) != 0 {
+		fmt.Printf("%s RX_LOS implemented\n", pfx)
+	}
+	if id[65]&(1<<2) != 0 {
+		fmt.Printf("%s RX_LOS implemented, inverted\n", pfx)
+	}
+	if id[65]&(1<<3) != 0 {
+		fmt.Printf("%s TX_FAULT implemented\n", pfx)
+	}
+	if id[65]&(1<<4) != 0 {
+		fmt.Printf("%s TX_DISABLE implemented\n", pfx)
+	}
+	if id[65]&(1<<5) != 0 {
+		fmt.Printf("%s RATE_SELECT implemented\n", pfx)
+	}
+	if id[65]&(1<<6) != 0 {
+		fmt.Printf("%s Tunable transmitter technology\n", pfx)
+	}
+	if id[65]&(1<<7) != 0 {
+		fmt.Printf("%s Receiver decision threshold implemented\n", pfx)
+	}
+	if id[64]&(1<<0) != 0 {
+		fmt.Printf("%s Linear receiver output implemented\n", pfx)
+	}
+	if id[64]&(1<<1) != 0 {
+		fmt.Printf("%s Power level 2 requirement\n", pfx)
+	}
+	if id[64]&(1<<2) != 0 {
+		fmt.Printf("%s Cooled transceiver implemented\n", pfx)
+	}
+	if id[64]&(1<<3) != 0 {
+		fmt.Printf("%s Retimer or CDR implemented\n", pfx)
+	}
+	if id[64]&(1<<4) != 0 {
+		fmt.Printf("%s Paging implemented\n", pfx)
+	}
+	if id[64]&(1<<5) != 0 {
+		fmt.Printf("%s Power level 3 requirement\n", pfx)
+	}
+}
+
+func sff8079_show_all(id []byte) {
+	sff8079_show_identifier(id)
+	if (id[0] == SFF8024_ID_SOLDERED_MODULE || id[0] == SFF8024_ID_SFP) &&
+		id[1] == 0x04 {
+		var br_nom, br_min, br_max uint32
+
+		if id[12] == 0 {
+			br_nom, br_min, br_max = 0, 0, 0
+		} else if id[12] == 255 {
+			br_nom = uint32(id[66]) * 250
+			br_max = uint32(id[67])
+			br_min = uint32(id[67])
+		} else {
+			br_nom = uint32(id[12]) * 100
+			br_max = uint32(id[66])
+			br_min = uint32(id[67])
+		}
+		sff8079_show_ext_identifier(id)
+		sff8079_show_connector(id)
+		sff8079_show_transceiver(id)
+		sff8079_show_encoding(id)
+		fmt.Printf("\t%-41s : %d%s\n", "BR, Nominal", br_nom, "MBd")
+		sff8079_show_rate_identifier(id)
+		sff_show_value_with_unit(id, 14, "Length (SMF,km)", 1, "km")
+		sff_show_value_with_unit(id, 15, "Length (SMF)", 100, "m")
+		sff_show_value_with_unit(id, 16, "Length (50um)", 10, "m")
+		sff_show_value_with_unit(id, 17, "Length (62.5um)", 10, "m")
+		sff_show_value_with_unit(id, 18, "Length (Copper)", 1, "m")
+		sff_show_value_with_unit(id, 19, "Length (OM3)", 10, "m")
+		sff8079_show_wavelength_or_copper_compliance(id)
+		sff_show_ascii(id, 20, 35, "Vendor name")
+		sff8079_show_oui(id)
+		sff_show_ascii(id, 40, 55, "Vendor PN")
+		sff_show_ascii(id, 56, 59, "Vendor rev")
+		sff8079_show_options(id)
+		fmt.Printf("\t%-41s : %d%s\n", "BR margin, max", br_max, "%")
+		fmt.Printf("\t%-41s : %d%s\n", "BR margin, min", br_min, "%")
+		sff_show_ascii(id, 68, 83, "Vendor SN")
+		sff_show_ascii(id, 84, 91, "Date code")
+	}
+}
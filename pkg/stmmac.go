@@ -0,0 +1,218 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * Register dump of the stmmac driver: the MAC registers at their offsets
+ * from 0, the DMA registers at their offsets from 0x1000 (DMA_BUS_MODE).
+ * The driver reports itself as st_gmac for the GMAC cores and st_mac100
+ * for the 10/100 MAC.
+ */
+
+const (
+	STMMAC_DMA_BASE = 0x1000
+
+	/* GMAC Configuration */
+	GMAC_CONTROL_RE   = 0x00000004 /* Receiver Enable */
+	GMAC_CONTROL_TE   = 0x00000008 /* Transmitter Enable */
+	GMAC_CONTROL_ACS  = 0x00000080 /* Auto Pad/FCS Stripping */
+	GMAC_CONTROL_IPC  = 0x00000400 /* Checksum Offload */
+	GMAC_CONTROL_DM   = 0x00000800 /* Duplex Mode */
+	GMAC_CONTROL_LM   = 0x00001000 /* Loop-back mode */
+	GMAC_CONTROL_FES  = 0x00004000 /* Speed in Fast Ethernet port */
+	GMAC_CONTROL_PS   = 0x00008000 /* Port Select 0:GMI 1:MII */
+	GMAC_CONTROL_JE   = 0x00100000 /* Jumbo frame */
+	GMAC_CONTROL_JD   = 0x00400000 /* Jabber disable */
+	GMAC_CONTROL_WD   = 0x00800000 /* Disable Watchdog on receive */
+	GMAC_CONTROL_2K   = 0x08000000 /* IEEE 802.3as 2K packets */
+	GMAC_FRAME_PR     = 0x00000001 /* Promiscuous Mode */
+	GMAC_FRAME_HMC    = 0x00000004 /* Hash Multicast */
+	GMAC_FRAME_PM     = 0x00000010 /* Pass all multicast */
+	GMAC_FRAME_DBF    = 0x00000020 /* Disable Broadcast frames */
+	GMAC_FRAME_RA     = 0x80000000 /* Receive all mode */
+	MAC100_CONTROL_RE = 0x00000004 /* Receiver Enable */
+	MAC100_CONTROL_TE = 0x00000008 /* Transmitter Enable */
+	MAC100_CONTROL_F  = 0x00100000 /* Full Duplex Mode */
+	MAC100_CONTROL_PR = 0x00040000 /* Promiscuous Mode */
+
+	/* DMA Operation Mode */
+	DMA_CONTROL_SR  = 0x00000002 /* Start/Stop Receive */
+	DMA_CONTROL_OSF = 0x00000004 /* Operate on second frame */
+	DMA_CONTROL_ST  = 0x00002000 /* Start/Stop Transmission */
+	DMA_CONTROL_FTF = 0x00100000 /* Flush transmit FIFO */
+	DMA_CONTROL_TSF = 0x00200000 /* Transmit  Store and Forward */
+	DMA_CONTROL_RSF = 0x02000000 /* Receive Store and Forward */
+
+	/* DMA Status */
+	DMA_STATUS_RS_MASK = 0x000e0000 /* Receive Process State */
+	DMA_STATUS_TS_MASK = 0x00700000 /* Transmit Process State */
+)
+
+type stmmac_reg struct {
+	offset uint32
+	name   string
+}
+
+var st_gmac_mac_regs = []stmmac_reg{
+	{0x00, "MAC Configuration"},
+	{0x04, "MAC Frame Filter"},
+	{0x08, "Hash Table High"},
+	{0x0c, "Hash Table Low"},
+	{0x10, "GMII Address"},
+	{0x14, "GMII Data"},
+	{0x18, "Flow Control"},
+	{0x1c, "VLAN Tag"},
+	{0x20, "Version"},
+	{0x24, "Debug"},
+	{0x2c, "PMT Control and Status"},
+	{0x30, "LPI Control and Status"},
+	{0x34, "LPI Timers Control"},
+	{0x38, "Interrupt Status"},
+	{0x3c, "Interrupt Mask"},
+	{0x40, "MAC Address0 High"},
+	{0x44, "MAC Address0 Low"},
+	{0xc0, "AN Control"},
+	{0xc4, "AN Status"},
+	{0xc8, "AN Advertisement"},
+	{0xcc, "AN Link Partner Ability"},
+	{0xd0, "AN Expansion"},
+	{0xd4, "TBI Extended Status"},
+	{0xd8, "SGMII/RGMII/SMII Control and Status"},
+}
+
+var st_mac100_mac_regs = []stmmac_reg{
+	{0x00, "MAC Control"},
+	{0x04, "MAC Address High"},
+	{0x08, "MAC Address Low"},
+	{0x0c, "Multicast Hash High"},
+	{0x10, "Multicast Hash Low"},
+	{0x14, "MII Address"},
+	{0x18, "MII Data"},
+	{0x1c, "Flow Control"},
+	{0x20, "VLAN1 Tag"},
+	{0x24, "VLAN2 Tag"},
+}
+
+var stmmac_dma_regs = []stmmac_reg{
+	{0x00, "Bus Mode"},
+	{0x04, "Transmit Poll Demand"},
+	{0x08, "Receive Poll Demand"},
+	{0x0c, "Receive Descriptor List Address"},
+	{0x10, "Transmit Descriptor List Address"},
+	{0x14, "Status"},
+	{0x18, "Operation Mode"},
+	{0x1c, "Interrupt Enable"},
+	{0x20, "Missed Frame and Buffer Overflow Counter"},
+	{0x24, "Receive Interrupt Watchdog Timer"},
+	{0x28, "AXI Bus Mode"},
+	{0x2c, "AHB or AXI Status"},
+	{0x48, "Current Host Transmit Descriptor"},
+	{0x4c, "Current Host Receive Descriptor"},
+	{0x50, "Current Host Transmit Buffer Address"},
+	{0x54, "Current Host Receive Buffer Address"},
+	{0x58, "HW Feature"},
+}
+
+var stmmac_rx_states = []string{
+	"Stopped", "Fetching Rx descriptor", "reserved",
+	"Waiting for Rx packet", "Suspended", "Closing Rx descriptor",
+	"Timestamp write", "Transferring Rx packet to host",
+}
+
+var stmmac_tx_states = []string{
+	"Stopped", "Fetching Tx descriptor", "Waiting for status",
+	"Reading data from host", "Timestamp write", "reserved",
+	"Suspended", "Closing Tx descriptor",
+}
+
+func stmmac_dump_field(name string, val string) {
+	fmt.Printf("\t%-28s %s\n", name+":", val)
+}
+
+func stmmac_dump_dma(regs *ethtool_regs, count int) {
+	fmt.Printf("DMA Registers\n")
+	for _, r := range stmmac_dma_regs[:count] {
+		val := regs_u32(regs, (STMMAC_DMA_BASE+r.offset)/4)
+		fmt.Printf("0x%04x: %-40s 0x%08x\n",
+			STMMAC_DMA_BASE+r.offset, r.name, val)
+		switch r.name {
+		case "Status":
+			stmmac_dump_field("Receive process",
+				stmmac_rx_states[(val&DMA_STATUS_RS_MASK)>>17])
+			stmmac_dump_field("Transmit process",
+				stmmac_tx_states[(val&DMA_STATUS_TS_MASK)>>20])
+		case "Operation Mode":
+			stmmac_dump_field("Receive", regs_bit(val&DMA_CONTROL_SR != 0, "started", "stopped"))
+			stmmac_dump_field("Transmit", regs_bit(val&DMA_CONTROL_ST != 0, "started", "stopped"))
+			stmmac_dump_field("Operate on second frame", yesno(val&DMA_CONTROL_OSF != 0))
+			stmmac_dump_field("Flush transmit FIFO", yesno(val&DMA_CONTROL_FTF != 0))
+			stmmac_dump_field("Transmit store and forward", yesno(val&DMA_CONTROL_TSF != 0))
+			stmmac_dump_field("Receive store and forward", yesno(val&DMA_CONTROL_RSF != 0))
+		}
+	}
+}
+
+func st_gmac_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	if regs.len < STMMAC_DMA_BASE+0x5c {
+		return -1
+	}
+
+	fmt.Printf("ST GMAC Registers\n")
+	fmt.Printf("GMAC Registers\n")
+	for _, r := range st_gmac_mac_regs {
+		val := regs_u32(regs, r.offset/4)
+		fmt.Printf("0x%04x: %-40s 0x%08x\n", r.offset, r.name, val)
+		switch r.offset {
+		case 0x00:
+			speed := "1000 Mbps"
+			if val&GMAC_CONTROL_PS != 0 {
+				speed = regs_bit(val&GMAC_CONTROL_FES != 0, "100 Mbps", "10 Mbps")
+			}
+			stmmac_dump_field("Receiver", endis(val&GMAC_CONTROL_RE != 0))
+			stmmac_dump_field("Transmitter", endis(val&GMAC_CONTROL_TE != 0))
+			stmmac_dump_field("Speed", speed)
+			stmmac_dump_field("Duplex", regs_bit(val&GMAC_CONTROL_DM != 0, "full", "half"))
+			stmmac_dump_field("Loopback", endis(val&GMAC_CONTROL_LM != 0))
+			stmmac_dump_field("Checksum offload", endis(val&GMAC_CONTROL_IPC != 0))
+			stmmac_dump_field("Pad/CRC stripping", endis(val&GMAC_CONTROL_ACS != 0))
+			stmmac_dump_field("Jumbo frames", endis(val&GMAC_CONTROL_JE != 0))
+			stmmac_dump_field("2K packets", endis(val&GMAC_CONTROL_2K != 0))
+			stmmac_dump_field("Jabber timer", regs_bit(val&GMAC_CONTROL_JD != 0, "disabled", "enabled"))
+			stmmac_dump_field("Watchdog", regs_bit(val&GMAC_CONTROL_WD != 0, "disabled", "enabled"))
+		case 0x04:
+			stmmac_dump_field("Promiscuous", endis(val&GMAC_FRAME_PR != 0))
+			stmmac_dump_field("Receive all", endis(val&GMAC_FRAME_RA != 0))
+			stmmac_dump_field("Pass all multicast", endis(val&GMAC_FRAME_PM != 0))
+			stmmac_dump_field("Hash multicast", endis(val&GMAC_FRAME_HMC != 0))
+			stmmac_dump_field("Broadcast frames", regs_bit(val&GMAC_FRAME_DBF != 0, "disabled", "enabled"))
+		case 0x20:
+			stmmac_dump_field("Synopsys version", fmt.Sprintf("0x%02x", val&0xff))
+		}
+	}
+	fmt.Printf("\n")
+	stmmac_dump_dma(regs, len(stmmac_dma_regs))
+	return 0
+}
+
+func st_mac100_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	/* The 10/100 core only has the first nine DMA registers */
+	if regs.len < STMMAC_DMA_BASE+0x24 {
+		return -1
+	}
+
+	fmt.Printf("ST MAC 10/100 Registers\n")
+	fmt.Printf("MAC Registers\n")
+	for _, r := range st_mac100_mac_regs {
+		val := regs_u32(regs, r.offset/4)
+		fmt.Printf("0x%04x: %-40s 0x%08x\n", r.offset, r.name, val)
+		if r.offset == 0x00 {
+			stmmac_dump_field("Receiver", endis(val&MAC100_CONTROL_RE != 0))
+			stmmac_dump_field("Transmitter", endis(val&MAC100_CONTROL_TE != 0))
+			stmmac_dump_field("Duplex", regs_bit(val&MAC100_CONTROL_F != 0, "full", "half"))
+			stmmac_dump_field("Promiscuous", endis(val&MAC100_CONTROL_PR != 0))
+		}
+	}
+	fmt.Printf("\n")
+	stmmac_dump_dma(regs, 9)
+	return 0
+}
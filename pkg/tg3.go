@@ -0,0 +1,104 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * Register dump of the tg3 driver: the register space as is, each
+ * register at its own offset, with unreadable blocks left zero. Only the
+ * non-zero registers are shown.
+ */
+
+var tg3_reg_names = map[uint32]string{
+	0x0068: "TG3PCI_MISC_HOST_CTRL",
+	0x006c: "TG3PCI_DMA_RW_CTRL",
+	0x0070: "TG3PCI_PCISTATE",
+	0x0074: "TG3PCI_CLOCK_CTRL",
+	0x0400: "MAC_MODE",
+	0x0404: "MAC_STATUS",
+	0x0408: "MAC_EVENT",
+	0x040c: "MAC_LED_CTRL",
+	0x0410: "MAC_ADDR_0_HIGH",
+	0x0414: "MAC_ADDR_0_LOW",
+	0x044c: "MAC_MI_COM",
+	0x0450: "MAC_MI_STAT",
+	0x0454: "MAC_MI_MODE",
+	0x0458: "MAC_AUTO_POLL_STATUS",
+	0x045c: "MAC_TX_MODE",
+	0x0460: "MAC_TX_STATUS",
+	0x0464: "MAC_TX_LENGTHS",
+	0x0468: "MAC_RX_MODE",
+	0x046c: "MAC_RX_STATUS",
+	0x0c00: "SNDDATAI_MODE",
+	0x1000: "SNDDATAC_MODE",
+	0x1400: "SNDBDS_MODE",
+	0x1800: "SNDBDI_MODE",
+	0x1c00: "SNDBDC_MODE",
+	0x2000: "RCVLPC_MODE",
+	0x2400: "RCVDBDI_MODE",
+	0x2800: "RCVDCC_MODE",
+	0x2c00: "RCVBDI_MODE",
+	0x3000: "RCVCC_MODE",
+	0x3400: "RCVLSC_MODE",
+	0x3800: "MBFREE_MODE",
+	0x3c00: "HOSTCC_MODE",
+	0x4000: "MEMARB_MODE",
+	0x4400: "BUFMGR_MODE",
+	0x4800: "RDMAC_MODE",
+	0x4c00: "WDMAC_MODE",
+	0x5000: "RX_CPU_MODE",
+	0x5004: "RX_CPU_STATE",
+	0x501c: "RX_CPU_PC",
+	0x5400: "TX_CPU_MODE",
+	0x5404: "TX_CPU_STATE",
+	0x541c: "TX_CPU_PC",
+	0x6800: "GRC_MODE",
+	0x6804: "GRC_MISC_CFG",
+	0x6808: "GRC_LOCAL_CTRL",
+	0x7000: "NVRAM_CMD",
+}
+
+const (
+	TG3_MAC_MODE_HALF_DUPLEX     = 0x00000002
+	TG3_MAC_MODE_PORT_MODE_MASK  = 0x0000000c
+	TG3_MAC_MODE_PORT_INT_LPBACK = 0x00000010
+
+	TG3_MAC_STATUS_PCS_SYNCED       = 0x00000001
+	TG3_MAC_STATUS_SIGNAL_DET       = 0x00000002
+	TG3_MAC_STATUS_LNKSTATE_CHANGED = 0x00001000
+)
+
+func tg3_dump_fields(offset uint32, reg uint32) {
+	switch offset {
+	case 0x0400: /* MAC_MODE */
+		ports := []string{"none", "MII", "GMII", "TBI"}
+		fmt.Printf("\t\t\tPort mode: %s, %s duplex%s\n",
+			ports[(reg&TG3_MAC_MODE_PORT_MODE_MASK)>>2],
+			regs_bit(reg&TG3_MAC_MODE_HALF_DUPLEX != 0, "half", "full"),
+			regs_bit(reg&TG3_MAC_MODE_PORT_INT_LPBACK != 0,
+				", internal loopback", ""))
+	case 0x0404: /* MAC_STATUS */
+		fmt.Printf("\t\t\tPCS synced: %s, signal detected: %s, "+
+			"link state changed: %s\n",
+			yesno(reg&TG3_MAC_STATUS_PCS_SYNCED != 0),
+			yesno(reg&TG3_MAC_STATUS_SIGNAL_DET != 0),
+			yesno(reg&TG3_MAC_STATUS_LNKSTATE_CHANGED != 0))
+	}
+}
+
+func tg3_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	fmt.Printf("Offset\tValue\t\tRegister\n")
+	fmt.Printf("------\t----------\t--------\n")
+	for i := uint32(0); i+4 <= regs.len; i += 4 {
+		reg := regs_u32(regs, i/4)
+		if reg != 0 {
+			fmt.Printf("0x%04x\t0x%08x", i, reg)
+			if name, ok := tg3_reg_names[i]; ok {
+				fmt.Printf("\t%s", name)
+			}
+			fmt.Printf("\n")
+			tg3_dump_fields(i, reg)
+		}
+	}
+	fmt.Printf("\n")
+	return 0
+}
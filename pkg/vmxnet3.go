@@ -0,0 +1,134 @@
+package ethtool
+
+import "fmt"
+
+/*
+ * Register dump of the vmxnet3 driver, version 2: the BAR1 control
+ * registers, then the interrupt mask registers, the tx queues and the rx
+ * queues, each block preceded by its count. Ring base addresses are
+ * split in a low and a high word.
+ */
+
+const (
+	VMXNET3_DUMP_VERSION = 2
+
+	/* Words per tx and rx queue */
+	VMXNET3_DUMP_TXQ_LEN = 17
+	VMXNET3_DUMP_RXQ_LEN = 23
+)
+
+/* vmxnet3_dump_valid checks the counts in the dump against its length */
+func vmxnet3_dump_valid(regs *ethtool_regs) bool {
+	words := regs.len / 4
+
+	n := uint32(9)
+	for _, qlen := range []uint32{1, VMXNET3_DUMP_TXQ_LEN,
+		VMXNET3_DUMP_RXQ_LEN} {
+		if n >= words {
+			return false
+		}
+		cnt := regs_u32(regs, n)
+		if cnt > words {
+			return false
+		}
+		n += 1 + cnt*qlen
+	}
+	return n <= words
+}
+
+func vmxnet3_dump_regs(info *ethtool_drvinfo, regs *ethtool_regs) int {
+	if regs.version != VMXNET3_DUMP_VERSION || !vmxnet3_dump_valid(regs) {
+		return -1
+	}
+
+	i := uint32(0)
+	next := func() uint32 {
+		i++
+		return regs_u32(regs, i-1)
+	}
+	var v [VMXNET3_DUMP_RXQ_LEN]uint32
+	/* take fills v with the next n words */
+	take := func(n int) {
+		for k := 0; k < n; k++ {
+			v[k] = next()
+		}
+	}
+
+	take(9)
+	fmt.Printf("Control Registers\n")
+	fmt.Printf("=================\n")
+	fmt.Printf("    VRRS (Vmxnet3 Revision Report and Selection)    0x%x\n", v[0])
+	fmt.Printf("    UVRS (UPT Version Report and Selection)         0x%x\n", v[1])
+	fmt.Printf("    DSA  (Driver Shared Address)                    0x%08x%08x\n", v[3], v[2])
+	fmt.Printf("    CMD  (Command Register)                         0x%x\n", v[4])
+	fmt.Printf("    MAC  (Media Access Control address)             %02x:%02x:%02x:%02x:%02x:%02x\n",
+		v[5]&0xff, (v[5]>>8)&0xff, (v[5]>>16)&0xff, v[5]>>24,
+		v[6]&0xff, (v[6]>>8)&0xff)
+	fmt.Printf("    ICR  (Interrupt Cause Register)                 0x%x\n", v[7])
+	fmt.Printf("    ECR  (Event Cause Register)                     0x%x\n", v[8])
+
+	cnt := next()
+	fmt.Printf("\nInterrupt Mask Registers\n")
+	fmt.Printf("========================\n")
+	for j := uint32(0); j < cnt; j++ {
+		fmt.Printf("    IMR %-2d                                         0x%x\n", j, next())
+	}
+
+	cnt = next()
+	for j := uint32(0); j < cnt; j++ {
+		take(VMXNET3_DUMP_TXQ_LEN)
+		fmt.Printf("\nTX Queue %d\n", j)
+		fmt.Printf("==========\n")
+		fmt.Printf("    TXPROD (Transmit Ring Producer Index)           %d\n", v[0])
+		fmt.Printf("    Transmit Ring\n"+
+			"        Base Address                                0x%08x%08x\n"+
+			"        Size                                        %d\n"+
+			"        next2fill                                   %d\n"+
+			"        next2comp                                   %d\n"+
+			"        gen                                         %d\n",
+			v[2], v[1], v[3], v[4], v[5], v[6])
+		fmt.Printf("    Transmit Data Ring\n"+
+			"        Base Address                                0x%08x%08x\n"+
+			"        Size                                        %d\n"+
+			"        Buffer Size                                 %d\n",
+			v[8], v[7], v[9], v[10])
+		fmt.Printf("    Transmit Completion Ring\n"+
+			"        Base Address                                0x%08x%08x\n"+
+			"        size                                        %d\n"+
+			"        next2proc                                   %d\n"+
+			"        gen                                         %d\n",
+			v[12], v[11], v[13], v[14], v[15])
+		fmt.Printf("    stopped                                         %d\n", v[16])
+	}
+
+	cnt = next()
+	for j := uint32(0); j < cnt; j++ {
+		take(VMXNET3_DUMP_RXQ_LEN)
+		fmt.Printf("\nRX Queue %d\n", j)
+		fmt.Printf("==========\n")
+		fmt.Printf("    RXPROD1 (Receive Ring Producer Index) 1         %d\n", v[0])
+		fmt.Printf("    RXPROD2 (Receive Ring Producer Index) 2         %d\n", v[1])
+		for r := 0; r < 2; r++ {
+			w := v[2+r*6:]
+			fmt.Printf("    Receive Ring %d\n"+
+				"        Base Address                                0x%08x%08x\n"+
+				"        Size                                        %d\n"+
+				"        next2fill                                   %d\n"+
+				"        next2comp                                   %d\n"+
+				"        gen                                         %d\n",
+				r, w[1], w[0], w[2], w[3], w[4], w[5])
+		}
+		fmt.Printf("    Receive Data Ring\n"+
+			"        Base Address                                0x%08x%08x\n"+
+			"        Size                                        %d\n"+
+			"        Buffer Size                                 %d\n",
+			v[15], v[14], v[16], v[17])
+		fmt.Printf("    Receive Completion Ring\n"+
+			"        Base Address                                0x%08x%08x\n"+
+			"        size                                        %d\n"+
+			"        next2proc                                   %d\n"+
+			"        gen                                         %d\n",
+			v[19], v[18], v[20], v[21], v[22])
+	}
+	return 0
+}